@@ -0,0 +1,311 @@
+// Package auth provides http authentication middleware for the todo server.
+//
+// An Authenticator identifies the user making a request from its credentials. Static bearer tokens and HTTP Basic
+// passwords hashed with bcrypt are provided, and may be combined with Any. Custom schemes implement Authenticator, or
+// use AuthenticatorFunc. The Middleware rejects unauthenticated requests, and passes the user of the rest to the task
+// store with task.WithUser, which scopes their requests to their own tasks, and the lists shared with them or their
+// Groups, unless they are Admins.
+package auth
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/jmank88/todo/task"
+)
+
+// ErrNoCredentials is returned by an Authenticator when a request has no credentials for it. It wraps
+// task.ErrUnauthenticated.
+var ErrNoCredentials = fmt.Errorf("%w: no credentials", task.ErrUnauthenticated)
+
+// An Authenticator authenticates the user making a request.
+type Authenticator interface {
+	// The Authenticate method returns the non-empty name of the user making r. Returns an error wrapping
+	// ErrNoCredentials if r has no credentials for the Authenticator, or task.ErrUnauthenticated if they are invalid.
+	// Any other error is a failure to check the credentials.
+	Authenticate(r *http.Request) (user string, err error)
+}
+
+// An AuthenticatorFunc is a function implementing Authenticator.
+type AuthenticatorFunc func(r *http.Request) (string, error)
+
+// The Authenticate method calls f.
+func (f AuthenticatorFunc) Authenticate(r *http.Request) (string, error) {
+	return f(r)
+}
+
+// A Challenger is an Authenticator which describes the credentials it accepts, as the values of WWW-Authenticate
+// headers sent with unauthenticated responses.
+type Challenger interface {
+	Challenges() []string
+}
+
+// An Option is a functional option for configuring the Middleware.
+type Option func(*middleware)
+
+// A middleware holds the configuration of the Middleware.
+type middleware struct {
+	admins map[string]bool
+	groups map[string][]string
+}
+
+// The Admins function returns an Option which makes users admins, who may read and write the tasks and lists of every
+// user. See task.AsAdmin.
+func Admins(users ...string) Option {
+	return func(m *middleware) {
+		for _, user := range users {
+			m.admins[user] = true
+		}
+	}
+}
+
+// The Groups function returns an Option which adds users to groups, with the members of each group by group name. The
+// groups of a user are passed to the task store with task.WithGroups, so that lists shared with a group are shared with
+// its members.
+func Groups(members map[string][]string) Option {
+	return func(m *middleware) {
+		for group, users := range members {
+			for _, user := range users {
+				m.groups[user] = append(m.groups[user], group)
+			}
+		}
+	}
+}
+
+// The Middleware function returns an http.Handler which authenticates each request with a, and serves it with next, with
+// the user in its context. See task.WithUser. Unauthenticated requests are rejected with 401 Unauthorized, and the
+// challenges of a, if it is a Challenger.
+func Middleware(a Authenticator, next http.Handler, options ...Option) http.Handler {
+	m := &middleware{admins: make(map[string]bool), groups: make(map[string][]string)}
+	for _, o := range options {
+		o(m)
+	}
+	for _, groups := range m.groups {
+		sort.Strings(groups)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, err := a.Authenticate(r)
+		if err == nil && user == "" {
+			err = errors.New("authenticator returned no user")
+		}
+		if errors.Is(err, task.ErrUnauthenticated) {
+			if c, ok := a.(Challenger); ok {
+				for _, challenge := range c.Challenges() {
+					w.Header().Add("WWW-Authenticate", challenge)
+				}
+			}
+			http.Error(w, fmt.Sprintf("failed to authenticate: %s", err), http.StatusUnauthorized)
+			return
+		} else if err != nil {
+			http.Error(w, fmt.Sprintf("failed to authenticate: %s", err), http.StatusInternalServerError)
+			return
+		}
+		ctx := task.WithUser(r.Context(), user)
+		if m.admins[user] {
+			ctx = task.AsAdmin(ctx)
+		}
+		if groups := m.groups[user]; len(groups) > 0 {
+			ctx = task.WithGroups(ctx, groups)
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// The Any function returns an Authenticator which authenticates requests with the first of authenticators which finds
+// credentials. Its challenges are those of each of authenticators.
+func Any(authenticators ...Authenticator) Authenticator {
+	return anyAuthenticator(authenticators)
+}
+
+// An anyAuthenticator implements Any.
+type anyAuthenticator []Authenticator
+
+func (as anyAuthenticator) Authenticate(r *http.Request) (string, error) {
+	for _, a := range as {
+		user, err := a.Authenticate(r)
+		if errors.Is(err, ErrNoCredentials) {
+			continue
+		}
+		return user, err
+	}
+	return "", ErrNoCredentials
+}
+
+func (as anyAuthenticator) Challenges() []string {
+	var challenges []string
+	for _, a := range as {
+		if c, ok := a.(Challenger); ok {
+			challenges = append(challenges, c.Challenges()...)
+		}
+	}
+	return challenges
+}
+
+// The realm is the protection space of the challenges.
+const realm = "todo"
+
+// The Tokens function returns an Authenticator of requests with an "Authorization: Bearer <token>" header, for the
+// tokens of users, by user name.
+func Tokens(users map[string]string) Authenticator {
+	t := tokens(make(map[[sha256.Size]byte]string, len(users)))
+	for user, token := range users {
+		t[sha256.Sum256([]byte(token))] = user
+	}
+	return t
+}
+
+// A tokens maps the sha256 hashes of tokens to users. Tokens are looked up by their hashes, so that the time taken does
+// not reveal how much of a guessed token is correct.
+type tokens map[[sha256.Size]byte]string
+
+func (t tokens) Authenticate(r *http.Request) (string, error) {
+	const prefix = "bearer "
+	header := r.Header.Get("Authorization")
+	if len(header) <= len(prefix) || !strings.EqualFold(header[:len(prefix)], prefix) {
+		return "", ErrNoCredentials
+	}
+	user, ok := t[sha256.Sum256([]byte(strings.TrimSpace(header[len(prefix):])))]
+	if !ok {
+		return "", fmt.Errorf("%w: invalid token", task.ErrUnauthenticated)
+	}
+	return user, nil
+}
+
+func (t tokens) Challenges() []string {
+	return []string{fmt.Sprintf("Bearer realm=%q", realm)}
+}
+
+// The LoadTokens function returns a Tokens Authenticator for the file at path, with a "<user>:<token>" line per user.
+// Blank lines, and lines beginning with "#", are ignored.
+func LoadTokens(path string) (Authenticator, error) {
+	users, err := loadSecrets(path)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]string)
+	for user, token := range users {
+		if other, ok := seen[token]; ok {
+			return nil, fmt.Errorf("failed to load tokens from %q: users %q and %q have the same token", path, other, user)
+		}
+		seen[token] = user
+	}
+	return Tokens(users), nil
+}
+
+// The Basic function returns an Authenticator of requests with HTTP Basic credentials, for the bcrypt hashes of the
+// passwords of users, by user name.
+func Basic(hashes map[string][]byte) Authenticator {
+	return basic(hashes)
+}
+
+// A basic maps users to the bcrypt hashes of their passwords.
+type basic map[string][]byte
+
+// The unknownUserHash is compared with the passwords of unknown users, so that they take as long to reject as wrong
+// passwords, and do not reveal which users exist.
+var unknownUserHash, _ = bcrypt.GenerateFromPassword([]byte("unknown user"), bcrypt.DefaultCost)
+
+func (b basic) Authenticate(r *http.Request) (string, error) {
+	user, password, ok := r.BasicAuth()
+	if !ok {
+		return "", ErrNoCredentials
+	}
+	hash, known := b[user]
+	if !known {
+		hash = unknownUserHash
+	}
+	if err := bcrypt.CompareHashAndPassword(hash, []byte(password)); err != nil || !known {
+		return "", fmt.Errorf("%w: invalid user or password", task.ErrUnauthenticated)
+	}
+	return user, nil
+}
+
+func (b basic) Challenges() []string {
+	return []string{fmt.Sprintf("Basic realm=%q, charset=\"UTF-8\"", realm)}
+}
+
+// The LoadPasswords function returns a Basic Authenticator for the file at path, with a "<user>:<bcrypt hash>" line per
+// user, like those written by "htpasswd -B". Blank lines, and lines beginning with "#", are ignored.
+func LoadPasswords(path string) (Authenticator, error) {
+	users, err := loadSecrets(path)
+	if err != nil {
+		return nil, err
+	}
+	hashes := make(map[string][]byte, len(users))
+	for user, hash := range users {
+		if _, err := bcrypt.Cost([]byte(hash)); err != nil {
+			return nil, fmt.Errorf("failed to load passwords from %q: invalid bcrypt hash for user %q: %s", path, user, err)
+		}
+		hashes[user] = []byte(hash)
+	}
+	return Basic(hashes), nil
+}
+
+// The LoadGroups function returns the members of each group, for Groups, from the file at path, with a
+// "<group>:<user>,<user>" line per group. Blank lines, and lines beginning with "#", are ignored.
+func LoadGroups(path string) (map[string][]string, error) {
+	groups, err := loadSecrets(path)
+	if err != nil {
+		return nil, err
+	}
+	members := make(map[string][]string, len(groups))
+	for group, users := range groups {
+		for _, user := range strings.Split(users, ",") {
+			if user = strings.TrimSpace(user); user == "" {
+				return nil, fmt.Errorf("failed to load groups from %q: empty user in group %q", path, group)
+			}
+			members[group] = append(members[group], user)
+		}
+	}
+	return members, nil
+}
+
+// The loadSecrets function reads the file at path, and returns the secret of each user, from a "<user>:<secret>" line
+// per user.
+func loadSecrets(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %q: %s", path, err)
+	}
+	defer f.Close()
+	secrets, err := readSecrets(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %q: %s", path, err)
+	}
+	return secrets, nil
+}
+
+// The readSecrets function reads the secret of each user from r, with a "<user>:<secret>" line per user. Blank lines,
+// and lines beginning with "#", are ignored.
+func readSecrets(r io.Reader) (map[string]string, error) {
+	secrets := make(map[string]string)
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.Index(line, ":")
+		if i <= 0 || i == len(line)-1 {
+			return nil, fmt.Errorf("line %d: expected <user>:<secret>", n)
+		}
+		user, secret := line[:i], line[i+1:]
+		if _, ok := secrets[user]; ok {
+			return nil, fmt.Errorf("line %d: duplicate user %q", n, user)
+		}
+		secrets[user] = secret
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return secrets, nil
+}
@@ -0,0 +1,203 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/jmank88/todo/task"
+)
+
+// The request function returns a request with the given Authorization header, if not empty.
+func request(authorization string) *http.Request {
+	r := httptest.NewRequest("GET", "/", nil)
+	if authorization != "" {
+		r.Header.Set("Authorization", authorization)
+	}
+	return r
+}
+
+// Tests authenticating bearer tokens.
+func TestTokens(t *testing.T) {
+	a := Tokens(map[string]string{"alice": "secret-a", "bob": "secret-b"})
+	for _, test := range []struct {
+		authorization string
+		user          string
+		err           error
+	}{
+		{"Bearer secret-a", "alice", nil},
+		{"bearer secret-b", "bob", nil},
+		{"Bearer secret-c", "", task.ErrUnauthenticated},
+		{"Bearer ", "", ErrNoCredentials},
+		{"Basic YWxpY2U6c2VjcmV0LWE=", "", ErrNoCredentials},
+		{"", "", ErrNoCredentials},
+	} {
+		user, err := a.Authenticate(request(test.authorization))
+		if user != test.user || (test.err == nil) != (err == nil) || !errors.Is(err, test.err) {
+			t.Errorf("%q: expected %q and %v but got %q and %v", test.authorization, test.user, test.err, user, err)
+		}
+	}
+}
+
+// Tests authenticating HTTP Basic credentials against bcrypt hashes.
+func TestBasic(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	a := Basic(map[string][]byte{"alice": hash})
+	for _, test := range []struct {
+		user, password string
+		expected       string
+		err            error
+	}{
+		{"alice", "password", "alice", nil},
+		{"alice", "wrong", "", task.ErrUnauthenticated},
+		{"mallory", "password", "", task.ErrUnauthenticated},
+	} {
+		r := request("")
+		r.SetBasicAuth(test.user, test.password)
+		user, err := a.Authenticate(r)
+		if user != test.expected || (test.err == nil) != (err == nil) || !errors.Is(err, test.err) {
+			t.Errorf("%s:%s: expected %q and %v but got %q and %v", test.user, test.password, test.expected, test.err, user, err)
+		}
+	}
+	if _, err := a.Authenticate(request("Bearer token")); !errors.Is(err, ErrNoCredentials) {
+		t.Errorf("expected %v but got %v", ErrNoCredentials, err)
+	}
+}
+
+// Tests that the middleware passes the user to the handler, marking admins, and rejects unauthenticated requests with
+// challenges.
+func TestMiddleware(t *testing.T) {
+	h := Middleware(Any(Tokens(map[string]string{"alice": "secret"}), Basic(nil)), http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(task.User(r.Context())))
+		}))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, request("Bearer secret"))
+	if w.Code != http.StatusOK || w.Body.String() != "alice" {
+		t.Fatalf("expected %d and alice but got %d and %q", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	for _, authorization := range []string{"", "Bearer wrong", "Basic Ym9iOnBhc3N3b3Jk"} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, request(authorization))
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("%q: expected %d but got %d", authorization, http.StatusUnauthorized, w.Code)
+		}
+		expected := []string{`Bearer realm="todo"`, `Basic realm="todo", charset="UTF-8"`}
+		if got := w.Header()["Www-Authenticate"]; !reflect.DeepEqual(got, expected) {
+			t.Errorf("%q: expected challenges %v but got %v", authorization, expected, got)
+		}
+	}
+
+	admin := Middleware(Tokens(map[string]string{"alice": "a", "root": "r"}), http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, task.IsAdmin(r.Context()))
+		}), Admins("root"))
+	for token, expected := range map[string]string{"a": "false", "r": "true"} {
+		w := httptest.NewRecorder()
+		admin.ServeHTTP(w, request("Bearer "+token))
+		if w.Body.String() != expected {
+			t.Errorf("%q: expected admin %s but got %s", token, expected, w.Body.String())
+		}
+	}
+
+	grouped := Middleware(Tokens(map[string]string{"alice": "a", "bob": "b"}), http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, task.Groups(r.Context()))
+		}), Groups(map[string][]string{"team": {"alice", "bob"}, "ops": {"alice"}}))
+	for token, expected := range map[string]string{"a": "[ops team]", "b": "[team]"} {
+		w := httptest.NewRecorder()
+		grouped.ServeHTTP(w, request("Bearer "+token))
+		if w.Body.String() != expected {
+			t.Errorf("%q: expected groups %s but got %s", token, expected, w.Body.String())
+		}
+	}
+
+	failing := Middleware(AuthenticatorFunc(func(*http.Request) (string, error) {
+		return "", errors.New("backend down")
+	}), http.NotFoundHandler())
+	w = httptest.NewRecorder()
+	failing.ServeHTTP(w, request(""))
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("expected %d but got %d", http.StatusInternalServerError, w.Code)
+	}
+}
+
+// Tests loading token and password files.
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "auth")
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	defer os.RemoveAll(dir)
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal("unexpected error: ", err)
+		}
+		return path
+	}
+
+	a, err := LoadTokens(write("tokens", "# comment\n\nalice:secret:with:colons\nbob:other\n"))
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if user, err := a.Authenticate(request("Bearer secret:with:colons")); err != nil || user != "alice" {
+		t.Fatalf("expected alice but got %q and %v", user, err)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	a, err = LoadPasswords(write("passwords", "alice:"+string(hash)+"\n"))
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	r := request("")
+	r.SetBasicAuth("alice", "password")
+	if user, err := a.Authenticate(r); err != nil || user != "alice" {
+		t.Fatalf("expected alice but got %q and %v", user, err)
+	}
+
+	for name, content := range map[string]string{
+		"missing secret":  "alice:\n",
+		"missing user":    ":secret\n",
+		"no separator":    "alice\n",
+		"duplicate user":  "alice:a\nalice:b\n",
+		"duplicate token": "alice:a\nbob:a\n",
+	} {
+		if _, err := LoadTokens(write("invalid", content)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+	groups, err := LoadGroups(write("groups", "team: alice, bob\nops:alice\n"))
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if expected := map[string][]string{"team": {"alice", "bob"}, "ops": {"alice"}}; !reflect.DeepEqual(groups, expected) {
+		t.Fatalf("expected %v but got %v", expected, groups)
+	}
+	if _, err := LoadGroups(write("invalid", "team:alice,,bob\n")); err == nil {
+		t.Error("expected error for empty user")
+	}
+	if _, err := LoadPasswords(write("plain", "alice:password\n")); err == nil || !strings.Contains(err.Error(), "bcrypt") {
+		t.Errorf("expected an invalid hash error but got %v", err)
+	}
+	if _, err := LoadTokens(filepath.Join(dir, "missing")); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
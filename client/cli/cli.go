@@ -1,68 +1,590 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"flag"
+	"fmt"
 	"log"
+	"os"
+	"os/signal"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/jmank88/todo/client"
 	"github.com/jmank88/todo/task"
 )
 
+// The tags are the values of the repeatable tag flag.
+var tags stringsFlag
+
+func init() {
+	flag.Var(&tags, "tag", "task tag. may be repeated. used for put, post, and edit, where an empty tag clears the tags, and to only get tasks with any of the tags for get all, next, and order")
+}
+
 var (
-	host        = flag.String("host", "http://localhost:8080", "http task host to connect to")
-	method      = flag.String("X", "", "method to execute. required. must be one of 'GET', 'PUT', or 'DEL'")
-	id          = flag.String("id", "", "task id. required for delete, optional for get and put")
-	title       = flag.String("title", "", "task title. only used for put")
-	description = flag.String("description", "", "task description. only used for put")
+	host         = flag.String("host", "http://localhost:8080", "http task host to connect to")
+	token        = flag.String("token", os.Getenv("TODO_TOKEN"), "bearer token to authenticate with. defaults to $TODO_TOKEN")
+	method       = flag.String("X", "", "method to execute. required. must be one of 'GET', 'PUT', 'POST', 'EDIT', 'DEL', 'DONE', 'REOPEN', 'TREE', 'NEXT', 'ORDER', 'UPCOMING', 'SEARCH', 'TAGS', 'LISTS', 'PUT-LIST', 'DEL-LIST', 'SHARE', 'UNSHARE', or 'WATCH'")
+	id           = flag.String("id", "", "task id. required for edit, delete, done, reopen, and tree, optional for get, put, and post")
+	title        = flag.String("title", "", "task title. used for put, post, and edit")
+	description  = flag.String("description", "", "task description. used for put, post, and edit")
+	status       = flag.String("status", "", "task status. one of 'open', 'in-progress', 'done', or 'cancelled'. used for put, post, and edit, and as a comma separated list to only get tasks with any of the statuses for get all, next, and order")
+	priority     = flag.String("priority", "", "task priority. one of 'P0' to 'P4'. used for put, post, and edit, where empty clears the priority, and as a comma separated list to only get tasks with any of the priorities, or 'none', for get all, next, and order")
+	start        = flag.String("start", "", "task start time, as RFC 3339 or local 'YYYY-MM-DD[THH:MM]'. used for put, post, and edit")
+	due          = flag.String("due", "", "task due time, as RFC 3339 or local 'YYYY-MM-DD[THH:MM]'. used for put, post, and edit")
+	overdue      = flag.Bool("overdue", false, "only get overdue tasks. only used for get all, next, and order")
+	today        = flag.Bool("today", false, "only get tasks due today. only used for get all, next, and order")
+	dueBefore    = flag.String("due-before", "", "only get tasks due before this time. only used for get all, next, and order")
+	dueAfter     = flag.String("due-after", "", "only get tasks due at or after this time. only used for get all, next, and order")
+	allTags      = flag.Bool("all-tags", false, "only get tasks with all of the tags, rather than any. only used for get all, next, and order")
+	limit        = flag.Int("limit", 0, "maximum number of tasks to get, or per page with -all. only used for get all")
+	all          = flag.Bool("all", false, "get every page of tasks. only used for get all")
+	cursor       = flag.String("cursor", "", "continue getting tasks from a previous page. only used for get all")
+	listID       = flag.String("list", os.Getenv("TODO_LIST"), "task list id. defaults to $TODO_LIST. used for put and post, edit when given explicitly, where empty removes the task from its list, and to only get tasks in the list for get all, next, and order. required for put-list, del-list, share, and unshare")
+	name         = flag.String("name", "", "list name. used for put-list")
+	user         = flag.String("user", "", "user to share the list with. share and unshare require one of -user or -group")
+	group        = flag.String("group", "", "group to share the list with. share and unshare require one of -user or -group")
+	role         = flag.String("role", "viewer", "role to share the list with. one of 'viewer', who may read its tasks, or 'editor', who may also write them. only used for share")
+	parent       = flag.String("parent", "", "parent task id, making the task a subtask. used for put, post, and edit, where empty makes the task top level, and to only get subtasks of the parent for get all, next, and order")
+	blockedBy    = flag.String("blocked-by", "", "comma separated ids of the tasks blocking the task. used for put, post, and edit, where empty clears the blockers")
+	recurrence   = flag.String("recurrence", "", "RFC 5545 recurrence rule repeating the task from its due time, like 'FREQ=WEEKLY;BYDAY=MO'. used for put, post, and edit, where empty stops the task repeating")
+	from         = flag.String("from", "", "start of the range of upcoming occurrences. defaults to now. only used for upcoming")
+	to           = flag.String("to", "", "end of the range of upcoming occurrences. defaults to a week after -from. only used for upcoming")
+	searchQuery  = flag.String("q", "", "words to search task titles and descriptions for. required for search")
+	updatedSince = flag.String("updated-since", "", "only get tasks updated at or after this time, or within this long ago, like '2h' or '3d'. only used for get all, next, and order")
+	revision     = flag.Int64("revision", 0, "only put, edit, delete, done, or reopen the task if it is still at this revision, as printed by get")
+	cascade      = flag.Bool("cascade", false, "delete the task's subtasks too, rather than keeping them as top level tasks. only used for delete")
+	sortKey      = flag.String("sort", "", "order tasks by 'id', 'title', 'due', 'priority', 'created', or 'updated', or descending with a '-' prefix. only used for get all")
+	afterEvent   = flag.Int64("after", 0, "resume watching after the event with this id, as printed by watch. only used for watch")
+	timeout      = flag.Duration("timeout", 30*time.Second, "maximum time to wait for the task host. not used for watch")
 )
 
 func main() {
 	flag.Parse()
 
 	if *method == "" {
-		log.Fatal("no method (-X) specified. must be one of 'GET', 'PUT', or 'DEL'")
+		log.Fatal("no method (-X) specified. must be one of 'GET', 'PUT', 'POST', 'EDIT', 'DEL', 'DONE', 'REOPEN', 'TREE', 'NEXT', 'ORDER', 'UPCOMING', 'SEARCH', 'TAGS', 'LISTS', 'PUT-LIST', 'DEL-LIST', 'SHARE', 'UNSHARE', or 'WATCH'")
 	}
 
-	taskClient := client.NewClient(client.Host(*host))
+	options := []client.Option{client.Host(*host)}
+	if *token != "" {
+		options = append(options, client.Token(*token))
+	}
+	taskClient := client.NewClient(options...)
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	if *revision != 0 {
+		ctx = task.IfMatch(ctx, *revision)
+	}
 
 	switch strings.ToUpper(*method) {
 	case "GET":
 		if *id == "" {
-			tasks, err := taskClient.GetAll()
-			if err != nil {
-				log.Fatalf("failed to get all tasks: %s", err)
-			}
-			log.Printf("%#v\n", tasks)
+			list(ctx, taskClient)
 		} else {
-			task, err := taskClient.Get(*id)
-			if err != nil {
-				log.Fatalf("failed to get task %q: %s", *id, err)
-			}
-			if task == nil {
+			t, err := taskClient.Get(ctx, *id)
+			if errors.Is(err, task.ErrNotFound) {
 				log.Fatalf("no task found for id %q", *id)
+			} else if err != nil {
+				log.Fatalf("failed to get task %q: %s", *id, err)
 			}
-			log.Printf("%#v\n", task)
+			log.Printf("%#v\n", t)
+			log.Println(ages(*t, time.Now()))
 		}
 	case "DEL":
 		if *id == "" {
 			log.Fatal("no id specified for delete")
 		}
-		if err := taskClient.Delete(*id); err != nil {
+		del := taskClient.Delete
+		if *cascade {
+			del = taskClient.DeleteTree
+		}
+		if err := del(ctx, *id); errors.Is(err, task.ErrNotFound) {
+			log.Fatalf("no task found for id %q", *id)
+		} else if errors.Is(err, task.ErrRevisionMismatch) {
+			log.Fatalf("task %q is no longer at revision %d", *id, *revision)
+		} else if err != nil {
 			log.Fatalf("failed to delete task %q: %s", *id, err)
 		}
 		log.Printf("deleted task %q\n", *id)
 	case "PUT":
-		id, err := taskClient.Put(task.Task{
-			ID:          *id,
-			Title:       *title,
-			Description: *description,
-		})
-		if err != nil {
+		t := flagTask()
+		id, created, err := taskClient.Put(ctx, t)
+		if errors.Is(err, task.ErrRevisionMismatch) {
+			log.Fatalf("task %q is no longer at revision %d", t.ID, *revision)
+		} else if err != nil {
 			log.Fatalf("failed to put task: %s", err)
 		}
-		log.Printf("put task %q\n", id)
+		if created {
+			log.Printf("created task %q\n", id)
+		} else {
+			log.Printf("replaced task %q\n", id)
+		}
+	case "POST":
+		created, err := taskClient.Create(ctx, flagTask())
+		if errors.Is(err, task.ErrConflict) {
+			log.Fatalf("task %q already exists", *id)
+		} else if err != nil {
+			log.Fatalf("failed to create task: %s", err)
+		}
+		log.Printf("created task %q\n", created)
+	case "EDIT":
+		edit(ctx, taskClient)
+	case "DONE":
+		setStatus(ctx, taskClient, task.StatusDone)
+	case "REOPEN":
+		setStatus(ctx, taskClient, task.StatusOpen)
+	case "TREE":
+		tree(ctx, taskClient)
+	case "UPCOMING":
+		upcoming(ctx, taskClient)
+	case "SEARCH":
+		search(ctx, taskClient)
+	case "NEXT":
+		tasks, err := taskClient.Actionable(ctx, filter())
+		if err != nil {
+			log.Fatalf("failed to get actionable tasks: %s", err)
+		}
+		printTasks(tasks)
+	case "ORDER":
+		tasks, err := taskClient.Ordered(ctx, filter())
+		if err != nil {
+			log.Fatalf("failed to get ordered tasks: %s", err)
+		}
+		printTasks(tasks)
+	case "TAGS":
+		tagCounts, err := taskClient.Tags(ctx)
+		if err != nil {
+			log.Fatalf("failed to get tags: %s", err)
+		}
+		for _, tc := range tagCounts {
+			log.Printf("%s\t%d\n", tc.Tag, tc.Count)
+		}
+	case "LISTS":
+		lists, err := taskClient.GetLists(ctx)
+		if err != nil {
+			log.Fatalf("failed to get lists: %s", err)
+		}
+		for _, l := range lists {
+			log.Printf("%s\t%s\n", l.ID, l.Name)
+		}
+	case "PUT-LIST":
+		if *listID == "" {
+			log.Fatal("no list specified for put-list")
+		}
+		id, created, err := taskClient.PutList(ctx, task.TaskList{ID: *listID, Name: *name})
+		if err != nil {
+			log.Fatalf("failed to put list: %s", err)
+		}
+		if created {
+			log.Printf("created list %q\n", id)
+		} else {
+			log.Printf("replaced list %q\n", id)
+		}
+	case "DEL-LIST":
+		if *listID == "" {
+			log.Fatal("no list specified for del-list")
+		}
+		if err := taskClient.DeleteList(ctx, *listID); errors.Is(err, task.ErrNotFound) {
+			log.Fatalf("no list found for id %q", *listID)
+		} else if err != nil {
+			log.Fatalf("failed to delete list %q: %s", *listID, err)
+		}
+		log.Printf("deleted list %q and its tasks\n", *listID)
+	case "WATCH":
+		watch(taskClient)
+	case "SHARE", "UNSHARE":
+		if *listID == "" {
+			log.Fatalf("no list specified for %s", strings.ToLower(*method))
+		}
+		grantee := task.Grantee{User: *user, Group: *group}
+		var l *task.TaskList
+		var err error
+		if strings.ToUpper(*method) == "SHARE" {
+			l, err = taskClient.Share(ctx, *listID, task.Grant{Grantee: grantee, Role: task.Role(*role)})
+		} else {
+			l, err = taskClient.Unshare(ctx, *listID, grantee)
+		}
+		if errors.Is(err, task.ErrNotFound) {
+			log.Fatalf("no list found for id %q", *listID)
+		} else if errors.Is(err, task.ErrForbidden) {
+			log.Fatalf("only the owner of list %q may share it", *listID)
+		} else if err != nil {
+			log.Fatalf("failed to %s list %q: %s", strings.ToLower(*method), *listID, err)
+		}
+		log.Printf("list %q is shared with:\n", l.ID)
+		for _, g := range l.Grants {
+			log.Printf("%s\t%s\n", g.Grantee, g.Role)
+		}
 	default:
-		log.Fatalf("unregonized method %q. must be one of 'GET', 'PUT', or 'DEL'", *method)
+		log.Fatalf("unregonized method %q. must be one of 'GET', 'PUT', 'POST', 'EDIT', 'DEL', 'DONE', 'REOPEN', 'TREE', 'NEXT', 'ORDER', 'UPCOMING', 'SEARCH', 'TAGS', 'LISTS', 'PUT-LIST', 'DEL-LIST', 'SHARE', 'UNSHARE', or 'WATCH'", *method)
+	}
+}
+
+// The watch function prints the events of changes to tasks as they happen, until interrupted. Dropped streams are
+// resumed after the last event printed.
+func watch(ti task.TaskInterface) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	after := *afterEvent
+	for {
+		events, err := ti.Watch(ctx, after)
+		if errors.Is(err, task.ErrExpired) {
+			log.Fatalf("event %d has expired. watch again without -after", after)
+		} else if err != nil {
+			log.Fatalf("failed to watch: %s", err)
+		}
+		for e := range events {
+			log.Printf("%d\t%s\t%s\t%s\n", e.ID, e.Type, e.Task.ID, e.Task.Title)
+			after = e.ID
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("reconnecting after event %d\n", after)
+		time.Sleep(time.Second)
+	}
+}
+
+// The flagTask function returns a task built from the task field flags.
+func flagTask() task.Task {
+	return task.Task{
+		ID:          *id,
+		Title:       *title,
+		Description: *description,
+		Status:      task.Status(*status),
+		Priority:    task.Priority(*priority),
+		Start:       timeFlag("start", *start),
+		Due:         timeFlag("due", *due),
+		Tags:        tags,
+		List:        *listID,
+		Parent:      *parent,
+		BlockedBy:   splitIDs(*blockedBy),
+		Recurrence:  *recurrence,
+	}
+}
+
+// The splitIDs function splits a comma separated list of ids, or returns nil if s is empty.
+func splitIDs(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
+
+// The edit function updates only the task fields whose flags were explicitly set. Empty start, due, list, and blocked-by
+// flags clear the field. The default list from $TODO_LIST is not applied, so that editing does not move tasks between lists.
+func edit(ctx context.Context, taskClient task.TaskInterface) {
+	if *id == "" {
+		log.Fatal("no id specified for edit")
+	}
+	patch := make(map[string]interface{})
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "title", "description", "status", "priority", "parent", "recurrence":
+			patch[f.Name] = f.Value.String()
+		case "list":
+			if *listID != "" {
+				patch["list"] = *listID
+			} else {
+				patch["list"] = nil
+			}
+		case "start", "due":
+			if t := timeFlag(f.Name, f.Value.String()); t != nil {
+				patch[f.Name] = t
+			} else {
+				patch[f.Name] = nil
+			}
+		case "blocked-by":
+			if ids := splitIDs(*blockedBy); ids != nil {
+				patch["blocked_by"] = ids
+			} else {
+				patch["blocked_by"] = nil
+			}
+		case "tag":
+			if len(tags) > 0 {
+				patch["tags"] = tags
+			} else {
+				patch["tags"] = nil
+			}
+		}
+	})
+	if len(patch) == 0 {
+		log.Fatal("no fields specified for edit")
+	}
+	bs, err := json.Marshal(patch)
+	if err != nil {
+		log.Fatalf("failed to serialize patch: %s", err)
+	}
+	t, err := taskClient.Update(ctx, *id, bs)
+	if errors.Is(err, task.ErrNotFound) {
+		log.Fatalf("no task found for id %q", *id)
+	} else if errors.Is(err, task.ErrRevisionMismatch) {
+		log.Fatalf("task %q is no longer at revision %d", *id, *revision)
+	} else if err != nil {
+		log.Fatalf("failed to edit task %q: %s", *id, err)
+	}
+	log.Printf("edited task %q, now at revision %d\n", t.ID, t.Revision)
+}
+
+// The setStatus function sets the status of the task specified by the id flag.
+func setStatus(ctx context.Context, taskClient task.TaskInterface, status task.Status) {
+	if *id == "" {
+		log.Fatalf("no id specified for %s", strings.ToLower(*method))
+	}
+	t, err := taskClient.SetStatus(ctx, *id, status)
+	if errors.Is(err, task.ErrNotFound) {
+		log.Fatalf("no task found for id %q", *id)
+	} else if errors.Is(err, task.ErrRevisionMismatch) {
+		log.Fatalf("task %q is no longer at revision %d", *id, *revision)
+	} else if err != nil {
+		log.Fatalf("failed to set status of task %q: %s", *id, err)
+	}
+	log.Printf("task %q is %s\n", t.ID, t.Status)
+}
+
+// The tree function prints the task specified by the id flag, and its subtasks, as an indented checklist.
+func tree(ctx context.Context, taskClient task.TaskInterface) {
+	if *id == "" {
+		log.Fatal("no id specified for tree")
+	}
+	t, err := taskClient.Get(ctx, *id)
+	if errors.Is(err, task.ErrNotFound) {
+		log.Fatalf("no task found for id %q", *id)
+	} else if err != nil {
+		log.Fatalf("failed to get task %q: %s", *id, err)
+	}
+	children, err := taskClient.Children(ctx, *id)
+	if err != nil {
+		log.Fatalf("failed to get subtasks of task %q: %s", *id, err)
+	}
+	var b strings.Builder
+	writeTree(&b, task.Tree{Task: *t, Children: children}, 0)
+	log.Printf("\n%s", b.String())
+}
+
+// The writeTree function writes t as a checklist line indented by depth, followed by its children one level deeper.
+func writeTree(b *strings.Builder, t task.Tree, depth int) {
+	check := " "
+	switch t.Status {
+	case task.StatusDone:
+		check = "x"
+	case task.StatusCancelled:
+		check = "-"
+	case task.StatusInProgress:
+		check = "~"
+	}
+	title := t.Title
+	if title == "" {
+		title = t.ID
+	}
+	fmt.Fprintf(b, "%s[%s] %s (%s)\n", strings.Repeat("  ", depth), check, title, t.ID)
+	for _, c := range t.Children {
+		writeTree(b, c, depth+1)
+	}
+}
+
+// The upcoming function prints the occurrences of tasks from the from flag until the to flag, one per line.
+func upcoming(ctx context.Context, taskClient task.TaskInterface) {
+	start := time.Now()
+	if t := timeFlag("from", *from); t != nil {
+		start = *t
+	}
+	end := start.AddDate(0, 0, 7)
+	if t := timeFlag("to", *to); t != nil {
+		end = *t
+	}
+	occurrences, err := taskClient.Occurrences(ctx, start, end, filter())
+	if err != nil {
+		log.Fatalf("failed to get upcoming occurrences: %s", err)
+	}
+	for _, o := range occurrences {
+		title := o.Task.Title
+		if title == "" {
+			title = o.Task.ID
+		}
+		log.Printf("%s\t%s\t%s\n", o.Due.Local().Format("Mon 2006-01-02 15:04"), o.Task.ID, title)
+	}
+}
+
+// The search function prints the tasks matching the q flag, and the filter flags, one per line, with a snippet of the
+// matching text, most relevant first.
+func search(ctx context.Context, taskClient task.TaskInterface) {
+	if *searchQuery == "" {
+		log.Fatal("no query (-q) specified for search")
+	}
+	results, err := taskClient.Search(ctx, *searchQuery, filter())
+	if errors.Is(err, task.ErrInvalid) {
+		log.Fatalf("invalid search query %q: %s", *searchQuery, err)
+	} else if err != nil {
+		log.Fatalf("failed to search tasks: %s", err)
+	}
+	for _, r := range results {
+		log.Printf("%s\t%s\n", r.Task.ID, r.Snippet)
+	}
+}
+
+// The printTasks function prints a line per task, with its id, title, the ids of the tasks blocking it, and how long ago
+// it was last updated.
+func printTasks(tasks []task.Task) {
+	now := time.Now()
+	for _, t := range tasks {
+		line := t.ID + "\t" + t.Title
+		if len(t.BlockedBy) > 0 {
+			line += "\t(blocked by " + strings.Join(t.BlockedBy, ", ") + ")"
+		}
+		if t.UpdatedAt != nil {
+			line += "\tupdated " + age(*t.UpdatedAt, now)
+		}
+		log.Println(line)
+	}
+}
+
+// The ages function describes when t was created, and by whom, and when it was last updated, relative to now.
+func ages(t task.Task, now time.Time) string {
+	if t.CreatedAt == nil || t.UpdatedAt == nil {
+		return "created and updated at unknown times"
+	}
+	created := "created " + age(*t.CreatedAt, now)
+	if t.CreatedBy != "" {
+		created += " by " + t.CreatedBy
+	}
+	return created + ", updated " + age(*t.UpdatedAt, now)
+}
+
+// The age function describes how long before now t was, in the largest whole unit, like "3d ago".
+func age(t time.Time, now time.Time) string {
+	switch d := now.Sub(t); {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", d/time.Minute)
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", d/time.Hour)
+	default:
+		return fmt.Sprintf("%dd ago", d/(24*time.Hour))
+	}
+}
+
+// The list function gets a page of tasks, or every page with the all flag.
+func list(ctx context.Context, taskClient task.TaskInterface) {
+	options := task.ListOptions{
+		Filter: filter(),
+		Limit:  *limit,
+		Cursor: *cursor,
+		Sort:   task.Sort(*sortKey),
+	}
+	if *all {
+		var tasks []task.Task
+		it := client.NewIterator(taskClient, options)
+		for it.Next(ctx) {
+			tasks = append(tasks, it.Task())
+		}
+		if err := it.Err(); err != nil {
+			log.Fatalf("failed to get all tasks: %s", err)
+		}
+		log.Printf("%#v\n", tasks)
+		return
+	}
+	page, err := taskClient.List(ctx, options)
+	if err != nil {
+		log.Fatalf("failed to get tasks: %s", err)
+	}
+	log.Printf("%#v\n", page.Tasks)
+	if page.Next != "" {
+		log.Printf("more tasks available with -cursor=%s\n", page.Next)
+	}
+}
+
+// The filter function returns a task.Filter built from the filter flags.
+func filter() task.Filter {
+	var f task.Filter
+	if *today {
+		f = task.DueOn(time.Now())
+	}
+	f.Overdue = *overdue
+	if *status != "" {
+		for _, s := range strings.Split(*status, ",") {
+			f.Statuses = append(f.Statuses, task.Status(s))
+		}
+	}
+	if *priority != "" {
+		for _, p := range strings.Split(*priority, ",") {
+			if p == "none" {
+				p = ""
+			}
+			f.Priorities = append(f.Priorities, task.Priority(p))
+		}
+	}
+	f.Tags = tags
+	f.AllTags = *allTags
+	f.List = *listID
+	f.Parent = *parent
+	if t := timeFlag("due-before", *dueBefore); t != nil {
+		f.DueBefore = t
+	}
+	if t := timeFlag("due-after", *dueAfter); t != nil {
+		f.DueAfter = t
+	}
+	f.UpdatedSince = sinceFlag("updated-since", *updatedSince)
+	return f
+}
+
+// The sinceFlag function parses the value of the named flag as a time, or as a duration before now, like "90m", or a
+// number of days, like "3d". Returns nil if it is empty.
+func sinceFlag(name, value string) *time.Time {
+	if days := strings.TrimSuffix(value, "d"); days != value {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			t := time.Now().AddDate(0, 0, -n)
+			return &t
+		}
+	}
+	if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		t := time.Now().Add(-d)
+		return &t
+	}
+	return timeFlag(name, value)
+}
+
+// The timeLayouts are the accepted layouts for time flags. All but RFC 3339 are interpreted in local time.
+var timeLayouts = []string{time.RFC3339, "2006-01-02T15:04", "2006-01-02"}
+
+// The timeFlag function parses the value of the named time flag, or returns nil if it is empty.
+func timeFlag(name, value string) *time.Time {
+	if value == "" {
+		return nil
+	}
+	t, err := parseTime(value)
+	if err != nil {
+		log.Fatalf("invalid -%s flag: %s", name, err)
+	}
+	return &t
+}
+
+// The parseTime function parses s with the first matching layout from timeLayouts.
+func parseTime(s string) (time.Time, error) {
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized time %q. must be RFC 3339 or 'YYYY-MM-DD[THH:MM]'", s)
+}
+
+// A stringsFlag is a flag.Value which may be repeated, collecting each non-empty value.
+type stringsFlag []string
+
+func (s *stringsFlag) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringsFlag) Set(value string) error {
+	if value != "" {
+		*s = append(*s, value)
 	}
+	return nil
 }
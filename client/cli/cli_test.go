@@ -0,0 +1,41 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jmank88/todo/task"
+)
+
+// Tests that the share and unshare methods are matched regardless of case.
+func TestShareMethod(t *testing.T) {
+	for _, test := range []struct {
+		method   string
+		expected string
+	}{
+		{"share", "PUT"},
+		{"SHARE", "PUT"},
+		{"unshare", "DELETE"},
+		{"Unshare", "DELETE"},
+	} {
+		var got string
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+
+			got = r.Method
+			if err := json.NewEncoder(w).Encode(task.TaskList{ID: "shopping"}); err != nil {
+				t.Error("unexpected error encoding response: ", err)
+			}
+		}))
+
+		*host, *token, *method, *listID, *user = ts.URL, "", test.method, "shopping", "bob"
+		main()
+		ts.Close()
+
+		if got != test.expected {
+			t.Errorf("expected %s for %q but got %s", test.expected, test.method, got)
+		}
+	}
+}
@@ -2,12 +2,17 @@
 package client
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/jmank88/todo/task"
 )
@@ -44,110 +49,730 @@ func HTTPClient(hc *http.Client) Option {
 	}
 }
 
+// The Token function returns an Option for authenticating a client's requests with a bearer token.
+func Token(token string) Option {
+	return func(c *client) {
+		c.authorize = func(req *http.Request) {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+	}
+}
+
+// The BasicAuth function returns an Option for authenticating a client's requests with HTTP Basic credentials.
+func BasicAuth(user, password string) Option {
+	return func(c *client) {
+		c.authorize = func(req *http.Request) {
+			req.SetBasicAuth(user, password)
+		}
+	}
+}
+
 // A client implements task.TaskInterface, and executes commands against a remote host with json over http.
 type client struct {
 	httpClient *http.Client
 	host       string
+	// Sets the credentials of each request, if not nil.
+	authorize func(*http.Request)
 }
 
-func (c *client) Get(id string) (*task.Task, error) {
-	if id == "" {
-		return nil, errors.New("no id specified")
+// The do method authorizes req, and sends it with the http client.
+func (c *client) do(req *http.Request) (*http.Response, error) {
+	if c.authorize != nil {
+		c.authorize(req)
 	}
-	resp, err := c.httpClient.Get(c.host + "/" + id)
+	return c.httpClient.Do(req)
+}
+
+// The statusErrors map response status codes to the task package errors they represent.
+var statusErrors = map[int]error{
+	http.StatusNotFound:            task.ErrNotFound,
+	http.StatusConflict:            task.ErrConflict,
+	http.StatusUnprocessableEntity: task.ErrInvalid,
+	http.StatusNotImplemented:      task.ErrListsUnsupported,
+	http.StatusPreconditionFailed:  task.ErrRevisionMismatch,
+	http.StatusUnauthorized:        task.ErrUnauthenticated,
+	http.StatusForbidden:           task.ErrForbidden,
+	http.StatusGone:                task.ErrExpired,
+}
+
+// The setIfMatch function sets the If-Match header of req to the ETag of the revision expected by ctx, if any. See
+// task.IfMatch.
+func setIfMatch(ctx context.Context, req *http.Request) {
+	if revision, ok := task.ExpectedRevision(ctx); ok {
+		req.Header.Set("If-Match", `"`+strconv.FormatInt(revision, 10)+`"`)
+	}
+}
+
+// The errorResponse function returns an error for a failed response to the described action. The error wraps the task
+// package error corresponding to the response status code, if any, or task.ErrCycle if the response describes one.
+func errorResponse(resp *http.Response, action string) error {
+	errStr, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get task %q: %s", id, err)
+		return fmt.Errorf("failed to read error response from %s attempt: %s", action, err)
+	}
+	return statusError(resp.StatusCode, bytes.TrimSpace(errStr), action)
+}
+
+// The statusError function returns an error for the described action, which failed with the http status code and
+// error message errStr. The error wraps the task package error corresponding to the status code, if any, or
+// task.ErrCycle if errStr describes one.
+func statusError(status int, errStr []byte, action string) error {
+	if status == http.StatusUnprocessableEntity && bytes.Contains(errStr, []byte(task.ErrCycle.Error())) {
+		return fmt.Errorf("failed to %s: %w: %s", action, task.ErrCycle, errStr)
+	}
+	if statusErr, ok := statusErrors[status]; ok {
+		return fmt.Errorf("failed to %s: %w: %s", action, statusErr, errStr)
+	}
+	return fmt.Errorf("failed to %s: %s", action, errStr)
+}
+
+// The checkID function returns an error for the described action if id is not a valid task id.
+func checkID(id, action string) error {
+	if err := task.ValidateID(id); err != nil {
+		return fmt.Errorf("failed to %s: %w", action, err)
+	}
+	return nil
+}
+
+// The taskURL method returns the url of the task with the given id.
+func (c *client) taskURL(id string) string {
+	return c.host + "/" + url.PathEscape(id)
+}
+
+// The listURL method returns the url of the list with the given id.
+func (c *client) listURL(id string) string {
+	return c.host + "/lists/" + url.PathEscape(id)
+}
+
+func (c *client) Get(ctx context.Context, id string) (*task.Task, error) {
+	action := fmt.Sprintf("get task %q", id)
+	if err := checkID(id, action); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", c.taskURL(id), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create http request for task %q: %s", id, err)
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to %s: %w", action, err)
+	}
+	defer resp.Body.Close()
+
+	return decodeTask(resp, action)
+}
+
+// The decodeTask function decodes a task from a successful response, or returns an error for the described action.
+func decodeTask(resp *http.Response, action string) (*task.Task, error) {
+	if resp.StatusCode != http.StatusOK {
+		return nil, errorResponse(resp, action)
+	}
+	var task task.Task
+	if err := json.NewDecoder(resp.Body).Decode(&task); err != nil {
+		return nil, fmt.Errorf("failed to deserialize response to %s: %s", action, err)
+	}
+	return &task, nil
+}
+
+func (c *client) GetAll(ctx context.Context) ([]task.Task, error) {
+	return c.Find(ctx, task.Filter{})
+}
+
+// The Find method lists all tasks matching filter, by requesting each page in turn.
+func (c *client) Find(ctx context.Context, filter task.Filter) ([]task.Task, error) {
+	var tasks []task.Task
+	it := NewIterator(c, task.ListOptions{Filter: filter, Limit: task.MaxLimit})
+	for it.Next(ctx) {
+		tasks = append(tasks, it.Task())
+	}
+	return tasks, it.Err()
+}
+
+func (c *client) List(ctx context.Context, options task.ListOptions) (*task.Page, error) {
+	// The cursor is opaque, and only validated by the server.
+	validate := options
+	validate.Cursor = ""
+	if _, err := validate.After(); err != nil {
+		return nil, fmt.Errorf("failed to list tasks: %w", err)
+	}
+	query := filterQuery(options.Filter)
+	if options.Limit != 0 {
+		query.Set("limit", strconv.Itoa(options.Limit))
+	}
+	if options.Cursor != "" {
+		query.Set("cursor", options.Cursor)
+	}
+	if options.Sort != "" {
+		query.Set("sort", string(options.Sort))
+	}
+	u := c.host + "/"
+	if query := query.Encode(); query != "" {
+		u += "?" + query
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create http request for tasks: %s", err)
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tasks: %w", err)
 	}
 	defer resp.Body.Close()
-	switch resp.StatusCode {
-	case http.StatusNotFound:
-		return nil, nil
-	case http.StatusOK:
-		var task task.Task
-		if err := json.NewDecoder(resp.Body).Decode(&task); err != nil {
-			return nil, fmt.Errorf("failed to deserialize task %q: %s", id, err)
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errorResponse(resp, "list tasks")
+	}
+
+	page := &task.Page{}
+	if err := json.NewDecoder(resp.Body).Decode(&page.Tasks); err != nil {
+		return nil, fmt.Errorf("failed to deserialize tasks: %s", err)
+	}
+	if page.Next, err = nextCursor(resp.Header.Get("Link")); err != nil {
+		return nil, fmt.Errorf("failed to list tasks: %s", err)
+	}
+	return page, nil
+}
+
+// The nextCursor function returns the cursor from the url of the rel="next" link in an RFC 8288 Link header, or an
+// empty string if there is none.
+func nextCursor(header string) (string, error) {
+	for _, link := range strings.Split(header, ",") {
+		parts := strings.Split(link, ";")
+		target := strings.TrimSpace(parts[0])
+		if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+			continue
 		}
-		return &task, nil
-	default:
-		errStr, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			return nil, fmt.Errorf("failed to read error response from get task %q attempt: %s", id, err)
+		for _, param := range parts[1:] {
+			if strings.TrimSpace(param) != `rel="next"` {
+				continue
+			}
+			u, err := url.Parse(target[1 : len(target)-1])
+			if err != nil {
+				return "", fmt.Errorf("invalid next link %q: %s", target, err)
+			}
+			return u.Query().Get("cursor"), nil
 		}
-		return nil, fmt.Errorf("failed to get task %q: %s", id, errStr)
 	}
+	return "", nil
+}
 
+// The filterQuery function encodes filter as query parameters.
+func filterQuery(filter task.Filter) url.Values {
+	query := url.Values{}
+	if filter.Overdue {
+		query.Set("overdue", "true")
+	}
+	if filter.DueBefore != nil {
+		query.Set("due_before", filter.DueBefore.Format(time.RFC3339))
+	}
+	if filter.DueAfter != nil {
+		query.Set("due_after", filter.DueAfter.Format(time.RFC3339))
+	}
+	for _, status := range filter.Statuses {
+		query.Add("status", string(status))
+	}
+	for _, priority := range filter.Priorities {
+		if priority == "" {
+			query.Add("priority", "none")
+		} else {
+			query.Add("priority", string(priority))
+		}
+	}
+	for _, tag := range filter.Tags {
+		query.Add("tag", tag)
+	}
+	if filter.AllTags {
+		query.Set("tag_match", "all")
+	}
+	if filter.List != "" {
+		query.Set("list", filter.List)
+	}
+	if filter.Parent != "" {
+		query.Set("parent", filter.Parent)
+	}
+	if filter.UpdatedSince != nil {
+		// Updates may be moments apart, so the fraction of a second is kept.
+		query.Set("updated_since", filter.UpdatedSince.Format(time.RFC3339Nano))
+	}
+	return query
+}
+
+func (c *client) Actionable(ctx context.Context, filter task.Filter) ([]task.Task, error) {
+	return c.getView(ctx, "_actionable", filter, "get actionable tasks")
+}
+
+func (c *client) Ordered(ctx context.Context, filter task.Filter) ([]task.Task, error) {
+	return c.getView(ctx, "_order", filter, "get ordered tasks")
 }
 
-func (c *client) GetAll() ([]task.Task, error) {
-	resp, err := c.httpClient.Get(c.host)
+// The getView method gets the tasks matching filter from the view with the given path, for the described action.
+func (c *client) getView(ctx context.Context, view string, filter task.Filter, action string) ([]task.Task, error) {
+	u := c.host + "/" + view
+	if query := filterQuery(filter).Encode(); query != "" {
+		u += "?" + query
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get tasks: %s", err)
+		return nil, fmt.Errorf("failed to create http request for tasks: %s", err)
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to %s: %w", action, err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		errStr, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			return nil, fmt.Errorf("failed to read error response from get all attempt: %s", err)
-		}
-		return nil, fmt.Errorf("failed to get all tasks: %s", errStr)
+		return nil, errorResponse(resp, action)
 	}
 
 	var tasks []task.Task
 	if err := json.NewDecoder(resp.Body).Decode(&tasks); err != nil {
-		return nil, fmt.Errorf("failed to deserialize tasks: %s", err)
+		return nil, fmt.Errorf("failed to deserialize response to %s: %s", action, err)
 	}
 	return tasks, nil
 }
 
-func (c *client) Put(task task.Task) (string, error) {
+func (c *client) Occurrences(ctx context.Context, from, to time.Time, filter task.Filter) ([]task.Occurrence, error) {
+	if err := task.ValidateRange(from, to); err != nil {
+		return nil, fmt.Errorf("failed to get occurrences: %w", err)
+	}
+	query := filterQuery(filter)
+	query.Set("from", from.Format(time.RFC3339Nano))
+	query.Set("to", to.Format(time.RFC3339Nano))
+	req, err := http.NewRequestWithContext(ctx, "GET", c.host+"/_occurrences?"+query.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create http request for occurrences: %s", err)
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get occurrences: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errorResponse(resp, "get occurrences")
+	}
+
+	var occurrences []task.Occurrence
+	if err := json.NewDecoder(resp.Body).Decode(&occurrences); err != nil {
+		return nil, fmt.Errorf("failed to deserialize occurrences: %s", err)
+	}
+	return occurrences, nil
+}
+
+func (c *client) Search(ctx context.Context, query string, filter task.Filter) ([]task.SearchResult, error) {
+	action := fmt.Sprintf("search tasks for %q", query)
+	if _, err := task.SearchTerms(query); err != nil {
+		return nil, fmt.Errorf("failed to %s: %w", action, err)
+	}
+	values := filterQuery(filter)
+	values.Set("q", query)
+	req, err := http.NewRequestWithContext(ctx, "GET", c.host+"/_search?"+values.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create http request for search: %s", err)
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to %s: %w", action, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errorResponse(resp, action)
+	}
+
+	var results []task.SearchResult
+	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+		return nil, fmt.Errorf("failed to deserialize response to %s: %s", action, err)
+	}
+	return results, nil
+}
+
+func (c *client) Tags(ctx context.Context) ([]task.TagCount, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.host+"/_tags", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create http request for tags: %s", err)
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tags: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errorResponse(resp, "get tags")
+	}
+
+	var tags []task.TagCount
+	if err := json.NewDecoder(resp.Body).Decode(&tags); err != nil {
+		return nil, fmt.Errorf("failed to deserialize tags: %s", err)
+	}
+	return tags, nil
+}
+
+func (c *client) Put(ctx context.Context, task task.Task) (string, bool, error) {
+	bs, err := json.Marshal(task)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to serialize task %v: %s", task, err)
+	}
+	if task.ID != "" {
+		if err := checkID(task.ID, fmt.Sprintf("put task %v", task)); err != nil {
+			return "", false, err
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, "PUT", c.taskURL(task.ID), bytes.NewReader(bs))
+	if err != nil {
+		return "", false, fmt.Errorf("failed to create http request for task %v: %s", task, err)
+	}
+	setIfMatch(ctx, req)
+	resp, err := c.do(req)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to execute put request for task %v: %w", task, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return "", false, errorResponse(resp, fmt.Sprintf("put task %v", task))
+	}
+
+	id, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to read response after putting task %v: %s", task, err)
+	}
+	return string(id), resp.StatusCode == http.StatusCreated, nil
+}
+
+func (c *client) Create(ctx context.Context, task task.Task) (string, error) {
 	bs, err := json.Marshal(task)
 	if err != nil {
 		return "", fmt.Errorf("failed to serialize task %v: %s", task, err)
 	}
-	req, err := http.NewRequest("PUT", c.host, bytes.NewReader(bs))
+	req, err := http.NewRequestWithContext(ctx, "POST", c.host+"/", bytes.NewReader(bs))
 	if err != nil {
 		return "", fmt.Errorf("failed to create http request for task %v: %s", task, err)
 	}
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
-		return "", fmt.Errorf("failed to execute put request for task %v: %s", task, err)
+		return "", fmt.Errorf("failed to execute post request for task %v: %w", task, err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		errStr, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			return "", fmt.Errorf("failed to read error response from task %v put attempt: %s", task, err)
-		}
-		return "", fmt.Errorf("failed to put task %v: %s", task, errStr)
+	if resp.StatusCode != http.StatusCreated {
+		return "", errorResponse(resp, fmt.Sprintf("create task %v", task))
 	}
 
 	id, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("failed to read response after putting task %v: %s", task, err)
+		return "", fmt.Errorf("failed to read response after creating task %v: %s", task, err)
 	}
 	return string(id), nil
 }
 
-func (c *client) Delete(id string) error {
-	req, err := http.NewRequest("DELETE", c.host+"/"+id, nil)
+func (c *client) Update(ctx context.Context, id string, patch []byte) (*task.Task, error) {
+	action := fmt.Sprintf("update task %q", id)
+	if err := checkID(id, action); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "PATCH", c.taskURL(id), bytes.NewReader(patch))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create http request for task %q: %s", id, err)
+	}
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+	setIfMatch(ctx, req)
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute patch request for task %q: %w", id, err)
+	}
+	defer resp.Body.Close()
+
+	return decodeTask(resp, action)
+}
+
+func (c *client) SetStatus(ctx context.Context, id string, status task.Status) (*task.Task, error) {
+	action := fmt.Sprintf("set status of task %q", id)
+	if err := checkID(id, action); err != nil {
+		return nil, err
+	}
+	bs, err := json.Marshal(status)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize status %q: %s", status, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, "PUT", c.taskURL(id)+"/status", bytes.NewReader(bs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create http request for task %q: %s", id, err)
+	}
+	setIfMatch(ctx, req)
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute set status request for task %q: %w", id, err)
+	}
+	defer resp.Body.Close()
+
+	return decodeTask(resp, action)
+}
+
+func (c *client) Children(ctx context.Context, id string) ([]task.Tree, error) {
+	action := fmt.Sprintf("get subtasks of task %q", id)
+	if err := checkID(id, action); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", c.taskURL(id)+"/children", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create http request for task %q: %s", id, err)
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to %s: %w", action, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errorResponse(resp, action)
+	}
+
+	var children []task.Tree
+	if err := json.NewDecoder(resp.Body).Decode(&children); err != nil {
+		return nil, fmt.Errorf("failed to deserialize response to %s: %s", action, err)
+	}
+	return children, nil
+}
+
+func (c *client) Delete(ctx context.Context, id string) error {
+	return c.delete(ctx, id, "")
+}
+
+func (c *client) DeleteTree(ctx context.Context, id string) error {
+	return c.delete(ctx, id, "?cascade=true")
+}
+
+// The delete method deletes the task with the given id, with the given query.
+func (c *client) delete(ctx context.Context, id, query string) error {
+	action := fmt.Sprintf("delete task %q", id)
+	if err := checkID(id, action); err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "DELETE", c.taskURL(id)+query, nil)
 	if err != nil {
 		return fmt.Errorf("failed to create http request for task %q: %s", id, err)
 	}
-	resp, err := c.httpClient.Do(req)
+	setIfMatch(ctx, req)
+	resp, err := c.do(req)
+	if err != nil {
+		return fmt.Errorf("failed to execute delete request for task %s: %w", id, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return errorResponse(resp, action)
+	}
+
+	return nil
+}
+
+func (c *client) GetList(ctx context.Context, id string) (*task.TaskList, error) {
+	action := fmt.Sprintf("get list %q", id)
+	if err := checkID(id, action); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", c.listURL(id), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create http request for list %q: %s", id, err)
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to %s: %w", action, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errorResponse(resp, action)
+	}
+	var list task.TaskList
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("failed to deserialize response to %s: %s", action, err)
+	}
+	return &list, nil
+}
+
+func (c *client) GetLists(ctx context.Context) ([]task.TaskList, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.host+"/lists", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create http request for lists: %s", err)
+	}
+	resp, err := c.do(req)
 	if err != nil {
-		return fmt.Errorf("failed to execute delete request for task %s: %s", id, err)
+		return nil, fmt.Errorf("failed to get lists: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		errStr, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			return fmt.Errorf("failed to read error response from task %q delete attempt: %s", id, err)
+		return nil, errorResponse(resp, "get lists")
+	}
+
+	var lists []task.TaskList
+	if err := json.NewDecoder(resp.Body).Decode(&lists); err != nil {
+		return nil, fmt.Errorf("failed to deserialize lists: %s", err)
+	}
+	return lists, nil
+}
+
+func (c *client) PutList(ctx context.Context, list task.TaskList) (string, bool, error) {
+	bs, err := json.Marshal(list)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to serialize list %v: %s", list, err)
+	}
+	if list.ID != "" {
+		if err := checkID(list.ID, fmt.Sprintf("put list %v", list)); err != nil {
+			return "", false, err
 		}
-		return fmt.Errorf("failed to delete task %q: %s", id, errStr)
+	}
+	req, err := http.NewRequestWithContext(ctx, "PUT", c.listURL(list.ID), bytes.NewReader(bs))
+	if err != nil {
+		return "", false, fmt.Errorf("failed to create http request for list %v: %s", list, err)
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to execute put request for list %v: %w", list, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return "", false, errorResponse(resp, fmt.Sprintf("put list %v", list))
+	}
+
+	id, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to read response after putting list %v: %s", list, err)
+	}
+	return string(id), resp.StatusCode == http.StatusCreated, nil
+}
+
+func (c *client) DeleteList(ctx context.Context, id string) error {
+	action := fmt.Sprintf("delete list %q", id)
+	if err := checkID(id, action); err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "DELETE", c.listURL(id), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create http request for list %q: %s", id, err)
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return fmt.Errorf("failed to execute delete request for list %s: %w", id, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return errorResponse(resp, action)
 	}
 
 	return nil
 }
+
+func (c *client) Share(ctx context.Context, list string, grant task.Grant) (*task.TaskList, error) {
+	action := fmt.Sprintf("share list %q with %s", list, grant.Grantee)
+	if err := checkID(list, action); err != nil {
+		return nil, err
+	}
+	bs, err := json.Marshal(grant)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize grant %v: %s", grant, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, "PUT", c.listURL(list)+"/grants", bytes.NewReader(bs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create http request for list %q: %s", list, err)
+	}
+	return c.grants(req, action)
+}
+
+func (c *client) Unshare(ctx context.Context, list string, grantee task.Grantee) (*task.TaskList, error) {
+	action := fmt.Sprintf("unshare list %q with %s", list, grantee)
+	if err := checkID(list, action); err != nil {
+		return nil, err
+	}
+	query := url.Values{}
+	if grantee.User != "" {
+		query.Set("user", grantee.User)
+	}
+	if grantee.Group != "" {
+		query.Set("group", grantee.Group)
+	}
+	req, err := http.NewRequestWithContext(ctx, "DELETE", c.listURL(list)+"/grants?"+query.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create http request for list %q: %s", list, err)
+	}
+	return c.grants(req, action)
+}
+
+// The grants method executes req, changing the grants of a list for the described action, and returns the list.
+func (c *client) grants(req *http.Request, action string) (*task.TaskList, error) {
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to %s: %w", action, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errorResponse(resp, action)
+	}
+	var list task.TaskList
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("failed to deserialize response to %s: %s", action, err)
+	}
+	return &list, nil
+}
+
+// The Watch method streams the events of the host as Server-Sent Events, resuming after the event with id after with a
+// Last-Event-ID header. The channel is closed when ctx is done, or the stream ends or fails.
+func (c *client) Watch(ctx context.Context, after int64) (<-chan task.Event, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.host+"/events", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create http request for events: %s", err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	if after != 0 {
+		req.Header.Set("Last-Event-ID", strconv.FormatInt(after, 10))
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to watch events: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, errorResponse(resp, "watch events")
+	}
+
+	events := make(chan task.Event)
+	go func() {
+		defer close(events)
+		defer resp.Body.Close()
+		s := bufio.NewScanner(resp.Body)
+		s.Buffer(nil, 1<<20)
+		var data []byte
+		for s.Scan() {
+			line := s.Bytes()
+			if len(line) > 0 {
+				// Only data fields are needed, since each event's data carries its id and type too.
+				if bytes.HasPrefix(line, []byte("data:")) {
+					data = append(data, bytes.TrimPrefix(bytes.TrimPrefix(line, []byte("data:")), []byte(" "))...)
+				}
+				continue
+			}
+			if len(data) == 0 {
+				continue
+			}
+			var e task.Event
+			err := json.Unmarshal(data, &e)
+			data = data[:0]
+			if err != nil {
+				return
+			}
+			select {
+			case events <- e:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events, nil
+}
@@ -1,11 +1,16 @@
 package client
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/jmank88/todo/task"
 )
@@ -34,15 +39,55 @@ func TestGet(t *testing.T) {
 
 	ti := NewClient(Host(ts.URL))
 
-	if got, err := ti.Get(expected.ID); err != nil {
+	if got, err := ti.Get(context.Background(), expected.ID); err != nil {
 		t.Fatal("unexpected error: ", err)
 	} else if got == nil {
 		t.Fatalf("expected %v but got nil", expected)
-	} else if *got != expected {
+	} else if !reflect.DeepEqual(*got, expected) {
 		t.Fatalf("expected %v but got %v", expected, got)
 	}
 }
 
+// Tests mapping error responses to task errors.
+func TestGetErrors(t *testing.T) {
+	for status, expected := range statusErrors {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer r.Body.Close()
+
+			http.Error(w, "test error", status)
+		}))
+
+		ti := NewClient(Host(ts.URL))
+
+		if _, err := ti.Get(context.Background(), "id"); !errors.Is(err, expected) {
+			t.Errorf("expected %v for status %d but got %v", expected, status, err)
+		}
+		ts.Close()
+	}
+
+	if _, err := NewClient().Get(context.Background(), ""); !errors.Is(err, task.ErrInvalid) {
+		t.Errorf("expected %v for empty id but got %v", task.ErrInvalid, err)
+	}
+}
+
+// Tests cancelling a request with its context.
+func TestGetCancelled(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+
+		<-r.Context().Done()
+	}))
+	defer ts.Close()
+
+	ti := NewClient(Host(ts.URL))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := ti.Get(ctx, "id"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected %v but got %v", context.DeadlineExceeded, err)
+	}
+}
+
 // Tests a get all request.
 func TestGetAll(t *testing.T) {
 	expected := []task.Task{
@@ -74,7 +119,7 @@ func TestGetAll(t *testing.T) {
 
 	ti := NewClient(Host(ts.URL))
 
-	if got, err := ti.GetAll(); err != nil {
+	if got, err := ti.GetAll(context.Background()); err != nil {
 		t.Fatal("unexpected error: ", err)
 	} else if len(expected) != len(got) {
 		t.Fatalf("epected %v but got %v", expected, got)
@@ -82,13 +127,82 @@ func TestGetAll(t *testing.T) {
 		expectedMap := indexByID(expected)
 		gotMap := indexByID(got)
 		for id, task := range expectedMap {
-			if gotTask, ok := gotMap[id]; !ok || gotTask != task {
+			if gotTask, ok := gotMap[id]; !ok || !reflect.DeepEqual(gotTask, task) {
 				t.Fatalf("epected %v but got %v", expected, got)
 			}
 		}
 	}
 }
 
+// Tests a get all request with a filter.
+func TestFind(t *testing.T) {
+	after := time.Date(2016, 1, 2, 3, 4, 5, 0, time.UTC)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+
+		query := r.URL.Query()
+		if got := query.Get("overdue"); got != "true" {
+			t.Fatalf("expected overdue %q but got %q", "true", got)
+		}
+		if got := query.Get("due_after"); got != after.Format(time.RFC3339) {
+			t.Fatalf("expected due_after %q but got %q", after.Format(time.RFC3339), got)
+		}
+		if _, ok := query["due_before"]; ok {
+			t.Fatal("unexpected due_before")
+		}
+
+		if err := json.NewEncoder(w).Encode([]task.Task{}); err != nil {
+			t.Fatal("unexpected error encoding json: ", err)
+		}
+	}))
+	defer ts.Close()
+
+	ti := NewClient(Host(ts.URL))
+
+	if _, err := ti.Find(context.Background(), task.Filter{Overdue: true, DueAfter: &after}); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+}
+
+// Tests following the next page links of a paginated list with an Iterator.
+func TestIterator(t *testing.T) {
+	pages := map[string][]task.Task{
+		"":      {{ID: "1"}, {ID: "2"}},
+		"page2": {{ID: "3"}},
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+
+		query := r.URL.Query()
+		if got := query.Get("limit"); got != "2" {
+			t.Fatalf("expected limit %q but got %q", "2", got)
+		}
+		if got := query.Get("sort"); got != "title" {
+			t.Fatalf("expected sort %q but got %q", "title", got)
+		}
+		cursor := query.Get("cursor")
+		if cursor == "" {
+			w.Header().Set("Link", `</?cursor=page2&limit=2&sort=title>; rel="next"`)
+		}
+		if err := json.NewEncoder(w).Encode(pages[cursor]); err != nil {
+			t.Fatal("unexpected error encoding json: ", err)
+		}
+	}))
+	defer ts.Close()
+
+	it := NewIterator(NewClient(Host(ts.URL)), task.ListOptions{Limit: 2, Sort: task.SortTitle})
+	var got []string
+	for it.Next(context.Background()) {
+		got = append(got, it.Task().ID)
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if expected := []string{"1", "2", "3"}; !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected %v but got %v", expected, got)
+	}
+}
+
 // Tests a put request.
 func TestPut(t *testing.T) {
 	testTask := task.Task{
@@ -100,7 +214,7 @@ func TestPut(t *testing.T) {
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer r.Body.Close()
 
-		const expectedPath = "/"
+		expectedPath := "/" + testTask.ID
 		if r.URL.Path != expectedPath {
 			t.Fatalf("expected path %q but got %q", expectedPath, r.URL.Path)
 		}
@@ -108,7 +222,7 @@ func TestPut(t *testing.T) {
 		var task task.Task
 		if err := json.NewDecoder(r.Body).Decode(&task); err != nil {
 			t.Fatal("unexpected error decoding json: ", err)
-		} else if task != testTask {
+		} else if !reflect.DeepEqual(task, testTask) {
 			t.Fatalf("expected %v but got %v", testTask, task)
 		}
 
@@ -120,10 +234,131 @@ func TestPut(t *testing.T) {
 
 	ti := NewClient(Host(ts.URL))
 
-	if got, err := ti.Put(testTask); err != nil {
+	if got, created, err := ti.Put(context.Background(), testTask); err != nil {
 		t.Fatal("unexpected error: ", err)
 	} else if got != testTask.ID {
 		t.Fatalf("expected id %q but got %q", testTask.ID, got)
+	} else if created {
+		t.Fatal("expected replaced task but got created")
+	}
+}
+
+// Tests a create request.
+func TestCreate(t *testing.T) {
+	const generatedId = "generated"
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+
+		if r.Method != "POST" {
+			t.Fatalf("expected method POST but got %s", r.Method)
+		}
+		const expectedPath = "/"
+		if r.URL.Path != expectedPath {
+			t.Fatalf("expected path %q but got %q", expectedPath, r.URL.Path)
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		if _, err := io.WriteString(w, generatedId); err != nil {
+			t.Fatal("unexpected error writing response")
+		}
+	}))
+	defer ts.Close()
+
+	ti := NewClient(Host(ts.URL))
+
+	if got, err := ti.Create(context.Background(), task.Task{Title: "test title"}); err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if got != generatedId {
+		t.Fatalf("expected id %q but got %q", generatedId, got)
+	}
+}
+
+// Tests a create request conflicting with an existing task.
+func TestCreateConflict(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+
+		http.Error(w, "task already exists", http.StatusConflict)
+	}))
+	defer ts.Close()
+
+	ti := NewClient(Host(ts.URL))
+
+	if _, err := ti.Create(context.Background(), task.Task{ID: "id"}); !errors.Is(err, task.ErrConflict) {
+		t.Fatalf("expected %v but got %v", task.ErrConflict, err)
+	}
+}
+
+// Tests a patch request.
+func TestUpdate(t *testing.T) {
+	const testId = "id"
+	const patch = `{"title":"new title"}`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+
+		if r.Method != "PATCH" {
+			t.Fatalf("expected method PATCH but got %s", r.Method)
+		}
+		expectedPath := "/" + testId
+		if r.URL.Path != expectedPath {
+			t.Fatalf("expected path %q but got %q", expectedPath, r.URL.Path)
+		}
+		if ct := r.Header.Get("Content-Type"); ct != "application/merge-patch+json" {
+			t.Fatalf("expected merge patch content type but got %q", ct)
+		}
+		if got, err := ioutil.ReadAll(r.Body); err != nil {
+			t.Fatal("unexpected error reading body: ", err)
+		} else if string(got) != patch {
+			t.Fatalf("expected patch %s but got %s", patch, got)
+		}
+
+		if err := json.NewEncoder(w).Encode(task.Task{ID: testId, Title: "new title"}); err != nil {
+			t.Fatal("unexpected error encoding json: ", err)
+		}
+	}))
+	defer ts.Close()
+
+	ti := NewClient(Host(ts.URL))
+
+	if got, err := ti.Update(context.Background(), testId, []byte(patch)); err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if got == nil || got.Title != "new title" {
+		t.Fatalf("expected updated task but got %v", got)
+	}
+}
+
+// Tests a set status request.
+func TestSetStatus(t *testing.T) {
+	const testId = "id"
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+
+		expectedPath := "/" + testId + "/status"
+		if r.URL.Path != expectedPath {
+			t.Fatalf("expected path %q but got %q", expectedPath, r.URL.Path)
+		}
+
+		var status task.Status
+		if err := json.NewDecoder(r.Body).Decode(&status); err != nil {
+			t.Fatal("unexpected error decoding json: ", err)
+		} else if status != task.StatusDone {
+			t.Fatalf("expected %q but got %q", task.StatusDone, status)
+		}
+
+		if err := json.NewEncoder(w).Encode(task.Task{ID: testId, Status: status}); err != nil {
+			t.Fatal("unexpected error encoding json: ", err)
+		}
+	}))
+	defer ts.Close()
+
+	ti := NewClient(Host(ts.URL))
+
+	if got, err := ti.SetStatus(context.Background(), testId, task.StatusDone); err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if got == nil {
+		t.Fatal("expected task but got nil")
+	} else if got.Status != task.StatusDone {
+		t.Fatalf("expected status %q but got %q", task.StatusDone, got.Status)
 	}
 }
 
@@ -142,7 +377,7 @@ func TestDelete(t *testing.T) {
 
 	ti := NewClient(Host(ts.URL))
 
-	if err := ti.Delete(testId); err != nil {
+	if err := ti.Delete(context.Background(), testId); err != nil {
 		t.Fatal("unexpected error: ", err)
 	}
 }
@@ -0,0 +1,62 @@
+package client
+
+import (
+	"context"
+
+	"github.com/jmank88/todo/task"
+)
+
+// An Iterator iterates over the tasks listed by a task.TaskInterface, requesting each page as it is needed.
+//
+//	it := client.NewIterator(taskInterface, task.ListOptions{Sort: task.SortDue})
+//	for it.Next(ctx) {
+//		fmt.Println(it.Task())
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type Iterator struct {
+	ti      task.TaskInterface
+	options task.ListOptions
+
+	tasks []task.Task
+	task  task.Task
+	done  bool
+	err   error
+}
+
+// The NewIterator function returns an Iterator over the tasks listed by ti with options, starting from options.Cursor.
+// The options.Limit is the size of each page.
+func NewIterator(ti task.TaskInterface, options task.ListOptions) *Iterator {
+	return &Iterator{ti: ti, options: options}
+}
+
+// The Next method advances to the next task, which is then available from Task. It returns false when there are no more
+// tasks, or an error occurs, which is then available from Err.
+func (it *Iterator) Next(ctx context.Context) bool {
+	for len(it.tasks) == 0 {
+		if it.done || it.err != nil {
+			return false
+		}
+		page, err := it.ti.List(ctx, it.options)
+		if err != nil {
+			it.err = err
+			return false
+		}
+		it.tasks = page.Tasks
+		it.options.Cursor = page.Next
+		it.done = page.Next == ""
+	}
+	it.task, it.tasks = it.tasks[0], it.tasks[1:]
+	return true
+}
+
+// The Task method returns the current task.
+func (it *Iterator) Task() task.Task {
+	return it.task
+}
+
+// The Err method returns the error which stopped iteration, if any.
+func (it *Iterator) Err() error {
+	return it.err
+}
@@ -0,0 +1,164 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jmank88/todo/auth"
+	"github.com/jmank88/todo/memstore"
+	"github.com/jmank88/todo/server"
+	"github.com/jmank88/todo/task"
+	"github.com/jmank88/todo/tasktest"
+)
+
+// Runs the conformance suite against a client of a server backed by memory.
+func TestConformance(t *testing.T) {
+	tasktest.RunConformance(t, func(t *testing.T) task.TaskInterface {
+		ts := httptest.NewServer(server.NewServer(memstore.NewMemstore()))
+		t.Cleanup(ts.Close)
+		return NewClient(Host(ts.URL), HTTPClient(ts.Client()))
+	})
+}
+
+// Runs the isolation suite against clients of a server backed by memory, authenticated with a token for each user.
+func TestIsolation(t *testing.T) {
+	tasktest.RunIsolation(t, func(t *testing.T) tasktest.As {
+		users := map[string]string{
+			"alice": "alice-token", "bob": "bob-token", "carol": "carol-token", "dave": "dave-token", "root": "root-token",
+		}
+		handler := auth.Middleware(auth.Tokens(users), server.NewServer(memstore.NewMemstore()), auth.Admins("root"),
+			auth.Groups(tasktest.Groups))
+		ts := httptest.NewServer(handler)
+		t.Cleanup(ts.Close)
+		return func(user string, admin bool) (context.Context, task.TaskInterface) {
+			if admin != (user == "root") {
+				t.Fatalf("no token for user %q with admin %t", user, admin)
+			}
+			return context.Background(), NewClient(Host(ts.URL), HTTPClient(ts.Client()), Token(users[user]))
+		}
+	})
+}
+
+// Tests that a client authenticated with a token creates tasks as its user, and that unauthenticated clients are
+// rejected.
+func TestAuthenticated(t *testing.T) {
+	a := auth.Tokens(map[string]string{"alice": "secret"})
+	ts := httptest.NewServer(auth.Middleware(a, server.NewServer(memstore.NewMemstore())))
+	defer ts.Close()
+
+	ctx := context.Background()
+	ti := NewClient(Host(ts.URL), HTTPClient(ts.Client()), Token("secret"))
+	if _, _, err := ti.Put(ctx, task.Task{ID: "a"}); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if got, err := ti.Get(ctx, "a"); err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if got.CreatedBy != "alice" {
+		t.Fatalf("expected task created by alice but got %q", got.CreatedBy)
+	}
+
+	for _, options := range [][]Option{{}, {Token("wrong")}, {BasicAuth("alice", "secret")}} {
+		ti := NewClient(append(options, Host(ts.URL), HTTPClient(ts.Client()))...)
+		if _, err := ti.Get(ctx, "a"); !errors.Is(err, task.ErrUnauthenticated) {
+			t.Errorf("expected %v but got %v", task.ErrUnauthenticated, err)
+		}
+	}
+}
+
+// Tests writing tasks and subscribing to a list over a sync connection authenticated with a token.
+func TestSync(t *testing.T) {
+	a := auth.Tokens(map[string]string{"alice": "alice-token", "bob": "bob-token"})
+	ts := httptest.NewServer(auth.Middleware(a, server.NewServer(memstore.NewMemstore())))
+	defer ts.Close()
+
+	ctx := context.Background()
+	if _, err := Dial(ctx, Host(ts.URL)); !errors.Is(err, task.ErrUnauthenticated) {
+		t.Fatalf("expected %v but got %v", task.ErrUnauthenticated, err)
+	}
+	if _, _, err := NewClient(Host(ts.URL), HTTPClient(ts.Client()), Token("alice-token")).PutList(ctx, task.TaskList{ID: "l"}); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	conn, err := Dial(ctx, Host(ts.URL), Token("alice-token"))
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	defer conn.Close()
+
+	subCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	events, err := conn.Subscribe(subCtx, "l", 0)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	next := func() task.Event {
+		t.Helper()
+		select {
+		case e, ok := <-events:
+			if !ok {
+				t.Fatal("expected an event but the subscription ended")
+			}
+			return e
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for an event")
+		}
+		return task.Event{}
+	}
+
+	if _, err := conn.Create(ctx, task.Task{ID: "b", Title: "elsewhere"}); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if id, err := conn.Create(ctx, task.Task{ID: "a", Title: "title", List: "l"}); err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if id != "a" {
+		t.Fatalf("expected id %q but got %q", "a", id)
+	}
+	created := next()
+	if created.Type != task.EventCreated || created.Task.ID != "a" {
+		t.Fatalf("expected task a to be created but got %+v", created)
+	}
+
+	updated, err := conn.Update(task.IfMatch(ctx, created.Task.Revision), "a", []byte(`{"title":"new"}`))
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if updated.Title != "new" {
+		t.Fatalf("expected title %q but got %q", "new", updated.Title)
+	}
+	if e := next(); e.Type != task.EventUpdated || e.Task.Title != "new" {
+		t.Fatalf("expected task a to be updated but got %+v", e)
+	}
+	if _, err := conn.SetStatus(task.IfMatch(ctx, created.Task.Revision), "a", task.StatusDone); !errors.Is(err, task.ErrRevisionMismatch) {
+		t.Fatalf("expected %v but got %v", task.ErrRevisionMismatch, err)
+	}
+	if err := conn.Delete(ctx, "a"); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if e := next(); e.Type != task.EventDeleted || e.Task.ID != "a" {
+		t.Fatalf("expected task a to be deleted but got %+v", e)
+	}
+
+	bob, err := Dial(ctx, Host(ts.URL), Token("bob-token"))
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	defer bob.Close()
+	if _, err := bob.Subscribe(ctx, "l", 0); !errors.Is(err, task.ErrNotFound) {
+		t.Fatalf("expected %v but got %v", task.ErrNotFound, err)
+	}
+
+	cancel()
+	select {
+	case e, ok := <-events:
+		if ok {
+			t.Fatalf("expected the subscription to end but got %+v", e)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the subscription to end")
+	}
+	conn.Close()
+	if _, err := conn.Create(ctx, task.Task{}); !errors.Is(err, ErrClosed) {
+		t.Fatalf("expected %v but got %v", ErrClosed, err)
+	}
+}
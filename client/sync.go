@@ -0,0 +1,345 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/jmank88/todo/task"
+)
+
+// The ErrClosed error is returned by the methods of a Conn after it has been closed, or its connection has failed.
+var ErrClosed = errors.New("connection closed")
+
+// The writeTimeout is the time allowed to write a request to a Conn before it fails.
+const writeTimeout = 10 * time.Second
+
+// A syncRequest is a request sent to the host over a Conn.
+type syncRequest struct {
+	ID           string          `json:"id"`
+	Op           string          `json:"op"`
+	List         string          `json:"list,omitempty"`
+	After        int64           `json:"after,omitempty"`
+	Subscription string          `json:"subscription,omitempty"`
+	Task         *task.Task      `json:"task,omitempty"`
+	TaskID       string          `json:"task_id,omitempty"`
+	Patch        json.RawMessage `json:"patch,omitempty"`
+	Status       task.Status     `json:"status,omitempty"`
+	Revision     int64           `json:"revision,omitempty"`
+	Cascade      bool            `json:"cascade,omitempty"`
+}
+
+// A syncReply is a message received from the host over a Conn, either a reply to a request, or an event of a
+// subscription.
+type syncReply struct {
+	ID           string      `json:"id"`
+	OK           bool        `json:"ok"`
+	Error        string      `json:"error"`
+	Status       int         `json:"status"`
+	TaskID       string      `json:"task_id"`
+	Created      bool        `json:"created"`
+	Task         *task.Task  `json:"task"`
+	Subscription string      `json:"subscription"`
+	Event        *task.Event `json:"event"`
+}
+
+// A Conn is a WebSocket connection to the host for syncing tasks. Writes are sent over the connection, and
+// acknowledged by the host, without a round trip per request, and subscriptions receive the events of changes. The
+// methods of a Conn may be called concurrently.
+type Conn struct {
+	ws *websocket.Conn
+
+	// Guards writes to ws.
+	writeMu sync.Mutex
+
+	// Guards the fields below.
+	mu sync.Mutex
+	// The id of the last request.
+	last int64
+	// The channels awaiting the replies to requests, by request id.
+	pending map[string]chan syncReply
+	// The channels of the events of subscriptions, by subscribe request id.
+	subscriptions map[string]chan task.Event
+	// The error the connection failed with, if it is closed.
+	err error
+	// Closed when the connection is closed.
+	done chan struct{}
+}
+
+// The Dial function opens a sync connection to the host, configured by options like NewClient. The HTTPClient option
+// is not used.
+func Dial(ctx context.Context, options ...Option) (*Conn, error) {
+	c := &client{host: defaultHost}
+	for _, o := range options {
+		o(c)
+	}
+	u := c.host + "/_sync"
+	switch {
+	case strings.HasPrefix(u, "https://"):
+		u = "wss://" + strings.TrimPrefix(u, "https://")
+	case strings.HasPrefix(u, "http://"):
+		u = "ws://" + strings.TrimPrefix(u, "http://")
+	default:
+		u = "ws://" + u
+	}
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create http request for sync: %s", err)
+	}
+	if c.authorize != nil {
+		c.authorize(req)
+	}
+	ws, resp, err := websocket.DefaultDialer.DialContext(ctx, u, req.Header)
+	if err != nil {
+		if errors.Is(err, websocket.ErrBadHandshake) && resp != nil {
+			defer resp.Body.Close()
+			return nil, errorResponse(resp, "dial sync")
+		}
+		return nil, fmt.Errorf("failed to dial sync: %w", err)
+	}
+	conn := &Conn{
+		ws:            ws,
+		pending:       make(map[string]chan syncReply),
+		subscriptions: make(map[string]chan task.Event),
+		done:          make(chan struct{}),
+	}
+	go conn.read()
+	return conn, nil
+}
+
+// The Close method closes the connection. Requests awaiting replies fail, and the channels of subscriptions are
+// closed.
+func (c *Conn) Close() error {
+	err := c.ws.Close()
+	c.fail(ErrClosed)
+	return err
+}
+
+// The read method reads messages from the host, and dispatches them to the pending requests and subscriptions, until
+// the connection fails.
+func (c *Conn) read() {
+	for {
+		var reply syncReply
+		if err := c.ws.ReadJSON(&reply); err != nil {
+			c.fail(fmt.Errorf("%w: %s", ErrClosed, err))
+			return
+		}
+		c.mu.Lock()
+		switch {
+		case reply.Subscription != "":
+			events, ok := c.subscriptions[reply.Subscription]
+			if !ok {
+				break
+			}
+			if reply.Event == nil {
+				// The subscription has ended.
+				delete(c.subscriptions, reply.Subscription)
+				close(events)
+				break
+			}
+			select {
+			case events <- *reply.Event:
+			default:
+				// The subscriber has fallen too far behind, so drop it, like the host drops slow watchers.
+				delete(c.subscriptions, reply.Subscription)
+				close(events)
+				go c.send(syncRequest{ID: c.nextID(), Op: "unsubscribe", Subscription: reply.Subscription})
+			}
+		case reply.ID != "":
+			if r, ok := c.pending[reply.ID]; ok {
+				delete(c.pending, reply.ID)
+				r <- reply
+			}
+		}
+		c.mu.Unlock()
+	}
+}
+
+// The fail method closes the connection with err, failing the pending requests, and closing the channels of the
+// subscriptions. Only the first error is kept.
+func (c *Conn) fail(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return
+	}
+	c.err = err
+	close(c.done)
+	for id, r := range c.pending {
+		delete(c.pending, id)
+		close(r)
+	}
+	for id, events := range c.subscriptions {
+		delete(c.subscriptions, id)
+		close(events)
+	}
+}
+
+// The nextID method returns a new request id. The caller must hold mu.
+func (c *Conn) nextID() string {
+	c.last++
+	return strconv.FormatInt(c.last, 10)
+}
+
+// The send method writes req to the host.
+func (c *Conn) send(req syncRequest) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.ws.SetWriteDeadline(time.Now().Add(writeTimeout))
+	return c.ws.WriteJSON(req)
+}
+
+// The do method sends req for the described action with a new id, and returns the reply of the host. The revision
+// expected by ctx, if any, is sent with req. Returns an error if the host failed to execute req.
+func (c *Conn) do(ctx context.Context, req syncRequest, action string) (syncReply, error) {
+	if revision, ok := task.ExpectedRevision(ctx); ok {
+		req.Revision = revision
+	}
+	r := make(chan syncReply, 1)
+	c.mu.Lock()
+	if c.err != nil {
+		err := c.err
+		c.mu.Unlock()
+		return syncReply{}, fmt.Errorf("failed to %s: %w", action, err)
+	}
+	req.ID = c.nextID()
+	c.pending[req.ID] = r
+	c.mu.Unlock()
+
+	if err := c.send(req); err != nil {
+		c.mu.Lock()
+		delete(c.pending, req.ID)
+		c.mu.Unlock()
+		return syncReply{}, fmt.Errorf("failed to %s: %s", action, err)
+	}
+	select {
+	case reply, ok := <-r:
+		if !ok {
+			c.mu.Lock()
+			err := c.err
+			c.mu.Unlock()
+			return reply, fmt.Errorf("failed to %s: %w", action, err)
+		}
+		if !reply.OK {
+			return reply, statusError(reply.Status, []byte(reply.Error), action)
+		}
+		return reply, nil
+	case <-ctx.Done():
+		c.mu.Lock()
+		delete(c.pending, req.ID)
+		c.mu.Unlock()
+		return syncReply{}, fmt.Errorf("failed to %s: %w", action, ctx.Err())
+	}
+}
+
+// The Put method puts a task, like task.TaskInterface.Put.
+func (c *Conn) Put(ctx context.Context, t task.Task) (string, bool, error) {
+	reply, err := c.do(ctx, syncRequest{Op: "put", Task: &t}, "store task")
+	return reply.TaskID, reply.Created, err
+}
+
+// The Create method creates a task, like task.TaskInterface.Create.
+func (c *Conn) Create(ctx context.Context, t task.Task) (string, error) {
+	reply, err := c.do(ctx, syncRequest{Op: "create", Task: &t}, "create task")
+	return reply.TaskID, err
+}
+
+// The Update method patches a task, like task.TaskInterface.Update.
+func (c *Conn) Update(ctx context.Context, id string, patch []byte) (*task.Task, error) {
+	reply, err := c.do(ctx, syncRequest{Op: "update", TaskID: id, Patch: patch}, "update task "+id)
+	return reply.Task, err
+}
+
+// The SetStatus method sets the status of a task, like task.TaskInterface.SetStatus.
+func (c *Conn) SetStatus(ctx context.Context, id string, status task.Status) (*task.Task, error) {
+	reply, err := c.do(ctx, syncRequest{Op: "status", TaskID: id, Status: status}, "set status of task "+id)
+	return reply.Task, err
+}
+
+// The Delete method deletes a task, like task.TaskInterface.Delete.
+func (c *Conn) Delete(ctx context.Context, id string) error {
+	_, err := c.do(ctx, syncRequest{Op: "delete", TaskID: id}, "delete task "+id)
+	return err
+}
+
+// The DeleteTree method deletes a task and its subtasks, like task.TaskInterface.DeleteTree.
+func (c *Conn) DeleteTree(ctx context.Context, id string) error {
+	_, err := c.do(ctx, syncRequest{Op: "delete", TaskID: id, Cascade: true}, "delete task "+id)
+	return err
+}
+
+// The Subscribe method subscribes to the events of changes to the tasks in list, or to all tasks if list is empty,
+// resuming after the event with id after, if not zero, like task.TaskInterface.Watch. The channel is closed when ctx
+// is done, the host ends the subscription, the connection fails, or the events are not received fast enough.
+func (c *Conn) Subscribe(ctx context.Context, list string, after int64) (<-chan task.Event, error) {
+	// The events are buffered, so that subscribers may send requests while receiving events.
+	events := make(chan task.Event, task.DefaultFeedSize)
+	r := make(chan syncReply, 1)
+	c.mu.Lock()
+	if c.err != nil {
+		err := c.err
+		c.mu.Unlock()
+		return nil, fmt.Errorf("failed to subscribe: %w", err)
+	}
+	id := c.nextID()
+	// The subscription is registered with the request, since events may be received before the reply.
+	c.pending[id] = r
+	c.subscriptions[id] = events
+	c.mu.Unlock()
+
+	unsubscribe := func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		delete(c.pending, id)
+		if e, ok := c.subscriptions[id]; ok && e == events {
+			delete(c.subscriptions, id)
+			close(events)
+		}
+	}
+	var err error
+	if err = c.send(syncRequest{ID: id, Op: "subscribe", List: list, After: after}); err != nil {
+		err = fmt.Errorf("failed to subscribe: %s", err)
+	} else {
+		select {
+		case reply, ok := <-r:
+			if !ok {
+				c.mu.Lock()
+				err = fmt.Errorf("failed to subscribe: %w", c.err)
+				c.mu.Unlock()
+			} else if !reply.OK {
+				err = statusError(reply.Status, []byte(reply.Error), "subscribe")
+			}
+		case <-ctx.Done():
+			err = fmt.Errorf("failed to subscribe: %w", ctx.Err())
+		}
+	}
+	if err != nil {
+		unsubscribe()
+		return nil, err
+	}
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-c.done:
+			return
+		}
+		c.mu.Lock()
+		_, ok := c.subscriptions[id]
+		unsub := syncRequest{ID: c.nextID(), Op: "unsubscribe", Subscription: id}
+		c.mu.Unlock()
+		unsubscribe()
+		if ok {
+			// The reply is not awaited, since events received meanwhile are dropped.
+			c.send(unsub)
+		}
+	}()
+	return events, nil
+}
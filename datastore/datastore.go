@@ -2,30 +2,67 @@
 package datastore
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
+	"sync"
+	"time"
+
 	"github.com/rs/xid"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 
 	"github.com/jmank88/todo/task"
 )
 
 // The NewDatastore function creates a new task.TaskInterface backed by the postgres database host.
-func NewDatastore(host string) (task.TaskInterface, error) {
-	return &dataStore{host: host}, nil
+func NewDatastore(host string, options ...Option) (task.TaskInterface, error) {
+	d := &dataStore{host: host}
+	for _, o := range options {
+		o(d)
+	}
+	return d, nil
+}
+
+// An Option is a functional option for configuring a datastore.
+type Option func(*dataStore)
+
+// The AutoMigrate function returns an Option which applies any pending migrations when the database is first used.
+// Without it, a database which is not at the latest migration version is rejected instead, so that a database reverted
+// with Migrator.Down is not silently migrated up again.
+func AutoMigrate() Option {
+	return func(d *dataStore) {
+		d.autoMigrate = true
+	}
 }
 
 // A datastore implements the task.TaskInterface, and executes commands against a sql.DB.
 type dataStore struct {
 	host string
+	// Whether to apply pending migrations. See AutoMigrate.
+	autoMigrate bool
 
+	// Guards cachedDB.
+	mu sync.Mutex
 	// Cached database handle. Not to be used directly, use db() instead.
 	cachedDB *sql.DB
+
+	// Guards listener and watchers.
+	watchMu sync.Mutex
+	// The listener for notifications of new events, shared by every watcher. Created by the first call to listen.
+	listener *pq.Listener
+	// The wake channels of the watchers, signalled on each notification.
+	watchers map[chan struct{}]struct{}
 }
 
 // The db function returns a cached sql.DB, or create and instantiates a new one.
-func (d *dataStore) db() (*sql.DB, error) {
+func (d *dataStore) db(ctx context.Context) (*sql.DB, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
 	if d.cachedDB != nil {
 		return d.cachedDB, nil
 	}
@@ -34,7 +71,8 @@ func (d *dataStore) db() (*sql.DB, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database connection for host (%s): %s", d.host, err)
 	}
-	if err := initDB(db); err != nil {
+	if err := initDB(ctx, db, d.autoMigrate); err != nil {
+		db.Close()
 		return nil, fmt.Errorf("failed to initialize database: %s", err)
 	}
 
@@ -43,50 +81,553 @@ func (d *dataStore) db() (*sql.DB, error) {
 	return db, nil
 }
 
-// The initDB method initializes the database.
-func initDB(db *sql.DB) error {
-	if _, err := db.Exec("CREATE TABLE IF NOT EXISTS tasks (id TEXT PRIMARY KEY, title TEXT, content TEXT)"); err != nil {
-		return fmt.Errorf("failed to create tasks table: %s", err)
+// The initDB function initializes the database, by applying any migrations it has not applied yet if autoMigrate is
+// true, or otherwise by checking that there are none.
+func initDB(ctx context.Context, db *sql.DB, autoMigrate bool) error {
+	migrations, err := Migrations()
+	if err != nil {
+		return err
+	}
+	m := &Migrator{db: db, migrations: migrations}
+	if autoMigrate {
+		return m.Up(ctx, m.Latest())
+	}
+	version, err := m.Version(ctx)
+	if err != nil {
+		return err
+	}
+	if version < m.Latest() {
+		return fmt.Errorf("database schema is at version %d, but version %d is required. it must be migrated up", version, m.Latest())
+	}
+	return nil
+}
+
+// The taskColumns are the columns of the tasks table, in order.
+const taskColumns = "id, title, content, status, completed, start, due, priority, list_id, parent_id, recurrence, next_id, created_at, updated_at, created_by, revision, owner"
+
+// The updateColumns set the taskColumns other than id, from the placeholders of taskValues.
+const updateColumns = "title = $2, content = $3, status = $4, completed = $5, start = $6, due = $7, priority = $8, list_id = $9, parent_id = $10, recurrence = $11, next_id = $12, created_at = $13, updated_at = $14, created_by = $15, revision = $16, owner = $17"
+
+// The selectColumns are the taskColumns, the task's tags from the task_tags table, and its blockers from the
+// task_blockers table, scanned by scanTask.
+const selectColumns = taskColumns + `, ARRAY(SELECT tag FROM task_tags WHERE task_id = tasks.id ORDER BY tag COLLATE "C")` +
+	`, ARRAY(SELECT blocker_id FROM task_blockers WHERE task_id = tasks.id ORDER BY blocker_id COLLATE "C")`
+
+// A scanner is implemented by both sql.Row and sql.Rows.
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// The scanTask function scans a row of selectColumns into a task, and any extra columns following them into extra.
+func scanTask(s scanner, extra ...interface{}) (*task.Task, error) {
+	var t task.Task
+	var priority, list, parent, recurrence, next, createdBy, owner sql.NullString
+	dest := []interface{}{&t.ID, &t.Title, &t.Description, &t.Status, &t.Completed, &t.Start, &t.Due, &priority, &list, &parent, &recurrence, &next,
+		&t.CreatedAt, &t.UpdatedAt, &createdBy, &t.Revision, &owner, pq.Array(&t.Tags), pq.Array(&t.BlockedBy)}
+	if err := s.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+	t.Priority = task.Priority(priority.String)
+	t.List = list.String
+	t.Parent = parent.String
+	t.Recurrence = recurrence.String
+	t.Next = next.String
+	t.CreatedBy = createdBy.String
+	t.Owner = owner.String
+	t.Tags = task.NormalizeTags(t.Tags)
+	t.BlockedBy = task.NormalizeIDs(t.BlockedBy)
+	return &t, nil
+}
+
+// The nullString function returns s as a column value, or NULL if s is empty. Tasks without a priority have a NULL
+// priority, so that they are ordered last, and tasks without a list or parent have a NULL list_id or parent_id, to
+// satisfy their foreign keys. Tasks and lists without an owner have a NULL owner.
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// The taskValues function returns the values of the taskColumns of t, in order.
+func taskValues(t *task.Task) []interface{} {
+	return []interface{}{t.ID, t.Title, t.Description, t.Status, t.Completed, t.Start, t.Due, nullString(string(t.Priority)),
+		nullString(t.List), nullString(t.Parent), nullString(t.Recurrence), nullString(t.Next), t.CreatedAt, t.UpdatedAt, nullString(t.CreatedBy), t.Revision,
+		nullString(t.Owner)}
+}
+
+// The checkList function returns an error wrapping task.ErrInvalid if t belongs to a list other than current which does
+// not exist, or ctx may not read, or task.ErrForbidden if ctx may not edit it. The list's row is locked until tx ends,
+// so that it cannot be deleted concurrently.
+func checkList(ctx context.Context, tx *sql.Tx, t *task.Task, current string) error {
+	if t.List == "" || t.List == current {
+		return nil
+	}
+	var id string
+	if err := tx.QueryRowContext(ctx, "SELECT id FROM lists WHERE id = $1 FOR SHARE", t.List).Scan(&id); err == sql.ErrNoRows {
+		return fmt.Errorf("%w: unknown list %q", task.ErrInvalid, t.List)
+	} else if err != nil {
+		return fmt.Errorf("failed to get list %q: %s", t.List, err)
+	}
+	l, err := getList(ctx, tx, t.List)
+	if err != nil {
+		return err
+	}
+	if err := task.Authorize(task.ListRole(ctx, *l), task.RoleEditor); errors.Is(err, task.ErrNotFound) {
+		return fmt.Errorf("%w: unknown list %q", task.ErrInvalid, t.List)
+	} else if err != nil {
+		return fmt.Errorf("failed to add task %q to list %q: %w", t.ID, t.List, err)
+	}
+	return nil
+}
+
+// The taskRole function returns the role of ctx on a task owned by owner, belonging to list, if not empty. See
+// task.TaskRole.
+func taskRole(ctx context.Context, tx *sql.Tx, owner, list string) (task.Role, error) {
+	t := task.Task{Owner: owner, List: list}
+	if list == "" || task.Visible(ctx, owner) {
+		return task.TaskRole(ctx, t, nil), nil
+	}
+	l, err := getList(ctx, tx, list)
+	if err != nil {
+		return "", err
+	}
+	return task.TaskRole(ctx, t, l), nil
+}
+
+// The hierarchyLockID is the key of the postgres advisory lock held while changing the parent or blockers of a task, so
+// that concurrent changes cannot together make a task its own subtask, or depend on itself.
+const hierarchyLockID = 8675310
+
+// The checkParent function returns an error wrapping task.ErrInvalid if t has a parent which does not exist, or ctx may
+// not read, or is one of its own subtasks. The ancestors of the parent are found with a recursive query.
+func checkParent(ctx context.Context, tx *sql.Tx, t *task.Task) error {
+	if t.Parent == "" {
+		return nil
+	}
+	if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1)", hierarchyLockID); err != nil {
+		return fmt.Errorf("failed to acquire hierarchy lock: %s", err)
+	}
+	var found int
+	var cycle bool
+	c := byID(ctx, t.Parent)
+	err := tx.QueryRowContext(ctx, `WITH RECURSIVE ancestors (id, parent_id) AS (
+			SELECT id, parent_id FROM tasks`+c.where()+`
+			UNION
+			SELECT tasks.id, tasks.parent_id FROM tasks JOIN ancestors ON tasks.id = ancestors.parent_id
+		)
+		SELECT count(*), COALESCE(bool_or(id = `+c.arg(t.ID)+`), false) FROM ancestors`, c.args...).Scan(&found, &cycle)
+	if err != nil {
+		return fmt.Errorf("failed to check parent %q of task %q: %s", t.Parent, t.ID, err)
+	}
+	if found == 0 {
+		return fmt.Errorf("%w: unknown parent %q", task.ErrInvalid, t.Parent)
+	}
+	if cycle {
+		return fmt.Errorf("%w: parent %q of task %q is its own subtask", task.ErrInvalid, t.Parent, t.ID)
+	}
+	return nil
+}
+
+// The checkBlockers function returns an error wrapping task.ErrInvalid if t is blocked by a task which does not exist,
+// or ctx may not read, or task.ErrCycle if t would depend on itself. The tasks reachable from its blockers are found with a recursive query.
+func checkBlockers(ctx context.Context, tx *sql.Tx, t *task.Task) error {
+	if len(t.BlockedBy) == 0 {
+		return nil
+	}
+	if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1)", hierarchyLockID); err != nil {
+		return fmt.Errorf("failed to acquire hierarchy lock: %s", err)
+	}
+	var missing []string
+	c := conditions{conds: []string{"tasks.id = blockers.id"}, args: []interface{}{pq.Array(t.BlockedBy)}}
+	c.scope(ctx)
+	rows, err := tx.QueryContext(ctx, "SELECT id FROM unnest($1::text[]) AS blockers (id) WHERE NOT EXISTS (SELECT 1 FROM tasks"+c.where()+") ORDER BY id COLLATE \"C\"", c.args...)
+	if err != nil {
+		return fmt.Errorf("failed to check blockers of task %q: %s", t.ID, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return fmt.Errorf("failed to check blockers of task %q: %s", t.ID, err)
+		}
+		missing = append(missing, id)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to check blockers of task %q: %s", t.ID, err)
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: unknown blocker %q", task.ErrInvalid, missing[0])
+	}
+	var path []string
+	err = tx.QueryRowContext(ctx, `WITH RECURSIVE reachable (id, path) AS (
+			SELECT id, ARRAY[id] FROM unnest($1::text[]) AS blockers (id)
+			UNION ALL
+			SELECT task_blockers.blocker_id, reachable.path || task_blockers.blocker_id
+			FROM task_blockers JOIN reachable ON task_blockers.task_id = reachable.id
+			WHERE reachable.id <> $2 AND NOT task_blockers.blocker_id = ANY(reachable.path)
+		)
+		SELECT path FROM reachable WHERE id = $2 LIMIT 1`, pq.Array(t.BlockedBy), t.ID).Scan(pq.Array(&path))
+	if err == sql.ErrNoRows {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to check blockers of task %q: %s", t.ID, err)
+	}
+	return fmt.Errorf("%w: %s", task.ErrCycle, strings.Join(append([]string{t.ID}, path...), " -> "))
+}
+
+// The descendants is a recursive common table expression of the ids of the task with id $1, and all of its subtasks.
+const descendants = `WITH RECURSIVE descendants (id) AS (
+		SELECT id FROM tasks WHERE id = $1
+		UNION
+		SELECT tasks.id FROM tasks JOIN descendants ON tasks.parent_id = descendants.id
+	) `
+
+// The setTags function replaces the tags of the task with the given id.
+func setTags(ctx context.Context, tx *sql.Tx, id string, tags []string) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM task_tags WHERE task_id = $1", id); err != nil {
+		return fmt.Errorf("failed to delete tags of task %q: %s", id, err)
+	}
+	if len(tags) == 0 {
+		return nil
+	}
+	if _, err := tx.ExecContext(ctx, "INSERT INTO task_tags (task_id, tag) SELECT $1, unnest($2::text[])", id, pq.Array(tags)); err != nil {
+		return fmt.Errorf("failed to insert tags of task %q: %s", id, err)
+	}
+	return nil
+}
+
+// The setBlockers function replaces the blockers of the task with the given id.
+func setBlockers(ctx context.Context, tx *sql.Tx, id string, blockers []string) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM task_blockers WHERE task_id = $1", id); err != nil {
+		return fmt.Errorf("failed to delete blockers of task %q: %s", id, err)
+	}
+	if len(blockers) == 0 {
+		return nil
+	}
+	if _, err := tx.ExecContext(ctx, "INSERT INTO task_blockers (task_id, blocker_id) SELECT $1, unnest($2::text[])", id, pq.Array(blockers)); err != nil {
+		return fmt.Errorf("failed to insert blockers of task %q: %s", id, err)
 	}
 	return nil
 }
 
 // The Get method queries the tasks table for a single task with the given id.
-func (d *dataStore) Get(id string) (*task.Task, error) {
-	db, err := d.db()
+func (d *dataStore) Get(ctx context.Context, id string) (*task.Task, error) {
+	if err := task.ValidateID(id); err != nil {
+		return nil, err
+	}
+	db, err := d.db(ctx)
 	if err != nil {
 		return nil, err
 	}
-	row := db.QueryRow("SELECT title, content FROM tasks WHERE id = $1", id)
-	task := &task.Task{ID: id}
-	if err := row.Scan(&(task.Title), &(task.Description)); err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil
-		}
-		return nil, fmt.Errorf("failed to get task %q: %s", id, err)
+	c := byID(ctx, id)
+	task, err := scanTask(db.QueryRowContext(ctx, "SELECT "+selectColumns+" FROM tasks"+c.where(), c.args...))
+	if err != nil {
+		return nil, getError(id, err)
 	}
 	return task, nil
 }
 
-// The GetAll method queries the tasks table for all tasks.
-func (d *dataStore) GetAll() ([]task.Task, error) {
-	db, err := d.db()
+// The getError function returns an error for a failed query of the task with the given id, wrapping task.ErrNotFound
+// if there was no such row.
+func getError(id string, err error) error {
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("failed to get task %q: %w", id, task.ErrNotFound)
+	}
+	return fmt.Errorf("failed to get task %q: %s", id, err)
+}
+
+// The GetAll method queries the tasks table for all tasks, ordered by id.
+func (d *dataStore) GetAll(ctx context.Context) ([]task.Task, error) {
+	return d.Find(ctx, task.Filter{})
+}
+
+// The Find method queries the tasks table for all tasks matching filter, ordered by id. Ids are compared byte-wise, like
+// the other stores, rather than by the database's collation.
+func (d *dataStore) Find(ctx context.Context, filter task.Filter) ([]task.Task, error) {
+	db, err := d.db(ctx)
 	if err != nil {
 		return nil, err
 	}
-	rows, err := db.Query("SELECT id, title, content FROM tasks")
+	var c conditions
+	c.scope(ctx)
+	c.filter(filter)
+	return queryTasks(ctx, db, "SELECT "+selectColumns+" FROM tasks"+c.where()+orderBy(task.SortID), c.args...)
+}
+
+// The Actionable method queries the tasks table for all tasks matching filter which are not closed, and have no blockers
+// which are not closed, ordered by id.
+func (d *dataStore) Actionable(ctx context.Context, filter task.Filter) ([]task.Task, error) {
+	db, err := d.db(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var c conditions
+	c.scope(ctx)
+	c.filter(filter)
+	c.conds = append(c.conds, "status NOT IN ('done', 'cancelled')",
+		"NOT EXISTS (SELECT 1 FROM task_blockers JOIN tasks blockers ON blockers.id = task_blockers.blocker_id "+
+			"WHERE task_blockers.task_id = tasks.id AND blockers.status NOT IN ('done', 'cancelled'))")
+	return queryTasks(ctx, db, "SELECT "+selectColumns+" FROM tasks"+c.where()+orderBy(task.SortID), c.args...)
+}
+
+// The Occurrences method queries the tasks table for the tasks matching filter which are not closed, and due before to,
+// and lists their occurrences at or after from, and before to.
+func (d *dataStore) Occurrences(ctx context.Context, from, to time.Time, filter task.Filter) ([]task.Occurrence, error) {
+	if err := task.ValidateRange(from, to); err != nil {
+		return nil, err
+	}
+	db, err := d.db(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var c conditions
+	c.scope(ctx)
+	c.filter(filter)
+	c.conds = append(c.conds, "status NOT IN ('done', 'cancelled')", "due < "+c.arg(to))
+	tasks, err := queryTasks(ctx, db, "SELECT "+selectColumns+" FROM tasks"+c.where()+orderBy(task.SortID), c.args...)
+	if err != nil {
+		return nil, err
+	}
+	return task.ExpandOccurrences(tasks, from, to)
+}
+
+// The Ordered method queries the tasks table for all tasks, and orders those matching filter so that every task follows
+// the tasks blocking it. All tasks are ordered, since a matching task may be blocked through tasks which do not match.
+func (d *dataStore) Ordered(ctx context.Context, filter task.Filter) ([]task.Task, error) {
+	tasks, err := d.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if tasks, err = task.OrderTasks(tasks); err != nil {
+		return nil, err
+	}
+	return filter.Apply(tasks, time.Now()), nil
+}
+
+// A conditions accumulates the conditions of a WHERE clause, and their arguments.
+type conditions struct {
+	conds []string
+	args  []interface{}
+}
+
+// The byID function returns the conditions selecting the task with the given id, if ctx may read it.
+func byID(ctx context.Context, id string) conditions {
+	c := conditions{conds: []string{"id = $1"}, args: []interface{}{id}}
+	c.scope(ctx)
+	return c
+}
+
+// The scope method adds the condition for tasks ctx may read, if it is scoped to a user: their own tasks, and the tasks
+// of the lists they own, or which are shared with them. See task.Scope and task.TaskRole.
+func (c *conditions) scope(ctx context.Context) {
+	if user, ok := task.Scope(ctx); ok {
+		u := c.arg(user)
+		c.conds = append(c.conds, "(owner = "+u+" OR list_id IN (SELECT id FROM lists WHERE owner = "+u+") OR list_id IN ("+c.shared(ctx, u)+"))")
+	}
+}
+
+// The scopeLists method adds the condition for lists ctx may read, if it is scoped to a user: the lists they own, or
+// which are shared with them. See task.ListRole.
+func (c *conditions) scopeLists(ctx context.Context) {
+	if user, ok := task.Scope(ctx); ok {
+		u := c.arg(user)
+		c.conds = append(c.conds, "(owner = "+u+" OR id IN ("+c.shared(ctx, u)+"))")
+	}
+}
+
+// The shared method returns a query of the ids of the lists shared with the user with placeholder u, or their groups.
+func (c *conditions) shared(ctx context.Context, u string) string {
+	return "SELECT list_id FROM list_grants WHERE kind = 'user' AND grantee = " + u +
+		" OR kind = 'group' AND grantee = ANY(" + c.arg(pq.Array(task.Groups(ctx))) + ")"
+}
+
+// The arg method adds an argument, and returns its placeholder.
+func (c *conditions) arg(v interface{}) string {
+	c.args = append(c.args, v)
+	return fmt.Sprintf("$%d", len(c.args))
+}
+
+// The filter method adds the conditions for filter.
+func (c *conditions) filter(filter task.Filter) {
+	if filter.Overdue {
+		c.conds = append(c.conds, "due < now() AND status NOT IN ('done', 'cancelled')")
+	}
+	if filter.DueBefore != nil {
+		c.conds = append(c.conds, "due < "+c.arg(*filter.DueBefore))
+	}
+	if filter.DueAfter != nil {
+		c.conds = append(c.conds, "due >= "+c.arg(*filter.DueAfter))
+	}
+	if len(filter.Statuses) > 0 {
+		statuses := make([]string, len(filter.Statuses))
+		for i, status := range filter.Statuses {
+			statuses[i] = string(status)
+		}
+		c.conds = append(c.conds, "status = ANY("+c.arg(pq.Array(statuses))+")")
+	}
+	if len(filter.Priorities) > 0 {
+		var priorities []string
+		var none bool
+		for _, p := range filter.Priorities {
+			if p == "" {
+				none = true
+			} else {
+				priorities = append(priorities, string(p))
+			}
+		}
+		cond := "priority = ANY(" + c.arg(pq.Array(priorities)) + ")"
+		if none {
+			cond = "(" + cond + " OR priority IS NULL)"
+		}
+		c.conds = append(c.conds, cond)
+	}
+	if filter.List != "" {
+		c.conds = append(c.conds, "list_id = "+c.arg(filter.List))
+	}
+	if filter.Parent != "" {
+		c.conds = append(c.conds, "parent_id = "+c.arg(filter.Parent))
+	}
+	if filter.UpdatedSince != nil {
+		c.conds = append(c.conds, "updated_at >= "+c.arg(*filter.UpdatedSince))
+	}
+	if tags := task.NormalizeTags(filter.Tags); len(tags) > 0 {
+		matching := "SELECT count(*) FROM task_tags WHERE task_id = tasks.id AND tag = ANY(" + c.arg(pq.Array(tags)) + ")"
+		if filter.AllTags {
+			c.conds = append(c.conds, fmt.Sprintf("(%s) = %d", matching, len(tags)))
+		} else {
+			c.conds = append(c.conds, fmt.Sprintf("(%s) > 0", matching))
+		}
+	}
+}
+
+// The after method adds the condition for tasks ordered after the task after by sort, matching task.Sort.Less.
+func (c *conditions) after(after *task.Task, sort task.Sort) {
+	cmp := ">"
+	if sort.Desc() {
+		cmp = "<"
+	}
+	idAfter := `id COLLATE "C" ` + cmp + " " + c.arg(after.ID)
+	switch sort.Key() {
+	case task.SortTitle:
+		title := c.arg(after.Title)
+		c.conds = append(c.conds, fmt.Sprintf(`(title COLLATE "C" %s %s OR (title = %s AND %s))`, cmp, title, title, idAfter))
+	case task.SortDue:
+		c.nullableAfter("due", timeValue(after.Due), sort.Desc(), idAfter)
+	case task.SortPriority:
+		var priority interface{}
+		if after.Priority != "" {
+			priority = string(after.Priority)
+		}
+		c.nullableAfter("priority", priority, sort.Desc(), idAfter)
+	case task.SortCreated:
+		c.nullableAfter("created_at", timeValue(after.CreatedAt), sort.Desc(), idAfter)
+	case task.SortUpdated:
+		c.nullableAfter("updated_at", timeValue(after.UpdatedAt), sort.Desc(), idAfter)
+	default:
+		c.conds = append(c.conds, idAfter)
+	}
+}
+
+// The timeValue function returns t as a value for nullableAfter, or nil if t is nil.
+func timeValue(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return *t
+}
+
+// The nullableAfter method adds the condition for tasks ordered after value in column, or NULL if value is nil, with
+// ties broken by idAfter. NULLs are last in ascending order, and first in descending order.
+func (c *conditions) nullableAfter(column string, value interface{}, desc bool, idAfter string) {
+	switch {
+	case value == nil && desc:
+		c.conds = append(c.conds, fmt.Sprintf("(%s IS NOT NULL OR %s)", column, idAfter))
+	case value == nil:
+		c.conds = append(c.conds, fmt.Sprintf("(%s IS NULL AND %s)", column, idAfter))
+	case desc:
+		v := c.arg(value)
+		c.conds = append(c.conds, fmt.Sprintf("(%s < %s OR (%s = %s AND %s))", column, v, column, v, idAfter))
+	default:
+		v := c.arg(value)
+		c.conds = append(c.conds, fmt.Sprintf("(%s > %s OR (%s = %s AND %s) OR %s IS NULL)", column, v, column, v, idAfter, column))
+	}
+}
+
+// The where method returns the WHERE clause, or an empty string if there are no conditions.
+func (c *conditions) where() string {
+	if len(c.conds) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(c.conds, " AND ")
+}
+
+// The orderBy function returns the ORDER BY clause for sort, matching task.Sort.Less.
+func orderBy(sort task.Sort) string {
+	dir := " ASC"
+	if sort.Desc() {
+		dir = " DESC"
+	}
+	id := `id COLLATE "C"` + dir
+	switch sort.Key() {
+	case task.SortTitle:
+		return ` ORDER BY title COLLATE "C"` + dir + ", " + id
+	case task.SortDue:
+		if sort.Desc() {
+			return " ORDER BY due DESC NULLS FIRST, " + id
+		}
+		return " ORDER BY due ASC NULLS LAST, " + id
+	case task.SortPriority:
+		if sort.Desc() {
+			return " ORDER BY priority DESC NULLS FIRST, " + id
+		}
+		return " ORDER BY priority ASC NULLS LAST, " + id
+	case task.SortCreated:
+		return " ORDER BY created_at" + dir + ", " + id
+	case task.SortUpdated:
+		return " ORDER BY updated_at" + dir + ", " + id
+	default:
+		return " ORDER BY " + id
+	}
+}
+
+// The List method queries the tasks table for a single page of tasks. Pages are selected by the sort key of the last
+// task of the previous page, rather than an offset, so that each page is an index range scan.
+func (d *dataStore) List(ctx context.Context, options task.ListOptions) (*task.Page, error) {
+	after, err := options.After()
+	if err != nil {
+		return nil, err
+	}
+	db, err := d.db(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var c conditions
+	c.scope(ctx)
+	c.filter(options.Filter)
+	if after != nil {
+		c.after(after, options.Sort)
+	}
+	// Fetch one extra task, to detect the last page.
+	query := "SELECT " + selectColumns + " FROM tasks" + c.where() + orderBy(options.Sort) + " LIMIT " + c.arg(options.PageLimit()+1)
+	tasks, err := queryTasks(ctx, db, query, c.args...)
+	if err != nil {
+		return nil, err
+	}
+	return task.NewPage(tasks, options), nil
+}
+
+// The queryTasks function runs query, and scans the resulting rows of selectColumns.
+func queryTasks(ctx context.Context, q querier, query string, args ...interface{}) ([]task.Task, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 	var tasks []task.Task
 	for rows.Next() {
-		var task task.Task
-		err = rows.Scan(&(task.ID), &(task.Title), &(task.Description))
+		task, err := scanTask(rows)
 		if err != nil {
 			return nil, err
 		}
-		tasks = append(tasks, task)
+		tasks = append(tasks, *task)
 	}
 	if err := rows.Err(); err != nil {
 		return nil, err
@@ -94,26 +635,875 @@ func (d *dataStore) GetAll() ([]task.Task, error) {
 	return tasks, nil
 }
 
-// The Put method inserts task into the tasks table.
-func (d *dataStore) Put(task task.Task) (string, error) {
-	db, err := d.db()
+// The Tags method counts the tasks visible to ctx with each tag in the task_tags table, ordered by tag.
+func (d *dataStore) Tags(ctx context.Context) ([]task.TagCount, error) {
+	db, err := d.db(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var c conditions
+	c.scope(ctx)
+	rows, err := db.QueryContext(ctx, "SELECT tag, count(*) FROM task_tags JOIN tasks ON tasks.id = task_tags.task_id"+c.where()+
+		` GROUP BY tag ORDER BY tag COLLATE "C"`, c.args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count tags: %s", err)
+	}
+	defer rows.Close()
+	tags := []task.TagCount{}
+	for rows.Next() {
+		var tc task.TagCount
+		if err := rows.Scan(&tc.Tag, &tc.Count); err != nil {
+			return nil, fmt.Errorf("failed to count tags: %s", err)
+		}
+		tags = append(tags, tc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to count tags: %s", err)
+	}
+	return tags, nil
+}
+
+// The headlineOptions are the ts_headline options producing task.Snippet style snippets.
+var headlineOptions = fmt.Sprintf(`StartSel="%s", StopSel="%s", MaxWords=%d, MinWords=%d, FragmentDelimiter=" ... "`,
+	task.HighlightStart, task.HighlightStop, task.SnippetWords, task.SnippetWords/2)
+
+// The Search method queries the GIN indexed search column of the tasks table for the tasks matching filter which match
+// query, parsed by websearch_to_tsquery, ordered by ts_rank. Words are stemmed, and stop words ignored, so results may
+// differ from the other stores.
+func (d *dataStore) Search(ctx context.Context, query string, filter task.Filter) ([]task.SearchResult, error) {
+	if _, err := task.SearchTerms(query); err != nil {
+		return nil, err
+	}
+	db, err := d.db(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var c conditions
+	c.scope(ctx)
+	c.filter(filter)
+	tsquery := "websearch_to_tsquery('english', " + c.arg(query) + ")"
+	c.conds = append(c.conds, "search @@ "+tsquery)
+	rank := "ts_rank(search, " + tsquery + ")"
+	headline := "ts_headline('english', coalesce(title, '') || ' ' || coalesce(content, ''), " + tsquery + ", " + c.arg(headlineOptions) + ")"
+	rows, err := db.QueryContext(ctx, "SELECT "+selectColumns+", "+rank+", "+headline+" FROM tasks"+c.where()+
+		" ORDER BY "+rank+` DESC, id COLLATE "C"`, c.args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search tasks for %q: %s", query, err)
+	}
+	defer rows.Close()
+	var results []task.SearchResult
+	for rows.Next() {
+		var r task.SearchResult
+		t, err := scanTask(rows, &r.Rank, &r.Snippet)
+		if err != nil {
+			return nil, fmt.Errorf("failed to search tasks for %q: %s", query, err)
+		}
+		r.Task = *t
+		results = append(results, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to search tasks for %q: %s", query, err)
+	}
+	return results, nil
+}
+
+// The Put method inserts task into the tasks table, or replaces the existing task with the same id.
+func (d *dataStore) Put(ctx context.Context, t task.Task) (string, bool, error) {
+	if err := prepare(&t); err != nil {
+		return "", false, err
+	}
+	var created bool
+	err := d.transact(ctx, func(tx *sql.Tx) error {
+		var existing task.Task
+		var createdBy, owner, list sql.NullString
+		err := tx.QueryRowContext(ctx, "SELECT created_at, created_by, revision, owner, list_id FROM tasks WHERE id = $1 FOR UPDATE", t.ID).
+			Scan(&existing.CreatedAt, &createdBy, &existing.Revision, &owner, &list)
+		if err != nil && err != sql.ErrNoRows {
+			return fmt.Errorf("failed to get task %q: %s", t.ID, err)
+		}
+		exists := err == nil
+		if exists {
+			role, err := taskRole(ctx, tx, owner.String, list.String)
+			if err != nil {
+				return err
+			}
+			if err := task.Authorize(role, task.RoleEditor); errors.Is(err, task.ErrNotFound) {
+				return fmt.Errorf("failed to put task %q: %w", t.ID, task.ErrConflict)
+			} else if err != nil {
+				return fmt.Errorf("failed to put task %q: %w", t.ID, err)
+			}
+		}
+		if err := checkList(ctx, tx, &t, list.String); err != nil {
+			return err
+		}
+		if err := checkParent(ctx, tx, &t); err != nil {
+			return err
+		}
+		if err := checkBlockers(ctx, tx, &t); err != nil {
+			return err
+		}
+		if err := task.CheckRevision(ctx, t.ID, existing.Revision); err != nil {
+			return err
+		}
+		t.Owner = task.AssignOwner(ctx, t.Owner, owner.String, exists)
+		existing.CreatedBy = createdBy.String
+		t.Stamp(&existing, time.Now(), task.User(ctx))
+		if !exists {
+			// The missing row could not be locked, so another transaction may have inserted it since.
+			if err := insertTask(ctx, tx, &t); errors.Is(err, task.ErrConflict) {
+				return putConflict(ctx, tx, t.ID)
+			} else if err != nil {
+				return err
+			}
+			created = true
+			return record(ctx, tx, task.EventCreated, t.ID)
+		}
+		if _, err := tx.ExecContext(ctx, "UPDATE tasks SET "+updateColumns+" WHERE id = $1", taskValues(&t)...); err != nil {
+			return fmt.Errorf("failed to put task %q: %s", t.ID, err)
+		}
+		if err := setTags(ctx, tx, t.ID, t.Tags); err != nil {
+			return err
+		}
+		if err := setBlockers(ctx, tx, t.ID, t.BlockedBy); err != nil {
+			return err
+		}
+		return record(ctx, tx, task.EventUpdated, t.ID)
+	})
+	if err != nil {
+		return "", false, err
+	}
+	return t.ID, created, nil
+}
+
+// The putConflict function returns the error of putting the task with the given id, which was missing, but was inserted
+// by another transaction before it could be: an error wrapping task.ErrForbidden if ctx may read but not edit the new
+// task, or task.ErrConflict otherwise, since the put would replace a task it never saw.
+func putConflict(ctx context.Context, tx *sql.Tx, id string) error {
+	var owner, list sql.NullString
+	err := tx.QueryRowContext(ctx, "SELECT owner, list_id FROM tasks WHERE id = $1", id).Scan(&owner, &list)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("failed to put task %q: %w", id, task.ErrConflict)
+	} else if err != nil {
+		return fmt.Errorf("failed to get task %q: %s", id, err)
+	}
+	role, err := taskRole(ctx, tx, owner.String, list.String)
+	if err != nil {
+		return err
+	}
+	if err := task.Authorize(role, task.RoleEditor); errors.Is(err, task.ErrForbidden) {
+		return fmt.Errorf("failed to put task %q: %w", id, err)
+	}
+	return fmt.Errorf("failed to put task %q: %w", id, task.ErrConflict)
+}
+
+// The Create method inserts task into the tasks table, unless a task with the same id already exists.
+func (d *dataStore) Create(ctx context.Context, t task.Task) (string, error) {
+	if err := prepare(&t); err != nil {
+		return "", err
+	}
+	err := d.transact(ctx, func(tx *sql.Tx) error {
+		if err := checkList(ctx, tx, &t, ""); err != nil {
+			return err
+		}
+		if err := checkParent(ctx, tx, &t); err != nil {
+			return err
+		}
+		if err := checkBlockers(ctx, tx, &t); err != nil {
+			return err
+		}
+		t.Owner = task.AssignOwner(ctx, t.Owner, "", false)
+		t.Stamp(nil, time.Now(), task.User(ctx))
+		if err := insertTask(ctx, tx, &t); err != nil {
+			return err
+		}
+		return record(ctx, tx, task.EventCreated, t.ID)
+	})
 	if err != nil {
 		return "", err
 	}
-	if task.ID == "" {
+	return t.ID, nil
+}
+
+// The insertTask function inserts t into the tasks table, with its tags and blockers, unless a task with the same id
+// already exists.
+func insertTask(ctx context.Context, tx *sql.Tx, t *task.Task) error {
+	res, err := tx.ExecContext(ctx, "INSERT INTO tasks ("+taskColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17) ON CONFLICT (id) DO NOTHING",
+		taskValues(t)...)
+	if err != nil {
+		return fmt.Errorf("failed to create task %q: %s", t.ID, err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("failed to create task %q: %s", t.ID, err)
+	} else if n == 0 {
+		return fmt.Errorf("failed to create task %q: %w", t.ID, task.ErrConflict)
+	}
+	if err := setTags(ctx, tx, t.ID, t.Tags); err != nil {
+		return err
+	}
+	return setBlockers(ctx, tx, t.ID, t.BlockedBy)
+}
+
+// The transact method calls fn in a transaction, and commits if fn succeeds.
+func (d *dataStore) transact(ctx context.Context, fn func(*sql.Tx) error) error {
+	db, err := d.db(ctx)
+	if err != nil {
+		return err
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %s", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %s", err)
+	}
+	return nil
+}
+
+// The prepare function validates a task for writing, generates an id if necessary, and normalizes its status, tags, and
+// blockers.
+func prepare(t *task.Task) error {
+	if t.ID == "" {
 		// No id, so generate a random id.
-		task.ID = xid.New().String()
+		t.ID = xid.New().String()
+	}
+	if err := t.Validate(); err != nil {
+		return err
+	}
+	t.SetStatus(t.Status, time.Now())
+	t.Tags = task.NormalizeTags(t.Tags)
+	t.BlockedBy = task.NormalizeIDs(t.BlockedBy)
+	return nil
+}
+
+// The Update method applies a merge patch to the task with the given id in the tasks table.
+func (d *dataStore) Update(ctx context.Context, id string, patch []byte) (*task.Task, error) {
+	return d.modify(ctx, id, func(t *task.Task) error {
+		patched, err := task.MergePatch(*t, patch, time.Now())
+		if err != nil {
+			return err
+		}
+		*t = patched
+		return nil
+	})
+}
+
+// The SetStatus method updates the status of the task with the given id in the tasks table.
+func (d *dataStore) SetStatus(ctx context.Context, id string, status task.Status) (*task.Task, error) {
+	if !status.Valid() {
+		return nil, fmt.Errorf("%w: unknown status %q", task.ErrInvalid, status)
+	}
+	return d.modify(ctx, id, func(t *task.Task) error {
+		t.SetStatus(status, time.Now())
+		return nil
+	})
+}
+
+// The modify method locks the row of the task with the given id, applies fn, and stamps and writes back the modified
+// task. If the task was just done, its next occurrence is inserted too.
+func (d *dataStore) modify(ctx context.Context, id string, fn func(*task.Task) error) (*task.Task, error) {
+	if err := task.ValidateID(id); err != nil {
+		return nil, err
+	}
+	var t *task.Task
+	err := d.transact(ctx, func(tx *sql.Tx) error {
+		var err error
+		t, err = scanTask(tx.QueryRowContext(ctx, "SELECT "+selectColumns+" FROM tasks WHERE id = $1 FOR UPDATE", id))
+		if err != nil {
+			return getError(id, err)
+		}
+		role, err := taskRole(ctx, tx, t.Owner, t.List)
+		if err != nil {
+			return err
+		}
+		if err := task.Authorize(role, task.RoleEditor); err != nil {
+			return fmt.Errorf("failed to update task %q: %w", id, err)
+		}
+		if err := task.CheckRevision(ctx, id, t.Revision); err != nil {
+			return err
+		}
+		old := *t
+		if err := fn(t); err != nil {
+			return err
+		}
+		if err := checkList(ctx, tx, t, old.List); err != nil {
+			return err
+		}
+		if err := checkParent(ctx, tx, t); err != nil {
+			return err
+		}
+		if err := checkBlockers(ctx, tx, t); err != nil {
+			return err
+		}
+		next, err := task.NextOccurrence(old, *t)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		if next != nil {
+			if err := prepare(next); err != nil {
+				return err
+			}
+			next.Stamp(nil, now, task.User(ctx))
+			if err := insertTask(ctx, tx, next); err != nil {
+				return err
+			}
+			if err := record(ctx, tx, task.EventCreated, next.ID); err != nil {
+				return err
+			}
+			t.Next = next.ID
+		}
+		t.Stamp(&old, now, task.User(ctx))
+		if _, err := tx.ExecContext(ctx, "UPDATE tasks SET "+updateColumns+" WHERE id = $1", taskValues(t)...); err != nil {
+			return fmt.Errorf("failed to update task %q: %s", id, err)
+		}
+		if err := setTags(ctx, tx, id, t.Tags); err != nil {
+			return err
+		}
+		if err := setBlockers(ctx, tx, id, t.BlockedBy); err != nil {
+			return err
+		}
+		return record(ctx, tx, task.EventUpdated, id)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// The Children method queries the tasks table for the subtasks of the task with the given id, recursively. Subtasks
+// which are not visible to ctx are left out, along with their own subtasks.
+func (d *dataStore) Children(ctx context.Context, id string) ([]task.Tree, error) {
+	if err := task.ValidateID(id); err != nil {
+		return nil, err
+	}
+	db, err := d.db(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c := conditions{conds: []string{"id IN (SELECT id FROM descendants)"}, args: []interface{}{id}}
+	c.scope(ctx)
+	tasks, err := queryTasks(ctx, db, descendants+"SELECT "+selectColumns+" FROM tasks"+c.where(), c.args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get subtasks of task %q: %s", id, err)
+	}
+	if len(tasks) == 0 {
+		return nil, fmt.Errorf("failed to get task %q: %w", id, task.ErrNotFound)
+	}
+	return task.ChildTrees(tasks, id), nil
+}
+
+// The DeleteTree method deletes the task with the given id, and all of its subtasks, from the tasks table.
+func (d *dataStore) DeleteTree(ctx context.Context, id string) error {
+	if err := task.ValidateID(id); err != nil {
+		return err
+	}
+	return d.transact(ctx, func(tx *sql.Tx) error {
+		if err := checkRevision(ctx, tx, id); err != nil {
+			return err
+		}
+		if err := checkSubtasks(ctx, tx, id); err != nil {
+			return err
+		}
+		return deleteTasks(ctx, tx, descendants, "SELECT id FROM descendants", id)
+	})
+}
+
+// The checkSubtasks function locks the rows of the subtasks of the task with the given id, recursively, and returns an
+// error wrapping task.ErrForbidden if ctx may not edit one of them, even if it may not read it.
+func checkSubtasks(ctx context.Context, tx *sql.Tx, id string) error {
+	subtasks, err := queryTasks(ctx, tx, descendants+"SELECT "+selectColumns+" FROM tasks WHERE id IN (SELECT id FROM descendants) AND id <> $1 FOR UPDATE", id)
+	if err != nil {
+		return fmt.Errorf("failed to get subtasks of task %q: %s", id, err)
+	}
+	for _, t := range subtasks {
+		role, err := taskRole(ctx, tx, t.Owner, t.List)
+		if err != nil {
+			return err
+		}
+		if !role.Allows(task.RoleEditor) {
+			return fmt.Errorf("failed to delete task %q: %w: may not delete all of its subtasks", id, task.ErrForbidden)
+		}
+	}
+	return nil
+}
+
+// The Delete method deletes the task with the given id from the tasks table. Its subtasks are kept by the foreign key,
+// as top level tasks.
+func (d *dataStore) Delete(ctx context.Context, id string) error {
+	if err := task.ValidateID(id); err != nil {
+		return err
+	}
+	return d.transact(ctx, func(tx *sql.Tx) error {
+		if err := checkRevision(ctx, tx, id); err != nil {
+			return err
+		}
+		return deleteTasks(ctx, tx, "", "$1", id)
+	})
+}
+
+// The checkRevision function locks the row of the task with the given id, and returns an error wrapping
+// task.ErrNotFound if it does not exist, or ctx may not read it, task.ErrForbidden if ctx may not edit it, or
+// task.ErrRevisionMismatch if ctx expects a different revision.
+func checkRevision(ctx context.Context, tx *sql.Tx, id string) error {
+	var revision int64
+	var owner, list sql.NullString
+	err := tx.QueryRowContext(ctx, "SELECT revision, owner, list_id FROM tasks WHERE id = $1 FOR UPDATE", id).Scan(&revision, &owner, &list)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("failed to delete task %q: %w", id, task.ErrNotFound)
+	} else if err != nil {
+		return fmt.Errorf("failed to delete task %q: %s", id, err)
+	}
+	role, err := taskRole(ctx, tx, owner.String, list.String)
+	if err != nil {
+		return err
+	}
+	if err := task.Authorize(role, task.RoleEditor); err != nil {
+		return fmt.Errorf("failed to delete task %q: %w", id, err)
+	}
+	return task.CheckRevision(ctx, id, revision)
+}
+
+// The deleteTasks function deletes the tasks whose ids are selected by the subquery deleted, which may refer to the
+// common table expressions of with, and the arguments. The remaining tasks whose parent or blockers are cleared by the
+// foreign keys have their revisions incremented first. Events are recorded for both last, so that the events lock is
+// always taken after the row locks, as in modify.
+func deleteTasks(ctx context.Context, tx *sql.Tx, with, deleted string, args ...interface{}) error {
+	tasks, err := queryTasks(ctx, tx, with+"SELECT "+selectColumns+" FROM tasks WHERE id IN ("+deleted+`) ORDER BY id COLLATE "C"`, args...)
+	if err != nil {
+		return fmt.Errorf("failed to get deleted tasks: %s", err)
+	}
+	dependents, err := queryIDs(ctx, tx, with+"UPDATE tasks SET revision = revision + 1 WHERE id NOT IN ("+deleted+") AND "+
+		"(parent_id IN ("+deleted+") OR id IN (SELECT task_id FROM task_blockers WHERE blocker_id IN ("+deleted+"))) RETURNING id", args...)
+	if err != nil {
+		return fmt.Errorf("failed to update dependents of deleted tasks: %s", err)
+	}
+	if _, err := tx.ExecContext(ctx, with+"DELETE FROM tasks WHERE id IN ("+deleted+")", args...); err != nil {
+		return fmt.Errorf("failed to delete tasks: %s", err)
+	}
+	if err := recordTasks(ctx, tx, task.EventDeleted, tasks); err != nil {
+		return err
+	}
+	return record(ctx, tx, task.EventUpdated, dependents...)
+}
+
+// The queryIDs function returns the ids returned by query, with args.
+func queryIDs(ctx context.Context, tx *sql.Tx, query string, args ...interface{}) ([]string, error) {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// The listColumns are the columns of the lists table, and the list's grants from the list_grants table, as a json array
+// like task.Grant's, scanned by scanList.
+const listColumns = `id, name, owner, (SELECT json_agg(json_build_object(kind, grantee, 'role', role) ORDER BY kind = 'group', grantee COLLATE "C") ` +
+	`FROM list_grants WHERE list_id = lists.id)`
+
+// The scanList function scans a row of listColumns into a list.
+func scanList(s scanner) (*task.TaskList, error) {
+	var l task.TaskList
+	var owner sql.NullString
+	var grants []byte
+	if err := s.Scan(&l.ID, &l.Name, &owner, &grants); err != nil {
+		return nil, err
+	}
+	l.Owner = owner.String
+	if grants != nil {
+		if err := json.Unmarshal(grants, &l.Grants); err != nil {
+			return nil, fmt.Errorf("failed to deserialize grants of list %q: %s", l.ID, err)
+		}
+	}
+	return &l, nil
+}
+
+// A querier is implemented by both sql.DB and sql.Tx.
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// The getList function queries the lists table for the list with the given id, regardless of the role of ctx.
+func getList(ctx context.Context, q querier, id string) (*task.TaskList, error) {
+	l, err := scanList(q.QueryRowContext(ctx, "SELECT "+listColumns+" FROM lists WHERE id = $1", id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to get list %q: %w", id, task.ErrNotFound)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get list %q: %s", id, err)
+	}
+	return l, nil
+}
+
+// The lockList function locks the row of the list with the given id until tx ends, and returns the list, or an error
+// wrapping task.ErrNotFound if it does not exist, or ctx may not read it, or task.ErrForbidden if the role of ctx does
+// not allow want.
+func lockList(ctx context.Context, tx *sql.Tx, id string, want task.Role) (*task.TaskList, error) {
+	var found string
+	if err := tx.QueryRowContext(ctx, "SELECT id FROM lists WHERE id = $1 FOR UPDATE", id).Scan(&found); err == sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to get list %q: %w", id, task.ErrNotFound)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get list %q: %s", id, err)
+	}
+	l, err := getList(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := task.Authorize(task.ListRole(ctx, *l), want); err != nil {
+		return nil, fmt.Errorf("failed to change list %q: %w", id, err)
+	}
+	return l, nil
+}
+
+// The GetList method queries the lists table for a single list with the given id.
+func (d *dataStore) GetList(ctx context.Context, id string) (*task.TaskList, error) {
+	if err := task.ValidateID(id); err != nil {
+		return nil, err
+	}
+	db, err := d.db(ctx)
+	if err != nil {
+		return nil, err
+	}
+	l, err := getList(ctx, db, id)
+	if err != nil {
+		return nil, err
+	}
+	if task.ListRole(ctx, *l) == "" {
+		return nil, fmt.Errorf("failed to get list %q: %w", id, task.ErrNotFound)
 	}
-	_, err = db.Exec("INSERT INTO tasks (id, title, content) VALUES ($1, $2, $3)", task.ID, task.Title, task.Description)
-	return task.ID, err
+	return l, nil
 }
 
-// The Delete method deletes the task with the given id from the tasks table.
-func (d *dataStore) Delete(id string) error {
-	db, err := d.db()
+// The GetLists method queries the lists table for all lists ctx may read, ordered by id.
+func (d *dataStore) GetLists(ctx context.Context) ([]task.TaskList, error) {
+	db, err := d.db(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var c conditions
+	c.scopeLists(ctx)
+	rows, err := db.QueryContext(ctx, "SELECT "+listColumns+" FROM lists"+c.where()+` ORDER BY id COLLATE "C"`, c.args...)
 	if err != nil {
+		return nil, fmt.Errorf("failed to get lists: %s", err)
+	}
+	defer rows.Close()
+	lists := []task.TaskList{}
+	for rows.Next() {
+		l, err := scanList(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get lists: %s", err)
+		}
+		lists = append(lists, *l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get lists: %s", err)
+	}
+	return lists, nil
+}
+
+// The PutList method inserts l into the lists table, or replaces the existing list with the same id, keeping its
+// grants.
+func (d *dataStore) PutList(ctx context.Context, l task.TaskList) (string, bool, error) {
+	if l.ID == "" {
+		// No id, so generate a random id.
+		l.ID = xid.New().String()
+	}
+	if err := l.Validate(); err != nil {
+		return "", false, err
+	}
+	var created bool
+	err := d.transact(ctx, func(tx *sql.Tx) error {
+		existing, err := lockList(ctx, tx, l.ID, task.RoleEditor)
+		if errors.Is(err, task.ErrNotFound) {
+			var found string
+			if tx.QueryRowContext(ctx, "SELECT id FROM lists WHERE id = $1", l.ID).Scan(&found) == nil {
+				return fmt.Errorf("failed to put list %q: %w", l.ID, task.ErrConflict)
+			}
+			l.Owner = task.AssignOwner(ctx, l.Owner, "", false)
+		} else if err != nil {
+			return err
+		} else {
+			l.Owner = task.AssignOwner(ctx, l.Owner, existing.Owner, true)
+		}
+		// xmax is only zero for freshly inserted rows.
+		if err := tx.QueryRowContext(ctx, "INSERT INTO lists (id, name, owner) VALUES ($1, $2, $3) ON CONFLICT (id) DO UPDATE SET name = $2, owner = $3 RETURNING xmax = 0",
+			l.ID, l.Name, nullString(l.Owner)).Scan(&created); err != nil {
+			return fmt.Errorf("failed to put list %q: %s", l.ID, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", false, err
+	}
+	return l.ID, created, nil
+}
+
+// The DeleteList method deletes the list with the given id from the lists table, after deleting its tasks. Its grants
+// are deleted by the foreign key.
+func (d *dataStore) DeleteList(ctx context.Context, id string) error {
+	if err := task.ValidateID(id); err != nil {
+		return err
+	}
+	return d.transact(ctx, func(tx *sql.Tx) error {
+		if _, err := lockList(ctx, tx, id, task.RoleOwner); err != nil {
+			return err
+		}
+		if err := deleteTasks(ctx, tx, "", "SELECT id FROM tasks WHERE list_id = $1", id); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM lists WHERE id = $1", id); err != nil {
+			return fmt.Errorf("failed to delete list %q: %s", id, err)
+		}
+		return nil
+	})
+}
+
+// The Share method inserts grant into the list_grants table, replacing any grant to the same grantee.
+func (d *dataStore) Share(ctx context.Context, list string, grant task.Grant) (*task.TaskList, error) {
+	if err := grant.Validate(); err != nil {
+		return nil, err
+	}
+	kind, grantee := grantKey(grant.Grantee)
+	return d.share(ctx, list, "INSERT INTO list_grants (list_id, kind, grantee, role) VALUES ($1, $2, $3, $4) "+
+		"ON CONFLICT (list_id, kind, grantee) DO UPDATE SET role = $4", list, kind, grantee, grant.Role)
+}
+
+// The Unshare method deletes the grant to grantee from the list_grants table.
+func (d *dataStore) Unshare(ctx context.Context, list string, grantee task.Grantee) (*task.TaskList, error) {
+	if err := grantee.Validate(); err != nil {
+		return nil, err
+	}
+	kind, name := grantKey(grantee)
+	return d.share(ctx, list, "DELETE FROM list_grants WHERE list_id = $1 AND kind = $2 AND grantee = $3", list, kind, name)
+}
+
+// The grantKey function returns the kind and grantee columns of g.
+func grantKey(g task.Grantee) (string, string) {
+	if g.Group != "" {
+		return "group", g.Group
+	}
+	return "user", g.User
+}
+
+// The share method executes the statement changing the grants of the list with the given id, with args, if ctx owns
+// it, and returns the list.
+func (d *dataStore) share(ctx context.Context, id, statement string, args ...interface{}) (*task.TaskList, error) {
+	if err := task.ValidateID(id); err != nil {
+		return nil, err
+	}
+	var l *task.TaskList
+	err := d.transact(ctx, func(tx *sql.Tx) error {
+		if _, err := lockList(ctx, tx, id, task.RoleOwner); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, statement, args...); err != nil {
+			return fmt.Errorf("failed to update grants of list %q: %s", id, err)
+		}
+		var err error
+		l, err = getList(ctx, tx, id)
 		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// The eventsChannel is the postgres notification channel on which writes announce new events.
+const eventsChannel = "task_events"
+
+// The eventsLockID is the key of the postgres advisory lock held while recording events, until the transaction ends, so
+// that events are committed in the order of their ids, and watchers cannot skip an event committed late.
+const eventsLockID = 8675311
+
+// The record function inserts an event of type typ into the task_events table for each of the tasks with the given ids,
+// as they are in tx, and notifies the watchers when tx commits. See recordTasks.
+func record(ctx context.Context, tx *sql.Tx, typ task.EventType, ids ...string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	tasks, err := queryTasks(ctx, tx, "SELECT "+selectColumns+` FROM tasks WHERE id = ANY($1) ORDER BY id COLLATE "C"`, pq.Array(ids))
+	if err != nil {
+		return fmt.Errorf("failed to get tasks for their events: %s", err)
+	}
+	return recordTasks(ctx, tx, typ, tasks)
+}
+
+// The recordTasks function inserts an event of type typ into the task_events table for each of the tasks, and notifies
+// the watchers when tx commits. Events older than a day are pruned. Deleted tasks must be read before they are deleted,
+// and recorded after, so that the events lock is taken last.
+func recordTasks(ctx context.Context, tx *sql.Tx, typ task.EventType, tasks []task.Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+	if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1)", eventsLockID); err != nil {
+		return fmt.Errorf("failed to lock events: %s", err)
+	}
+	for _, t := range tasks {
+		bs, err := json.Marshal(t)
+		if err != nil {
+			return fmt.Errorf("failed to serialize event of task %q: %s", t.ID, err)
+		}
+		if _, err := tx.ExecContext(ctx, "INSERT INTO task_events (type, task_id, owner, list_id, task) VALUES ($1, $2, $3, $4, $5)",
+			typ, t.ID, nullString(t.Owner), nullString(t.List), bs); err != nil {
+			return fmt.Errorf("failed to record event of task %q: %s", t.ID, err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM task_events WHERE created_at < now() - interval '1 day'"); err != nil {
+		return fmt.Errorf("failed to prune events: %s", err)
+	}
+	if _, err := tx.ExecContext(ctx, "SELECT pg_notify($1, '')", eventsChannel); err != nil {
+		return fmt.Errorf("failed to notify watchers: %s", err)
+	}
+	return nil
+}
+
+// The eventsBatch is the greatest number of events queried at once by a watcher.
+const eventsBatch = 100
+
+// The Watch method queries the task_events table for the events visible to ctx after after, and again whenever a write
+// notifies the eventsChannel.
+func (d *dataStore) Watch(ctx context.Context, after int64) (<-chan task.Event, error) {
+	db, err := d.db(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// Listen first, so that no notification is missed between finding the latest event and waiting.
+	wake, err := d.listen()
+	if err != nil {
+		return nil, err
+	}
+	if after == 0 {
+		err = db.QueryRowContext(ctx, "SELECT COALESCE(max(id), 0) FROM task_events").Scan(&after)
+	} else {
+		var found bool
+		err = db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM task_events WHERE id = $1)", after).Scan(&found)
+		if err == nil && !found {
+			d.unlisten(wake)
+			return nil, fmt.Errorf("failed to watch after event %d: %w", after, task.ErrExpired)
+		}
+	}
+	if err != nil {
+		d.unlisten(wake)
+		return nil, fmt.Errorf("failed to watch events: %s", err)
+	}
+
+	events := make(chan task.Event)
+	go func() {
+		defer close(events)
+		defer d.unlisten(wake)
+		for {
+			batch, err := queryEvents(ctx, db, after)
+			if err != nil {
+				return
+			}
+			for _, e := range batch {
+				select {
+				case events <- e:
+					after = e.ID
+				case <-ctx.Done():
+					return
+				}
+			}
+			if len(batch) == eventsBatch {
+				continue
+			}
+			select {
+			case <-wake:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events, nil
+}
+
+// The queryEvents function queries the task_events table for the next batch of events visible to ctx after after.
+func queryEvents(ctx context.Context, db *sql.DB, after int64) ([]task.Event, error) {
+	c := conditions{conds: []string{"id > $1"}, args: []interface{}{after}}
+	c.scope(ctx)
+	rows, err := db.QueryContext(ctx, "SELECT id, type, task FROM task_events"+c.where()+" ORDER BY id LIMIT "+
+		fmt.Sprint(eventsBatch), c.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var events []task.Event
+	for rows.Next() {
+		var e task.Event
+		var bs []byte
+		if err := rows.Scan(&e.ID, &e.Type, &bs); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(bs, &e.Task); err != nil {
+			return nil, fmt.Errorf("failed to deserialize event %d: %s", e.ID, err)
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// The listen method returns a new channel, which is signalled when the eventsChannel is notified, until it is passed to
+// unlisten.
+func (d *dataStore) listen() (chan struct{}, error) {
+	d.watchMu.Lock()
+	defer d.watchMu.Unlock()
+	if d.listener == nil {
+		l := pq.NewListener(d.host, time.Second, time.Minute, nil)
+		if err := l.Listen(eventsChannel); err != nil {
+			l.Close()
+			return nil, fmt.Errorf("failed to listen for events: %s", err)
+		}
+		d.listener = l
+		d.watchers = make(map[chan struct{}]struct{})
+		go d.notify(l)
+	}
+	wake := make(chan struct{}, 1)
+	d.watchers[wake] = struct{}{}
+	return wake, nil
+}
+
+// The unlisten method stops signalling wake.
+func (d *dataStore) unlisten(wake chan struct{}) {
+	d.watchMu.Lock()
+	defer d.watchMu.Unlock()
+	delete(d.watchers, wake)
+}
+
+// The notify method signals the watchers on each notification from l, and on reconnecting, when notifications may have
+// been missed. The connection of l is checked each minute, and the watchers signalled anyway.
+func (d *dataStore) notify(l *pq.Listener) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case _, ok := <-l.Notify:
+			if !ok {
+				return
+			}
+		case <-ticker.C:
+			go l.Ping()
+		}
+		d.watchMu.Lock()
+		for wake := range d.watchers {
+			select {
+			case wake <- struct{}{}:
+			default:
+			}
+		}
+		d.watchMu.Unlock()
 	}
-	_, err = db.Exec("DELETE FROM tasks WHERE id = $1", id)
-	return err
 }
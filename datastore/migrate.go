@@ -0,0 +1,227 @@
+package datastore
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// The migrationFiles are the embedded sql migrations, named like "0001_create_tasks.up.sql" and
+// "0001_create_tasks.down.sql". Databases created before versioned migrations may already have the schema of the first
+// three, so they only add tables and columns if missing.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// The migrationLockID is the key of the postgres advisory lock held while migrating, so that concurrent servers apply
+// each migration once.
+const migrationLockID = 8675309
+
+// A Migration is a single versioned change to the database schema.
+type Migration struct {
+	Version int
+	Name    string
+	// The sql statements to apply the migration.
+	Up string
+	// The sql statements to revert the migration.
+	Down string
+}
+
+// The migrationName matches the file names of migrations.
+var migrationName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// The Migrations function returns all migrations embedded in the binary, ordered by version. Versions start at 1, and
+// have no gaps.
+func Migrations() ([]Migration, error) {
+	return loadMigrations(migrationFiles, "migrations")
+}
+
+// The loadMigrations function reads migrations from the files in dir.
+func loadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %s", err)
+	}
+	byVersion := make(map[int]*Migration)
+	for _, e := range entries {
+		match := migrationName.FindStringSubmatch(e.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name %q", e.Name())
+		}
+		version, err := strconv.Atoi(match[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %q: %s", e.Name(), err)
+		}
+		contents, err := fs.ReadFile(fsys, path.Join(dir, e.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %q: %s", e.Name(), err)
+		}
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("migration version %d has conflicting names %q and %q", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(contents)
+		} else {
+			m.Down = string(contents)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			return nil, fmt.Errorf("missing migration version %d", i+1)
+		}
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s must have both up and down files", m.Version, m.Name)
+		}
+	}
+	return migrations, nil
+}
+
+// A Migrator applies and reverts migrations against a postgres database.
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+// The NewMigrator function creates a new Migrator for the postgres database host.
+func NewMigrator(host string) (*Migrator, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("postgres", host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database connection for host (%s): %s", host, err)
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// The Close method closes the database connection.
+func (m *Migrator) Close() error {
+	return m.db.Close()
+}
+
+// The Migrations method returns all known migrations, ordered by version.
+func (m *Migrator) Migrations() []Migration {
+	return m.migrations
+}
+
+// The Latest method returns the latest known migration version.
+func (m *Migrator) Latest() int {
+	return len(m.migrations)
+}
+
+// The Version method returns the current schema version of the database. A database without migrations has version 0.
+func (m *Migrator) Version(ctx context.Context) (int, error) {
+	var version int
+	err := m.locked(ctx, func(tx *sql.Tx) error {
+		var err error
+		version, err = schemaVersion(ctx, tx)
+		return err
+	})
+	return version, err
+}
+
+// The Up method applies migrations in order until the database is at version. A database already at or beyond version
+// is unchanged.
+func (m *Migrator) Up(ctx context.Context, version int) error {
+	if version < 0 || version > m.Latest() {
+		return fmt.Errorf("unknown migration version %d. latest is %d", version, m.Latest())
+	}
+	return m.locked(ctx, func(tx *sql.Tx) error {
+		current, err := schemaVersion(ctx, tx)
+		if err != nil {
+			return err
+		}
+		if current >= version {
+			return nil
+		}
+		for _, mig := range m.migrations[current:version] {
+			if _, err := tx.ExecContext(ctx, mig.Up); err != nil {
+				return fmt.Errorf("failed to apply migration %d_%s: %s", mig.Version, mig.Name, err)
+			}
+			if _, err := tx.ExecContext(ctx, "INSERT INTO schema_version (version, name) VALUES ($1, $2)", mig.Version, mig.Name); err != nil {
+				return fmt.Errorf("failed to record migration %d_%s: %s", mig.Version, mig.Name, err)
+			}
+		}
+		return nil
+	})
+}
+
+// The Down method reverts migrations in reverse order until the database is at version. A database already at or
+// before version is unchanged.
+func (m *Migrator) Down(ctx context.Context, version int) error {
+	if version < 0 || version > m.Latest() {
+		return fmt.Errorf("unknown migration version %d. latest is %d", version, m.Latest())
+	}
+	return m.locked(ctx, func(tx *sql.Tx) error {
+		current, err := schemaVersion(ctx, tx)
+		if err != nil {
+			return err
+		}
+		if current > m.Latest() {
+			return fmt.Errorf("database version %d is newer than the latest known version %d", current, m.Latest())
+		}
+		for i := current - 1; i >= version; i-- {
+			mig := m.migrations[i]
+			if _, err := tx.ExecContext(ctx, mig.Down); err != nil {
+				return fmt.Errorf("failed to revert migration %d_%s: %s", mig.Version, mig.Name, err)
+			}
+			if _, err := tx.ExecContext(ctx, "DELETE FROM schema_version WHERE version = $1", mig.Version); err != nil {
+				return fmt.Errorf("failed to record reverting migration %d_%s: %s", mig.Version, mig.Name, err)
+			}
+		}
+		return nil
+	})
+}
+
+// The locked method calls fn in a transaction holding the migration lock, and commits if fn succeeds. The
+// schema_version table is created if necessary. Every change is made in the one transaction, so a failed migration
+// leaves the database unchanged.
+func (m *Migrator) locked(ctx context.Context, fn func(*sql.Tx) error) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %s", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1)", migrationLockID); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %s", err)
+	}
+	if _, err := tx.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS schema_version (version INTEGER PRIMARY KEY, name TEXT NOT NULL, applied TIMESTAMPTZ NOT NULL DEFAULT now())"); err != nil {
+		return fmt.Errorf("failed to create schema_version table: %s", err)
+	}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit migration: %s", err)
+	}
+	return nil
+}
+
+// The schemaVersion function returns the latest applied migration version, or 0 if none have been applied.
+func schemaVersion(ctx context.Context, tx *sql.Tx) (int, error) {
+	var version int
+	if err := tx.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) FROM schema_version").Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to query schema version: %s", err)
+	}
+	return version, nil
+}
@@ -0,0 +1,38 @@
+package datastore
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+// Tests that the embedded migrations are valid.
+func TestMigrations(t *testing.T) {
+	migrations, err := Migrations()
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("expected embedded migrations")
+	}
+	if m := migrations[0]; m.Version != 1 || m.Name != "create_tasks" {
+		t.Fatalf("expected first migration 1_create_tasks but got %d_%s", m.Version, m.Name)
+	}
+}
+
+// Tests that malformed sets of migrations are rejected.
+func TestLoadMigrationsInvalid(t *testing.T) {
+	for name, files := range map[string][]string{
+		"bad name":         {"create.up.sql", "create.down.sql"},
+		"missing down":     {"1_create.up.sql"},
+		"gap":              {"1_create.up.sql", "1_create.down.sql", "3_alter.up.sql", "3_alter.down.sql"},
+		"conflicting name": {"1_create.up.sql", "1_other.down.sql"},
+	} {
+		fsys := fstest.MapFS{}
+		for _, f := range files {
+			fsys["migrations/"+f] = &fstest.MapFile{Data: []byte("SELECT 1;")}
+		}
+		if _, err := loadMigrations(fsys, "migrations"); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
@@ -0,0 +1 @@
+CREATE TABLE IF NOT EXISTS tasks (id TEXT PRIMARY KEY, title TEXT, content TEXT);
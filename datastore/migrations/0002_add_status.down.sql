@@ -0,0 +1,3 @@
+ALTER TABLE tasks
+    DROP COLUMN status,
+    DROP COLUMN completed;
@@ -0,0 +1,3 @@
+ALTER TABLE tasks
+    ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'open',
+    ADD COLUMN IF NOT EXISTS completed TIMESTAMPTZ;
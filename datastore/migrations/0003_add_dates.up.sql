@@ -0,0 +1,3 @@
+ALTER TABLE tasks
+    ADD COLUMN IF NOT EXISTS start TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS due TIMESTAMPTZ;
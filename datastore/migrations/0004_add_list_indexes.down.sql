@@ -0,0 +1 @@
+DROP INDEX tasks_id_c, tasks_title_id, tasks_due_id;
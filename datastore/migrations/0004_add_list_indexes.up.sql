@@ -0,0 +1,4 @@
+-- Indexes for the orders which tasks are listed in, so that each page is an index range scan.
+CREATE INDEX tasks_id_c ON tasks (id COLLATE "C");
+CREATE INDEX tasks_title_id ON tasks (title COLLATE "C", id COLLATE "C");
+CREATE INDEX tasks_due_id ON tasks (due, id COLLATE "C");
@@ -0,0 +1,7 @@
+-- Tags are a set per task, so each is a row, and deleted with its task.
+CREATE TABLE task_tags (
+    task_id TEXT NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+    tag TEXT NOT NULL,
+    PRIMARY KEY (task_id, tag)
+);
+CREATE INDEX task_tags_tag ON task_tags (tag);
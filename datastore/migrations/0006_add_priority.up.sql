@@ -0,0 +1,3 @@
+-- Tasks without a priority have a NULL priority, so that they are ordered last.
+ALTER TABLE tasks ADD COLUMN priority TEXT;
+CREATE INDEX tasks_priority_id ON tasks (priority, id COLLATE "C");
@@ -0,0 +1,2 @@
+ALTER TABLE tasks DROP COLUMN list_id;
+DROP TABLE lists;
@@ -0,0 +1,7 @@
+-- Tasks without a list have a NULL list_id, and a list's tasks are deleted with it.
+CREATE TABLE lists (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL DEFAULT ''
+);
+ALTER TABLE tasks ADD COLUMN list_id TEXT REFERENCES lists (id) ON DELETE CASCADE;
+CREATE INDEX tasks_list_id ON tasks (list_id, id COLLATE "C");
@@ -0,0 +1,3 @@
+-- Subtasks are kept, as top level tasks, when their parent is deleted.
+ALTER TABLE tasks ADD COLUMN parent_id TEXT REFERENCES tasks (id) ON DELETE SET NULL;
+CREATE INDEX tasks_parent_id ON tasks (parent_id, id COLLATE "C");
@@ -0,0 +1,7 @@
+-- Blockers are a set of edges between tasks, deleted with either task.
+CREATE TABLE task_blockers (
+    task_id TEXT NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+    blocker_id TEXT NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+    PRIMARY KEY (task_id, blocker_id)
+);
+CREATE INDEX task_blockers_blocker_id ON task_blockers (blocker_id);
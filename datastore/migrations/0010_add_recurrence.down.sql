@@ -0,0 +1,3 @@
+ALTER TABLE tasks
+    DROP COLUMN recurrence,
+    DROP COLUMN next_id;
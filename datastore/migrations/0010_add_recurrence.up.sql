@@ -0,0 +1,4 @@
+-- The next occurrence is not a foreign key, so that deleting it keeps the history of the series.
+ALTER TABLE tasks
+    ADD COLUMN recurrence TEXT,
+    ADD COLUMN next_id TEXT;
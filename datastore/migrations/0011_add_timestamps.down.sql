@@ -0,0 +1,5 @@
+DROP INDEX tasks_created_at_id, tasks_updated_at_id;
+ALTER TABLE tasks
+    DROP COLUMN created_at,
+    DROP COLUMN updated_at,
+    DROP COLUMN created_by;
@@ -0,0 +1,8 @@
+-- Existing tasks are stamped with the time of the migration, since their history is unknown.
+ALTER TABLE tasks
+    ADD COLUMN created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    ADD COLUMN updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    ADD COLUMN created_by TEXT;
+-- Indexes for listing tasks by recency, and filtering them by updated_at.
+CREATE INDEX tasks_created_at_id ON tasks (created_at, id COLLATE "C");
+CREATE INDEX tasks_updated_at_id ON tasks (updated_at, id COLLATE "C");
@@ -0,0 +1,2 @@
+-- Existing tasks start at the first revision.
+ALTER TABLE tasks ADD COLUMN revision BIGINT NOT NULL DEFAULT 1;
@@ -0,0 +1,2 @@
+DROP INDEX tasks_search;
+ALTER TABLE tasks DROP COLUMN search;
@@ -0,0 +1,5 @@
+-- The words of each task's title and description, weighted so that title matches rank higher, for full-text search.
+ALTER TABLE tasks ADD COLUMN search tsvector GENERATED ALWAYS AS
+    (setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+     setweight(to_tsvector('english', coalesce(content, '')), 'B')) STORED;
+CREATE INDEX tasks_search ON tasks USING GIN (search);
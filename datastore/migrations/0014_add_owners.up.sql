@@ -0,0 +1,8 @@
+-- The user each task and list belongs to. Existing tasks belong to the users who created them, and tasks and lists
+-- without an owner are only visible to admins, and servers without authentication.
+ALTER TABLE tasks ADD COLUMN owner TEXT;
+ALTER TABLE lists ADD COLUMN owner TEXT;
+UPDATE tasks SET owner = created_by;
+-- Indexes for scoping queries to the tasks and lists of a single user.
+CREATE INDEX tasks_owner_id ON tasks (owner, id COLLATE "C");
+CREATE INDEX lists_owner_id ON lists (owner, id COLLATE "C");
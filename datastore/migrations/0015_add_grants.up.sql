@@ -0,0 +1,10 @@
+-- The users and groups each list is shared with, and their roles. Grants are deleted with their list.
+CREATE TABLE list_grants (
+    list_id TEXT NOT NULL REFERENCES lists (id) ON DELETE CASCADE,
+    kind TEXT NOT NULL CHECK (kind IN ('user', 'group')),
+    grantee TEXT NOT NULL,
+    role TEXT NOT NULL CHECK (role IN ('viewer', 'editor')),
+    PRIMARY KEY (list_id, kind, grantee)
+);
+-- Index for finding the lists shared with a user or group.
+CREATE INDEX list_grants_grantee ON list_grants (kind, grantee);
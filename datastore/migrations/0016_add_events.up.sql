@@ -0,0 +1,13 @@
+-- The events of the changes to tasks, for watching, in the order they were committed. The owner and list_id of each
+-- task are copied from it, so that events are scoped like tasks. Events are pruned after a day.
+CREATE TABLE task_events (
+    id BIGSERIAL PRIMARY KEY,
+    type TEXT NOT NULL CHECK (type IN ('created', 'updated', 'deleted')),
+    task_id TEXT NOT NULL,
+    owner TEXT,
+    list_id TEXT,
+    task JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+-- Index for pruning events.
+CREATE INDEX task_events_created_at ON task_events (created_at);
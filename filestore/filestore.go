@@ -0,0 +1,198 @@
+// Package filestore provides a task.TaskInterface backed by a single local file, requiring no external database.
+//
+// Tasks and lists are held in memory, and every change is appended to the file as a line of json, and synced to disk
+// before it is applied. When the file is opened, the changes are replayed, and the file is compacted to one line per
+// list and task. A change torn by a crash is discarded, so the file always reflects every change which was acknowledged.
+// A file must only be opened by a single process at a time.
+package filestore
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/jmank88/todo/memstore"
+	"github.com/jmank88/todo/task"
+)
+
+// The NewFilestore function opens or creates the file at path, and returns a task.TaskInterface backed by it. The
+// returned value also implements io.Closer, for closing the file.
+func NewFilestore(path string) (task.TaskInterface, error) {
+	changes, err := readChanges(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file %q: %s", path, err)
+	}
+
+	f := &fileStore{}
+	f.TaskInterface = memstore.NewMemstore(memstore.Restore(changes), memstore.Journal(f.append))
+
+	lists, err := f.GetLists(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore lists from file %q: %s", path, err)
+	}
+	tasks, err := f.GetAll(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore tasks from file %q: %s", path, err)
+	}
+	if err := compact(path, lists, tasks); err != nil {
+		return nil, fmt.Errorf("failed to compact file %q: %s", path, err)
+	}
+
+	f.file, err = os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file %q: %s", path, err)
+	}
+	if f.offset, err = f.file.Seek(0, io.SeekEnd); err != nil {
+		f.file.Close()
+		return nil, fmt.Errorf("failed to seek file %q: %s", path, err)
+	}
+	return f, nil
+}
+
+// A fileStore implements task.TaskInterface with a memstore, which journals every change to a file.
+type fileStore struct {
+	task.TaskInterface
+
+	mu     sync.Mutex
+	file   *os.File
+	offset int64
+	// Set if a failed append could not be rolled back, after which all changes are rejected.
+	broken error
+}
+
+// The append method writes c to the end of the file, and syncs it to disk. A failed write is truncated away, so that it
+// cannot corrupt later changes.
+func (f *fileStore) append(c memstore.Change) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.broken != nil {
+		return f.broken
+	}
+	if f.file == nil {
+		return errors.New("file is closed")
+	}
+	line, err := json.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("failed to serialize change: %s", err)
+	}
+	line = append(line, '\n')
+	if _, err := f.file.Write(line); err != nil {
+		return f.rollback(fmt.Errorf("failed to write change: %s", err))
+	}
+	if err := f.file.Sync(); err != nil {
+		return f.rollback(fmt.Errorf("failed to sync change: %s", err))
+	}
+	f.offset += int64(len(line))
+	return nil
+}
+
+// The rollback method truncates the file to the end of the last successful change, and returns err. If the file cannot
+// be truncated, the store is marked broken.
+func (f *fileStore) rollback(err error) error {
+	if terr := f.file.Truncate(f.offset); terr != nil {
+		f.broken = fmt.Errorf("file store is broken: failed to roll back failed change: %s", terr)
+	}
+	return err
+}
+
+// The Close method closes the file. Changes are rejected after the store is closed.
+func (f *fileStore) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+// The readChanges function reads all changes from the file at path. A missing file has no changes, and a final line
+// which is not terminated, because it was torn by a crash, is ignored.
+func readChanges(path string) ([]memstore.Change, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var changes []memstore.Change
+	r := bufio.NewReader(file)
+	for n := 1; ; n++ {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			return changes, nil
+		} else if err != nil {
+			return nil, err
+		}
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var c memstore.Change
+		if err := json.Unmarshal(line, &c); err != nil {
+			return nil, fmt.Errorf("corrupt change on line %d: %s", n, err)
+		}
+		changes = append(changes, c)
+	}
+}
+
+// The compact function atomically replaces the file at path with one change per list and task. Lists are written first,
+// so that they exist before their tasks are replayed. The new file is written and synced under a temporary name, and
+// then renamed into place.
+func compact(path string, lists []task.TaskList, tasks []task.Task) error {
+	tmp := path + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(file)
+	enc := json.NewEncoder(w)
+	for i := range lists {
+		if err := enc.Encode(memstore.Change{PutList: &lists[i]}); err != nil {
+			file.Close()
+			return err
+		}
+	}
+	for i := range tasks {
+		if err := enc.Encode(memstore.Change{Put: &tasks[i]}); err != nil {
+			file.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+	return syncDir(filepath.Dir(path))
+}
+
+// The syncDir function syncs the directory at path, so that renames within it are durable.
+func syncDir(path string) error {
+	dir, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	return dir.Sync()
+}
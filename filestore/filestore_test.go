@@ -0,0 +1,200 @@
+package filestore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/jmank88/todo/task"
+	"github.com/jmank88/todo/tasktest"
+)
+
+// The fixture function opens a file store in a new temporary directory, and returns its path.
+func fixture(t *testing.T) (task.TaskInterface, string) {
+	path := filepath.Join(t.TempDir(), "todo.db")
+	taskInterface, err := NewFilestore(path)
+	if err != nil {
+		t.Fatal("failed to open file store: ", err)
+	}
+	t.Cleanup(func() { taskInterface.(io.Closer).Close() })
+	return taskInterface, path
+}
+
+// Runs the conformance suite.
+func TestConformance(t *testing.T) {
+	tasktest.RunConformance(t, func(t *testing.T) task.TaskInterface {
+		taskInterface, _ := fixture(t)
+		return taskInterface
+	})
+}
+
+// Runs the isolation suite.
+func TestIsolation(t *testing.T) {
+	tasktest.RunIsolation(t, tasktest.ContextUsers(func(t *testing.T) task.TaskInterface {
+		taskInterface, _ := fixture(t)
+		return taskInterface
+	}))
+}
+
+// Tests that tasks survive reopening the file.
+func TestReopen(t *testing.T) {
+	ctx := context.Background()
+	taskInterface, path := fixture(t)
+
+	if _, _, err := taskInterface.Put(ctx, task.Task{ID: "keep", Title: "testTitle"}); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	expected, err := taskInterface.Get(ctx, "keep")
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if _, _, err := taskInterface.Put(ctx, task.Task{ID: "delete"}); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if err := taskInterface.Delete(ctx, "delete"); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if err := taskInterface.(io.Closer).Close(); err != nil {
+		t.Fatal("unexpected error closing: ", err)
+	}
+
+	reopened, err := NewFilestore(path)
+	if err != nil {
+		t.Fatal("failed to reopen file store: ", err)
+	}
+	defer reopened.(io.Closer).Close()
+
+	got, err := reopened.Get(ctx, "keep")
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if !got.CreatedAt.Equal(*expected.CreatedAt) || !got.UpdatedAt.Equal(*expected.UpdatedAt) {
+		t.Fatalf("expected created and updated at %s but got %s and %s", expected.CreatedAt, got.CreatedAt, got.UpdatedAt)
+	}
+	// The times are equal, but may be in different locations after being read from the file.
+	got.CreatedAt, got.UpdatedAt = expected.CreatedAt, expected.UpdatedAt
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected %v but got %v", expected, got)
+	}
+	if _, err := reopened.Get(ctx, "delete"); !errors.Is(err, task.ErrNotFound) {
+		t.Fatalf("expected %v but got %v", task.ErrNotFound, err)
+	}
+}
+
+// Tests that lists, and the tasks in them, survive reopening the file, and a deleted list's tasks stay deleted.
+func TestReopenLists(t *testing.T) {
+	ctx := context.Background()
+	taskInterface, path := fixture(t)
+
+	for _, id := range []string{"keep", "delete"} {
+		if _, _, err := taskInterface.PutList(ctx, task.TaskList{ID: id}); err != nil {
+			t.Fatal("unexpected error: ", err)
+		}
+		if _, _, err := taskInterface.Put(ctx, task.Task{ID: id + "-task", List: id}); err != nil {
+			t.Fatal("unexpected error: ", err)
+		}
+	}
+	if err := taskInterface.DeleteList(ctx, "delete"); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if err := taskInterface.(io.Closer).Close(); err != nil {
+		t.Fatal("unexpected error closing: ", err)
+	}
+
+	// Reopen twice, so that the compacted file is replayed too.
+	for i := 0; i < 2; i++ {
+		reopened, err := NewFilestore(path)
+		if err != nil {
+			t.Fatal("failed to reopen file store: ", err)
+		}
+		if lists, err := reopened.GetLists(ctx); err != nil {
+			t.Fatal("unexpected error: ", err)
+		} else if expected := []task.TaskList{{ID: "keep"}}; !reflect.DeepEqual(lists, expected) {
+			t.Fatalf("expected %v but got %v", expected, lists)
+		}
+		if got, err := reopened.Get(ctx, "keep-task"); err != nil {
+			t.Fatal("unexpected error: ", err)
+		} else if got.List != "keep" {
+			t.Fatalf("expected list %q but got %q", "keep", got.List)
+		}
+		if _, err := reopened.Get(ctx, "delete-task"); !errors.Is(err, task.ErrNotFound) {
+			t.Fatalf("expected %v but got %v", task.ErrNotFound, err)
+		}
+		reopened.(io.Closer).Close()
+	}
+}
+
+// Tests that a change torn by a crash is discarded.
+func TestTornChange(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "todo.db")
+	contents := `{"put":{"id":"1","title":"one","description":""}}` + "\n" + `{"put":{"id":"2","ti`
+	if err := ioutil.WriteFile(path, []byte(contents), 0600); err != nil {
+		t.Fatal("failed to write file: ", err)
+	}
+
+	taskInterface, err := NewFilestore(path)
+	if err != nil {
+		t.Fatal("failed to open file store: ", err)
+	}
+	defer taskInterface.(io.Closer).Close()
+
+	if got, err := taskInterface.GetAll(ctx); err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if len(got) != 1 || got[0].ID != "1" {
+		t.Fatalf("expected only task 1 but got %v", got)
+	}
+
+	// Later changes must not be corrupted by the torn change.
+	if _, _, err := taskInterface.Put(ctx, task.Task{ID: "3"}); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if err := taskInterface.(io.Closer).Close(); err != nil {
+		t.Fatal("unexpected error closing: ", err)
+	}
+	reopened, err := NewFilestore(path)
+	if err != nil {
+		t.Fatal("failed to reopen file store: ", err)
+	}
+	defer reopened.(io.Closer).Close()
+	if got, err := reopened.GetAll(ctx); err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if len(got) != 2 {
+		t.Fatalf("expected 2 tasks but got %v", got)
+	}
+}
+
+// Tests that a corrupt change in the middle of the file is an error.
+func TestCorruptChange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "todo.db")
+	contents := "not json\n" + `{"put":{"id":"1","title":"one","description":""}}` + "\n"
+	if err := ioutil.WriteFile(path, []byte(contents), 0600); err != nil {
+		t.Fatal("failed to write file: ", err)
+	}
+
+	if _, err := NewFilestore(path); err == nil {
+		t.Fatal("expected error opening corrupt file")
+	}
+}
+
+// Tests that changes are rejected after closing.
+func TestClosed(t *testing.T) {
+	taskInterface, path := fixture(t)
+	if err := taskInterface.(io.Closer).Close(); err != nil {
+		t.Fatal("unexpected error closing: ", err)
+	}
+
+	if _, _, err := taskInterface.Put(context.Background(), task.Task{ID: "1"}); err == nil {
+		t.Fatal("expected error putting to closed store")
+	}
+	if fi, err := os.Stat(path); err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if fi.Size() != 0 {
+		t.Fatalf("expected empty file but got %d bytes", fi.Size())
+	}
+}
@@ -0,0 +1,811 @@
+// Package memstore provides a task.TaskInterface backed by memory, for local runs and tests.
+package memstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rs/xid"
+
+	"github.com/jmank88/todo/task"
+)
+
+// The NewMemstore function creates a new task.TaskInterface backed by memory. It is safe for concurrent use. The store
+// is empty, unless configured differently with options.
+func NewMemstore(options ...Option) task.TaskInterface {
+	m := &memStore{tasks: make(map[string]task.Task), lists: make(map[string]task.TaskList), index: task.NewIndex(),
+		feed: task.NewFeed(task.DefaultFeedSize)}
+	for _, o := range options {
+		o(m)
+	}
+	return m
+}
+
+// An Option is a functional option for configuring a memStore.
+type Option func(*memStore)
+
+// A Change is a single write to a memStore. Exactly one field is set, except for Next, which is only set with Put.
+type Change struct {
+
+	// Put is a task to store, replacing any existing task with the same id.
+	Put *task.Task `json:"put,omitempty"`
+
+	// Next is the next occurrence of a recurring task which Put completes, stored before it.
+	Next *task.Task `json:"next,omitempty"`
+
+	// Delete is the id of a task to delete. Its subtasks are kept, as top level tasks.
+	Delete string `json:"delete,omitempty"`
+
+	// DeleteTree is the id of a task to delete, along with all of its subtasks.
+	DeleteTree string `json:"delete_tree,omitempty"`
+
+	// PutList is a list to store, replacing any existing list with the same id.
+	PutList *task.TaskList `json:"put_list,omitempty"`
+
+	// DeleteList is the id of a list to delete, along with all of its tasks. Their subtasks in other lists are kept, as
+	// top level tasks.
+	DeleteList string `json:"delete_list,omitempty"`
+}
+
+// The Restore function returns an Option which initializes a memStore by applying changes in order.
+func Restore(changes []Change) Option {
+	return func(m *memStore) {
+		for _, c := range changes {
+			m.apply(c)
+		}
+	}
+}
+
+// The Journal function returns an Option which calls journal with each change before it is applied. If journal returns
+// an error, the change is not applied, and the error is returned to the caller. Calls are serialized.
+func Journal(journal func(Change) error) Option {
+	return func(m *memStore) {
+		m.journal = journal
+	}
+}
+
+// A memStore implements task.TaskInterface with a map guarded by a lock.
+type memStore struct {
+	mu    sync.RWMutex
+	tasks map[string]task.Task
+	lists map[string]task.TaskList
+	// The index of the words of the tasks, for Search. Kept up to date by apply.
+	index   *task.Index
+	journal func(Change) error
+	// The events of the changes committed, for Watch.
+	feed *task.Feed
+}
+
+// The commit method journals and then applies c, and publishes its events. Must be called with the write lock held.
+func (m *memStore) commit(c Change) error {
+	if m.journal != nil {
+		if err := m.journal(c); err != nil {
+			return fmt.Errorf("failed to journal change: %w", err)
+		}
+	}
+	for _, e := range m.apply(c) {
+		m.feed.Publish(e.Type, e.Task)
+	}
+	return nil
+}
+
+// The apply method applies c to the tasks and lists maps, and the index, and returns the events of the tasks changed,
+// without ids.
+func (m *memStore) apply(c Change) []task.Event {
+	var events []task.Event
+	remove := func(id string) {
+		if t, ok := m.tasks[id]; ok {
+			m.remove(id)
+			events = append(events, task.Event{Type: task.EventDeleted, Task: t})
+		}
+	}
+	prune := func() {
+		for _, t := range m.prune() {
+			events = append(events, task.Event{Type: task.EventUpdated, Task: clone(t)})
+		}
+	}
+	put := func(t task.Task) {
+		typ := task.EventCreated
+		if _, ok := m.tasks[t.ID]; ok {
+			typ = task.EventUpdated
+		}
+		m.tasks[t.ID] = clone(t)
+		m.index.Add(t)
+		events = append(events, task.Event{Type: typ, Task: clone(t)})
+	}
+	switch {
+	case c.Put != nil:
+		if c.Next != nil {
+			put(*c.Next)
+		}
+		put(*c.Put)
+	case c.Delete != "":
+		remove(c.Delete)
+		prune()
+	case c.DeleteTree != "":
+		tasks := make([]task.Task, 0, len(m.tasks))
+		for _, t := range m.tasks {
+			tasks = append(tasks, t)
+		}
+		var deleteTrees func([]task.Tree)
+		deleteTrees = func(trees []task.Tree) {
+			for _, t := range trees {
+				remove(t.ID)
+				deleteTrees(t.Children)
+			}
+		}
+		deleteTrees(task.ChildTrees(tasks, c.DeleteTree))
+		remove(c.DeleteTree)
+		prune()
+	case c.PutList != nil:
+		m.lists[c.PutList.ID] = *c.PutList
+	case c.DeleteList != "":
+		delete(m.lists, c.DeleteList)
+		var ids []string
+		for id, t := range m.tasks {
+			if t.List == c.DeleteList {
+				ids = append(ids, id)
+			}
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			remove(id)
+		}
+		prune()
+	}
+	return events
+}
+
+// The remove method removes the task with the given id from the tasks map, and the index.
+func (m *memStore) remove(id string) {
+	delete(m.tasks, id)
+	m.index.Remove(id)
+}
+
+// The prune method clears the parent of every task whose parent no longer exists, and removes blockers which no longer
+// exist, incrementing the revision of each task changed. Returns the tasks changed, ordered by id.
+func (m *memStore) prune() []task.Task {
+	var pruned []task.Task
+	for id, t := range m.tasks {
+		changed := false
+		if _, ok := m.tasks[t.Parent]; t.Parent != "" && !ok {
+			t.Parent = ""
+			changed = true
+		}
+		var blockers []string
+		for _, b := range t.BlockedBy {
+			if _, ok := m.tasks[b]; ok {
+				blockers = append(blockers, b)
+			}
+		}
+		if len(blockers) != len(t.BlockedBy) {
+			t.BlockedBy = blockers
+			changed = true
+		}
+		if changed {
+			t.Revision++
+			m.tasks[id] = t
+			pruned = append(pruned, t)
+		}
+	}
+	sort.Slice(pruned, func(i, j int) bool { return pruned[i].ID < pruned[j].ID })
+	return pruned
+}
+
+// The role method returns the role of ctx on t. Must be called with the lock held. See task.TaskRole.
+func (m *memStore) role(ctx context.Context, t task.Task) task.Role {
+	if l, ok := m.lists[t.List]; ok {
+		return task.TaskRole(ctx, t, &l)
+	}
+	return task.TaskRole(ctx, t, nil)
+}
+
+// The get method returns the task with the given id, and whether it exists and ctx may read it. Must be called with
+// the lock held.
+func (m *memStore) get(ctx context.Context, id string) (task.Task, bool) {
+	t, ok := m.tasks[id]
+	return t, ok && m.role(ctx, t) != ""
+}
+
+// The authorize method returns the task with the given id, or an error wrapping task.ErrNotFound if it does not exist,
+// or ctx may not read it, or task.ErrForbidden if the role of ctx does not allow want. Must be called with the lock held.
+func (m *memStore) authorize(ctx context.Context, id string, want task.Role) (task.Task, error) {
+	t, ok := m.tasks[id]
+	if !ok {
+		return t, task.ErrNotFound
+	}
+	return t, task.Authorize(m.role(ctx, t), want)
+}
+
+// The visible method returns copies of the tasks ctx may read. Must be called with the lock held.
+func (m *memStore) visible(ctx context.Context) []task.Task {
+	tasks := make([]task.Task, 0, len(m.tasks))
+	for _, t := range m.tasks {
+		if m.role(ctx, t) != "" {
+			tasks = append(tasks, clone(t))
+		}
+	}
+	return tasks
+}
+
+// The checkBlockers method returns an error wrapping task.ErrInvalid if t is blocked by a task which does not exist, or
+// ctx may not read, or task.ErrCycle if t would depend on itself. Must be called with the lock held.
+func (m *memStore) checkBlockers(ctx context.Context, t task.Task) error {
+	for _, id := range t.BlockedBy {
+		if _, ok := m.get(ctx, id); !ok && id != t.ID {
+			return fmt.Errorf("%w: unknown blocker %q", task.ErrInvalid, id)
+		}
+	}
+	return task.CheckBlockers(t, func(id string) ([]string, bool) {
+		b, ok := m.tasks[id]
+		return b.BlockedBy, ok
+	})
+}
+
+// The checkParent method returns an error wrapping task.ErrInvalid if t has a parent which does not exist, or ctx may
+// not read, or is one of its own subtasks. Must be called with the lock held.
+func (m *memStore) checkParent(ctx context.Context, t task.Task) error {
+	if _, ok := m.get(ctx, t.Parent); !ok && t.Parent != "" && t.Parent != t.ID {
+		return fmt.Errorf("%w: unknown parent %q", task.ErrInvalid, t.Parent)
+	}
+	return task.CheckParent(t, func(id string) (string, bool) {
+		p, ok := m.tasks[id]
+		return p.Parent, ok
+	})
+}
+
+// The checkList method returns an error wrapping task.ErrInvalid if t belongs to a list other than current which does
+// not exist, or ctx may not read, or task.ErrForbidden if ctx may not edit it. Must be called with the lock held.
+func (m *memStore) checkList(ctx context.Context, t task.Task, current string) error {
+	if t.List == "" || t.List == current {
+		return nil
+	}
+	l, ok := m.lists[t.List]
+	if !ok {
+		return fmt.Errorf("%w: unknown list %q", task.ErrInvalid, t.List)
+	}
+	if err := task.Authorize(task.ListRole(ctx, l), task.RoleEditor); errors.Is(err, task.ErrNotFound) {
+		return fmt.Errorf("%w: unknown list %q", task.ErrInvalid, t.List)
+	} else if err != nil {
+		return fmt.Errorf("failed to add task %q to list %q: %w", t.ID, t.List, err)
+	}
+	return nil
+}
+
+// The Get method looks up a single task by id.
+func (m *memStore) Get(ctx context.Context, id string) (*task.Task, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := task.ValidateID(id); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	t, ok := m.get(ctx, id)
+	if !ok {
+		return nil, fmt.Errorf("failed to get task %q: %w", id, task.ErrNotFound)
+	}
+	t = clone(t)
+	return &t, nil
+}
+
+// The GetAll method lists all tasks, ordered by id.
+func (m *memStore) GetAll(ctx context.Context) ([]task.Task, error) {
+	return m.Find(ctx, task.Filter{})
+}
+
+// The Find method lists all tasks ctx may read matching filter, ordered by id.
+func (m *memStore) Find(ctx context.Context, filter task.Filter) ([]task.Task, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := time.Now()
+	var tasks []task.Task
+	for _, t := range m.tasks {
+		if m.role(ctx, t) != "" && filter.Match(t, now) {
+			tasks = append(tasks, clone(t))
+		}
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		return tasks[i].ID < tasks[j].ID
+	})
+	return tasks, nil
+}
+
+// The List method lists a single page of tasks.
+func (m *memStore) List(ctx context.Context, options task.ListOptions) (*task.Page, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return task.Paginate(m.visible(ctx), options, time.Now())
+}
+
+// The Actionable method lists all tasks matching filter which are not closed, and whose blockers are all closed, ordered
+// by id.
+func (m *memStore) Actionable(ctx context.Context, filter task.Filter) ([]task.Task, error) {
+	tasks, err := m.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return filter.Apply(task.ActionableTasks(tasks), time.Now()), nil
+}
+
+// The Ordered method lists all tasks matching filter, ordered so that every task follows the tasks blocking it.
+func (m *memStore) Ordered(ctx context.Context, filter task.Filter) ([]task.Task, error) {
+	tasks, err := m.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if tasks, err = task.OrderTasks(tasks); err != nil {
+		return nil, err
+	}
+	return filter.Apply(tasks, time.Now()), nil
+}
+
+// The Occurrences method lists the occurrences of the tasks matching filter, at or after from, and before to.
+func (m *memStore) Occurrences(ctx context.Context, from, to time.Time, filter task.Filter) ([]task.Occurrence, error) {
+	tasks, err := m.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return task.ExpandOccurrences(tasks, from, to)
+}
+
+// The Tags method lists every tag in use, with the number of tasks with it, ordered by tag.
+func (m *memStore) Tags(ctx context.Context) ([]task.TagCount, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return task.CountTags(m.visible(ctx)), nil
+}
+
+// The Search method lists the tasks matching filter whose titles or descriptions contain every word of query, ordered
+// by rank, from the index.
+func (m *memStore) Search(ctx context.Context, query string, filter task.Filter) ([]task.SearchResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	terms, err := task.SearchTerms(query)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := time.Now()
+	var results []task.SearchResult
+	for id, rank := range m.index.Search(terms) {
+		if t := m.tasks[id]; m.role(ctx, t) != "" && filter.Match(t, now) {
+			results = append(results, task.NewSearchResult(clone(t), rank, terms))
+		}
+	}
+	task.SortResults(results)
+	return results, nil
+}
+
+// The Put method stores t, replacing any existing task with the same id.
+func (m *memStore) Put(ctx context.Context, t task.Task) (string, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return "", false, err
+	}
+	if err := prepare(&t); err != nil {
+		return "", false, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	existing, exists := m.tasks[t.ID]
+	if exists {
+		if err := task.Authorize(m.role(ctx, existing), task.RoleEditor); errors.Is(err, task.ErrNotFound) {
+			return "", false, fmt.Errorf("failed to put task %q: %w", t.ID, task.ErrConflict)
+		} else if err != nil {
+			return "", false, fmt.Errorf("failed to put task %q: %w", t.ID, err)
+		}
+	}
+	if err := m.checkList(ctx, t, existing.List); err != nil {
+		return "", false, err
+	}
+	if err := m.checkParent(ctx, t); err != nil {
+		return "", false, err
+	}
+	if err := m.checkBlockers(ctx, t); err != nil {
+		return "", false, err
+	}
+	if err := task.CheckRevision(ctx, t.ID, existing.Revision); err != nil {
+		return "", false, err
+	}
+	t.Owner = task.AssignOwner(ctx, t.Owner, existing.Owner, exists)
+	t.Stamp(&existing, time.Now(), task.User(ctx))
+	if err := m.commit(Change{Put: &t}); err != nil {
+		return "", false, err
+	}
+	return t.ID, !exists, nil
+}
+
+// The Create method stores t, unless a task with the same id already exists.
+func (m *memStore) Create(ctx context.Context, t task.Task) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	if err := prepare(&t); err != nil {
+		return "", err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.tasks[t.ID]; exists {
+		return "", fmt.Errorf("failed to create task %q: %w", t.ID, task.ErrConflict)
+	}
+	if err := m.checkList(ctx, t, ""); err != nil {
+		return "", err
+	}
+	if err := m.checkParent(ctx, t); err != nil {
+		return "", err
+	}
+	if err := m.checkBlockers(ctx, t); err != nil {
+		return "", err
+	}
+	t.Owner = task.AssignOwner(ctx, t.Owner, "", false)
+	t.Stamp(nil, time.Now(), task.User(ctx))
+	if err := m.commit(Change{Put: &t}); err != nil {
+		return "", err
+	}
+	return t.ID, nil
+}
+
+// The prepare function validates a task for writing, generates an id if necessary, and normalizes its status, tags, and
+// blockers.
+func prepare(t *task.Task) error {
+	if t.ID == "" {
+		// No id, so generate a random id.
+		t.ID = xid.New().String()
+	}
+	if err := t.Validate(); err != nil {
+		return err
+	}
+	t.SetStatus(t.Status, time.Now())
+	t.Tags = task.NormalizeTags(t.Tags)
+	t.BlockedBy = task.NormalizeIDs(t.BlockedBy)
+	return nil
+}
+
+// The Update method applies a merge patch to the task with the given id.
+func (m *memStore) Update(ctx context.Context, id string, patch []byte) (*task.Task, error) {
+	return m.modify(ctx, id, func(t *task.Task) error {
+		patched, err := task.MergePatch(*t, patch, time.Now())
+		if err != nil {
+			return err
+		}
+		*t = patched
+		return nil
+	})
+}
+
+// The SetStatus method updates the status of the task with the given id.
+func (m *memStore) SetStatus(ctx context.Context, id string, status task.Status) (*task.Task, error) {
+	if !status.Valid() {
+		return nil, fmt.Errorf("%w: unknown status %q", task.ErrInvalid, status)
+	}
+	return m.modify(ctx, id, func(t *task.Task) error {
+		t.SetStatus(status, time.Now())
+		return nil
+	})
+}
+
+// The modify method applies fn to a copy of the task with the given id, and stamps and stores the result if fn
+// succeeds. If the task was just done, its next occurrence is stored first, in the same Change.
+func (m *memStore) modify(ctx context.Context, id string, fn func(*task.Task) error) (*task.Task, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := task.ValidateID(id); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	old, err := m.authorize(ctx, id, task.RoleEditor)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update task %q: %w", id, err)
+	}
+	if err := task.CheckRevision(ctx, id, old.Revision); err != nil {
+		return nil, err
+	}
+	t := clone(old)
+	if err := fn(&t); err != nil {
+		return nil, err
+	}
+	if err := m.checkList(ctx, t, old.List); err != nil {
+		return nil, err
+	}
+	if err := m.checkParent(ctx, t); err != nil {
+		return nil, err
+	}
+	if err := m.checkBlockers(ctx, t); err != nil {
+		return nil, err
+	}
+	next, err := task.NextOccurrence(old, t)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if next != nil {
+		if err := prepare(next); err != nil {
+			return nil, err
+		}
+		next.Stamp(nil, now, task.User(ctx))
+		t.Next = next.ID
+	}
+	t.Stamp(&old, now, task.User(ctx))
+	if err := m.commit(Change{Put: &t, Next: next}); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// The Children method returns the trees of the subtasks of the task with the given id.
+func (m *memStore) Children(ctx context.Context, id string) ([]task.Tree, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := task.ValidateID(id); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if _, ok := m.get(ctx, id); !ok {
+		return nil, fmt.Errorf("failed to get task %q: %w", id, task.ErrNotFound)
+	}
+	return task.ChildTrees(m.visible(ctx), id), nil
+}
+
+// The DeleteTree method deletes the task with the given id, and all of its subtasks.
+func (m *memStore) DeleteTree(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := task.ValidateID(id); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t, err := m.authorize(ctx, id, task.RoleEditor)
+	if err != nil {
+		return fmt.Errorf("failed to delete task %q: %w", id, err)
+	}
+	if err := task.CheckRevision(ctx, id, t.Revision); err != nil {
+		return err
+	}
+	if err := m.checkSubtasks(ctx, id); err != nil {
+		return err
+	}
+	return m.commit(Change{DeleteTree: id})
+}
+
+// The checkSubtasks method returns an error wrapping task.ErrForbidden if ctx may not edit one of the subtasks of the
+// task with the given id, recursively, even if it may not read it. Must be called with the lock held.
+func (m *memStore) checkSubtasks(ctx context.Context, id string) error {
+	for _, t := range m.tasks {
+		if t.Parent != id {
+			continue
+		}
+		if !m.role(ctx, t).Allows(task.RoleEditor) {
+			return fmt.Errorf("failed to delete task %q: %w: may not delete all of its subtasks", id, task.ErrForbidden)
+		}
+		if err := m.checkSubtasks(ctx, t.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// The Delete method deletes the task with the given id. Its subtasks are kept, as top level tasks.
+func (m *memStore) Delete(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := task.ValidateID(id); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t, err := m.authorize(ctx, id, task.RoleEditor)
+	if err != nil {
+		return fmt.Errorf("failed to delete task %q: %w", id, err)
+	}
+	if err := task.CheckRevision(ctx, id, t.Revision); err != nil {
+		return err
+	}
+	return m.commit(Change{Delete: id})
+}
+
+// The GetList method looks up a single list by id.
+func (m *memStore) GetList(ctx context.Context, id string) (*task.TaskList, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := task.ValidateID(id); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	l, ok := m.lists[id]
+	if !ok || task.ListRole(ctx, l) == "" {
+		return nil, fmt.Errorf("failed to get list %q: %w", id, task.ErrNotFound)
+	}
+	l = cloneList(l)
+	return &l, nil
+}
+
+// The GetLists method lists all lists ctx may read, ordered by id.
+func (m *memStore) GetLists(ctx context.Context) ([]task.TaskList, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	lists := make([]task.TaskList, 0, len(m.lists))
+	for _, l := range m.lists {
+		if task.ListRole(ctx, l) != "" {
+			lists = append(lists, cloneList(l))
+		}
+	}
+	sort.Slice(lists, func(i, j int) bool {
+		return lists[i].ID < lists[j].ID
+	})
+	return lists, nil
+}
+
+// The PutList method stores l, replacing any existing list with the same id.
+func (m *memStore) PutList(ctx context.Context, l task.TaskList) (string, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return "", false, err
+	}
+	if l.ID == "" {
+		// No id, so generate a random id.
+		l.ID = xid.New().String()
+	}
+	if err := l.Validate(); err != nil {
+		return "", false, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	existing, exists := m.lists[l.ID]
+	if exists {
+		if err := task.Authorize(task.ListRole(ctx, existing), task.RoleEditor); errors.Is(err, task.ErrNotFound) {
+			return "", false, fmt.Errorf("failed to put list %q: %w", l.ID, task.ErrConflict)
+		} else if err != nil {
+			return "", false, fmt.Errorf("failed to put list %q: %w", l.ID, err)
+		}
+	}
+	l.Owner = task.AssignOwner(ctx, l.Owner, existing.Owner, exists)
+	l.Grants = existing.Grants
+	if err := m.commit(Change{PutList: &l}); err != nil {
+		return "", false, err
+	}
+	return l.ID, !exists, nil
+}
+
+// The DeleteList method deletes the list with the given id, and all of its tasks.
+func (m *memStore) DeleteList(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := task.ValidateID(id); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	l, ok := m.lists[id]
+	if !ok {
+		return fmt.Errorf("failed to delete list %q: %w", id, task.ErrNotFound)
+	}
+	if err := task.Authorize(task.ListRole(ctx, l), task.RoleOwner); err != nil {
+		return fmt.Errorf("failed to delete list %q: %w", id, err)
+	}
+	return m.commit(Change{DeleteList: id})
+}
+
+// The Share method adds grant to the list with the given id.
+func (m *memStore) Share(ctx context.Context, list string, grant task.Grant) (*task.TaskList, error) {
+	if err := grant.Validate(); err != nil {
+		return nil, err
+	}
+	return m.share(ctx, list, func(grants []task.Grant) []task.Grant {
+		return task.Share(grants, grant)
+	})
+}
+
+// The Unshare method removes the grant to grantee from the list with the given id.
+func (m *memStore) Unshare(ctx context.Context, list string, grantee task.Grantee) (*task.TaskList, error) {
+	if err := grantee.Validate(); err != nil {
+		return nil, err
+	}
+	return m.share(ctx, list, func(grants []task.Grant) []task.Grant {
+		return task.Unshare(grants, grantee)
+	})
+}
+
+// The share method replaces the grants of the list with the given id with the result of fn, if ctx owns it.
+func (m *memStore) share(ctx context.Context, id string, fn func([]task.Grant) []task.Grant) (*task.TaskList, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := task.ValidateID(id); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	l, ok := m.lists[id]
+	if !ok {
+		return nil, fmt.Errorf("failed to update grants of list %q: %w", id, task.ErrNotFound)
+	}
+	if err := task.Authorize(task.ListRole(ctx, l), task.RoleOwner); err != nil {
+		return nil, fmt.Errorf("failed to update grants of list %q: %w", id, err)
+	}
+	l.Grants = fn(l.Grants)
+	if err := m.commit(Change{PutList: &l}); err != nil {
+		return nil, err
+	}
+	l = cloneList(l)
+	return &l, nil
+}
+
+// The clone function returns a deep copy of t, so that stored tasks never share memory with callers.
+func clone(t task.Task) task.Task {
+	t.Completed = cloneTime(t.Completed)
+	t.Start = cloneTime(t.Start)
+	t.Due = cloneTime(t.Due)
+	t.CreatedAt = cloneTime(t.CreatedAt)
+	t.UpdatedAt = cloneTime(t.UpdatedAt)
+	t.Tags = append([]string(nil), t.Tags...)
+	t.BlockedBy = append([]string(nil), t.BlockedBy...)
+	return t
+}
+
+// The cloneList function returns a deep copy of l.
+func cloneList(l task.TaskList) task.TaskList {
+	l.Grants = append([]task.Grant(nil), l.Grants...)
+	return l
+}
+
+// The cloneTime function returns a copy of t, or nil.
+func cloneTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	c := *t
+	return &c
+}
+
+// The Watch method watches the changes committed to m, which are visible to ctx when they are delivered.
+func (m *memStore) Watch(ctx context.Context, after int64) (<-chan task.Event, error) {
+	return m.feed.Watch(ctx, after, func(e task.Event) bool {
+		m.mu.RLock()
+		defer m.mu.RUnlock()
+		return m.role(ctx, e.Task) != ""
+	})
+}
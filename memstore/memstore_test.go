@@ -0,0 +1,255 @@
+package memstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jmank88/todo/task"
+	"github.com/jmank88/todo/tasktest"
+)
+
+// Runs the conformance suite.
+func TestConformance(t *testing.T) {
+	tasktest.RunConformance(t, func(t *testing.T) task.TaskInterface {
+		return NewMemstore()
+	})
+}
+
+// Runs the isolation suite.
+func TestIsolation(t *testing.T) {
+	tasktest.RunIsolation(t, tasktest.ContextUsers(func(t *testing.T) task.TaskInterface {
+		return NewMemstore()
+	}))
+}
+
+// Tests getting a task after putting it.
+func TestPutGet(t *testing.T) {
+	ctx := context.Background()
+	taskInterface := NewMemstore()
+
+	expected := task.Task{
+		ID:          "testId",
+		Title:       "testTitle",
+		Description: "testDescription",
+		Status:      task.StatusOpen,
+	}
+	if id, created, err := taskInterface.Put(ctx, expected); err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if id != expected.ID {
+		t.Fatalf("expected %q but got %q", expected.ID, id)
+	} else if !created {
+		t.Fatal("expected task to be created")
+	}
+
+	got, err := taskInterface.Get(ctx, expected.ID)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if got.CreatedAt == nil || got.UpdatedAt == nil {
+		t.Fatalf("expected created and updated times but got %v", got)
+	}
+	expected.CreatedAt, expected.UpdatedAt, expected.Revision = got.CreatedAt, got.UpdatedAt, 1
+	if !reflect.DeepEqual(*got, expected) {
+		t.Fatalf("expected %v but got %v", expected, got)
+	}
+
+	if _, created, err := taskInterface.Put(ctx, expected); err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if created {
+		t.Fatal("expected task to be replaced")
+	}
+}
+
+// Tests that stored tasks are not modified through returned tasks.
+func TestGetCopy(t *testing.T) {
+	ctx := context.Background()
+	taskInterface := NewMemstore()
+
+	due := time.Date(2016, 1, 2, 3, 4, 5, 0, time.UTC)
+	if _, _, err := taskInterface.Put(ctx, task.Task{ID: "testId", Due: &due}); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	due = due.AddDate(1, 0, 0)
+
+	got, err := taskInterface.Get(ctx, "testId")
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	*got.Due = got.Due.AddDate(1, 0, 0)
+
+	if got, err := taskInterface.Get(ctx, "testId"); err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if expected := time.Date(2016, 1, 2, 3, 4, 5, 0, time.UTC); !got.Due.Equal(expected) {
+		t.Fatalf("expected due %s but got %s", expected, got.Due)
+	}
+}
+
+// Tests the errors returned for missing and conflicting tasks.
+func TestErrors(t *testing.T) {
+	ctx := context.Background()
+	taskInterface := NewMemstore()
+
+	if _, err := taskInterface.Get(ctx, "testId"); !errors.Is(err, task.ErrNotFound) {
+		t.Fatalf("expected %v but got %v", task.ErrNotFound, err)
+	}
+	if _, err := taskInterface.Update(ctx, "testId", []byte(`{}`)); !errors.Is(err, task.ErrNotFound) {
+		t.Fatalf("expected %v but got %v", task.ErrNotFound, err)
+	}
+	if err := taskInterface.Delete(ctx, "testId"); !errors.Is(err, task.ErrNotFound) {
+		t.Fatalf("expected %v but got %v", task.ErrNotFound, err)
+	}
+
+	if _, err := taskInterface.Create(ctx, task.Task{ID: "testId"}); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if _, err := taskInterface.Create(ctx, task.Task{ID: "testId"}); !errors.Is(err, task.ErrConflict) {
+		t.Fatalf("expected %v but got %v", task.ErrConflict, err)
+	}
+	if _, err := taskInterface.SetStatus(ctx, "testId", "finished"); !errors.Is(err, task.ErrInvalid) {
+		t.Fatalf("expected %v but got %v", task.ErrInvalid, err)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := taskInterface.Get(cancelled, "testId"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected %v but got %v", context.Canceled, err)
+	}
+}
+
+// Tests updating and completing a task.
+func TestUpdate(t *testing.T) {
+	ctx := context.Background()
+	taskInterface := NewMemstore()
+
+	if _, err := taskInterface.Create(ctx, task.Task{ID: "testId", Title: "testTitle"}); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if got, err := taskInterface.Update(ctx, "testId", []byte(`{"title":"newTitle"}`)); err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if got.Title != "newTitle" {
+		t.Fatalf("expected title %q but got %q", "newTitle", got.Title)
+	}
+	if got, err := taskInterface.SetStatus(ctx, "testId", task.StatusDone); err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if got.Title != "newTitle" || got.Status != task.StatusDone || got.Completed == nil {
+		t.Fatalf("expected completed task but got %v", got)
+	}
+}
+
+// Tests that completing a recurring task journals its next occurrence in the same change, so a failed journal stores
+// neither.
+func TestRecurrenceJournal(t *testing.T) {
+	ctx := context.Background()
+	var changes []Change
+	fail := false
+	taskInterface := NewMemstore(Journal(func(c Change) error {
+		if fail {
+			return errors.New("test failure")
+		}
+		changes = append(changes, c)
+		return nil
+	}))
+
+	due := time.Date(2016, 1, 2, 3, 4, 5, 0, time.UTC)
+	if _, _, err := taskInterface.Put(ctx, task.Task{ID: "testId", Due: &due, Recurrence: "FREQ=DAILY"}); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+
+	fail = true
+	if _, err := taskInterface.SetStatus(ctx, "testId", task.StatusDone); err == nil {
+		t.Fatal("expected journal error")
+	}
+	if got, err := taskInterface.GetAll(ctx); err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if len(got) != 1 || got[0].Status == task.StatusDone {
+		t.Fatalf("expected only the open task but got %v", got)
+	}
+
+	fail = false
+	got, err := taskInterface.SetStatus(ctx, "testId", task.StatusDone)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if len(changes) != 2 {
+		t.Fatalf("expected 2 changes but got %d", len(changes))
+	} else if c := changes[1]; c.Put == nil || c.Put.ID != "testId" || c.Next == nil || c.Next.ID != got.Next {
+		t.Fatalf("expected the done task and its next occurrence %q in one change but got %+v", got.Next, c)
+	}
+}
+
+// Tests that tasks record the user of the context which created them, and not of later writes by admins.
+func TestCreatedBy(t *testing.T) {
+	taskInterface := NewMemstore()
+	bob := task.AsAdmin(task.WithUser(context.Background(), "bob"))
+
+	if _, err := taskInterface.Create(task.WithUser(context.Background(), "alice"), task.Task{ID: "testId"}); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if _, _, err := taskInterface.Put(bob, task.Task{ID: "testId", CreatedBy: "bob"}); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if got, err := taskInterface.SetStatus(bob, "testId", task.StatusDone); err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if got.CreatedBy != "alice" {
+		t.Fatalf("expected created by %q but got %q", "alice", got.CreatedBy)
+	}
+}
+
+// Tests finding tasks with a filter.
+func TestFind(t *testing.T) {
+	ctx := context.Background()
+	taskInterface := NewMemstore()
+
+	yesterday := time.Now().AddDate(0, 0, -1)
+	for _, task := range []task.Task{
+		{ID: "overdue", Due: &yesterday},
+		{ID: "done", Due: &yesterday, Status: task.StatusDone},
+		{ID: "undated"},
+	} {
+		if _, _, err := taskInterface.Put(ctx, task); err != nil {
+			t.Fatal("unexpected error: ", err)
+		}
+	}
+
+	if got, err := taskInterface.GetAll(ctx); err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if len(got) != 3 || got[0].ID != "done" || got[1].ID != "overdue" || got[2].ID != "undated" {
+		t.Fatalf("expected all tasks ordered by id but got %v", got)
+	}
+
+	if got, err := taskInterface.Find(ctx, task.Filter{Overdue: true}); err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if len(got) != 1 || got[0].ID != "overdue" {
+		t.Fatalf("expected overdue task but got %v", got)
+	}
+}
+
+// Tests concurrent writers.
+func TestConcurrentPut(t *testing.T) {
+	ctx := context.Background()
+	taskInterface := NewMemstore()
+
+	const n = 100
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if _, _, err := taskInterface.Put(ctx, task.Task{ID: fmt.Sprint(i)}); err != nil {
+				t.Error("unexpected error: ", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if got, err := taskInterface.GetAll(ctx); err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if len(got) != n {
+		t.Fatalf("expected %d tasks but got %d", n, len(got))
+	}
+}
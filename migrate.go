@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/jmank88/todo/datastore"
+)
+
+const migrateUsage = `usage: todo [-host dsn] migrate status|up [version]|down [version]
+
+status prints the current and available schema versions.
+up applies migrations up to version, or the latest version.
+down reverts migrations down to version, or the previous version.`
+
+// The migrate function runs the migrate subcommand with args against the postgres database dsn.
+func migrate(ctx context.Context, dsn string, args []string) error {
+	if len(args) == 0 || len(args) > 2 {
+		return fmt.Errorf("%s", migrateUsage)
+	}
+	if u, err := url.Parse(dsn); err != nil {
+		return fmt.Errorf("invalid dsn: %s", err)
+	} else if u.Scheme != "postgres" && u.Scheme != "postgresql" {
+		return fmt.Errorf("migrations only apply to postgres stores, not %q", u.Scheme)
+	}
+
+	m, err := datastore.NewMigrator(dsn)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	current, err := m.Version(ctx)
+	if err != nil {
+		return err
+	}
+
+	command := args[0]
+	target := -1
+	if len(args) == 2 {
+		if command == "status" {
+			return fmt.Errorf("%s", migrateUsage)
+		}
+		if target, err = strconv.Atoi(args[1]); err != nil {
+			return fmt.Errorf("invalid version %q: %s", args[1], err)
+		}
+	}
+
+	switch command {
+	case "status":
+		for _, mig := range m.Migrations() {
+			state := "pending"
+			if mig.Version <= current {
+				state = "applied"
+			}
+			fmt.Printf("%04d_%s\t%s\n", mig.Version, mig.Name, state)
+		}
+		fmt.Printf("version %d of %d\n", current, m.Latest())
+		return nil
+	case "up":
+		if target < 0 {
+			target = m.Latest()
+		}
+		if err := m.Up(ctx, target); err != nil {
+			return err
+		}
+	case "down":
+		if target < 0 {
+			if current == 0 {
+				return fmt.Errorf("no migrations to revert")
+			}
+			target = current - 1
+		}
+		if err := m.Down(ctx, target); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unrecognized migrate command %q\n%s", command, migrateUsage)
+	}
+
+	version, err := m.Version(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("migrated from version %d to %d\n", current, version)
+	return nil
+}
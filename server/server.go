@@ -2,89 +2,878 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"mime"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/jmank88/todo/task"
 )
 
-// The NewServer function returns a new server as an http.Handler which routes requests to taskInterface.
-func NewServer(taskInterface task.TaskInterface) http.Handler {
-	return &server{taskInterface}
+// The NewServer function returns a new server as an http.Handler which routes requests to taskInterface, configured by
+// options.
+func NewServer(taskInterface task.TaskInterface, options ...Option) http.Handler {
+	s := &server{TaskInterface: taskInterface}
+	for _, o := range options {
+		o(s)
+	}
+	return s
+}
+
+// An Option is a functional option for configuring a server.
+type Option func(*server)
+
+// The RequireIfMatch function returns an Option which requires writes to existing tasks to be conditional, so that
+// concurrent edits cannot overwrite each other. Requests to patch, delete, or set the status of a task without an
+// If-Match header are rejected with 428 Precondition Required, as are requests to put a task without either an If-Match
+// header, or an "If-None-Match: *" header to only create it.
+func RequireIfMatch() Option {
+	return func(s *server) {
+		s.requireIfMatch = true
+	}
 }
 
 // Server implements http.Handler, and routes requests to a task.TaskInterface.
 type server struct {
 	task.TaskInterface
+
+	// Whether writes must have an If-Match header.
+	requireIfMatch bool
 }
 
-// Routes requests based on Method.
+// Routes requests based on path and Method. Requests under /lists/ are routed by serveLists, /events is streamed by
+// watch, /_sync is upgraded to a WebSocket connection by sync, and all others are routed by serveTasks.
 func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	id := r.URL.Path[1:]
-	if strings.Contains(id, "/") {
-		http.NotFound(w, r)
+	path := r.URL.Path[1:]
+	if path == "lists" || strings.HasPrefix(path, "lists/") {
+		s.serveLists(strings.TrimPrefix(strings.TrimPrefix(path, "lists"), "/"), w, r)
+		return
+	}
+	if path == "events" {
+		if r.Method != "GET" {
+			http.Error(w, fmt.Sprintf("method %s not supported", r.Method), http.StatusMethodNotAllowed)
+			return
+		}
+		s.watch(w, r)
+		return
+	}
+	if path == "_sync" {
+		if r.Method != "GET" {
+			http.Error(w, fmt.Sprintf("method %s not supported", r.Method), http.StatusMethodNotAllowed)
+			return
+		}
+		s.sync(w, r)
+		return
+	}
+	if path == "_tags" {
+		if r.Method != "GET" {
+			http.Error(w, fmt.Sprintf("method %s not supported", r.Method), http.StatusMethodNotAllowed)
+			return
+		}
+		s.getTags(w, r)
+		return
+	}
+	s.serveTasks("", path, w, r)
+}
+
+// Routes requests for tasks based on Method. The path is relative to the tasks collection. If list is not empty, only
+// tasks in that list are addressed. The _actionable, _order, _occurrences, and _search views are not valid task ids, so
+// cannot collide with tasks.
+func (s *server) serveTasks(list, path string, w http.ResponseWriter, r *http.Request) {
+	id := path
+	if id == "_actionable" || id == "_order" || id == "_occurrences" || id == "_search" {
+		if r.Method != "GET" {
+			http.Error(w, fmt.Sprintf("method %s not supported", r.Method), http.StatusMethodNotAllowed)
+			return
+		}
+		switch id {
+		case "_occurrences":
+			s.getOccurrences(list, w, r)
+		case "_search":
+			s.search(list, w, r)
+		default:
+			s.getView(list, id, w, r)
+		}
+		return
+	}
+	if i := strings.Index(id, "/"); i >= 0 {
+		var method string
+		var handle func(string, string, http.ResponseWriter, *http.Request)
+		switch id[i+1:] {
+		case "status":
+			method, handle = "PUT", s.setStatus
+		case "children":
+			method, handle = "GET", s.getChildren
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method != method {
+			http.Error(w, fmt.Sprintf("method %s not supported", r.Method), http.StatusMethodNotAllowed)
+			return
+		}
+		handle(list, id[:i], w, r)
 		return
 	}
 	switch r.Method {
 	case "GET":
 		if id == "" {
-			s.getAll(w, r)
+			s.getAll(list, w, r)
 		} else {
-			s.get(id, w, r)
+			s.get(list, id, w, r)
 		}
 	case "PUT":
-		s.put(w, r)
+		s.put(list, id, w, r)
+	case "POST":
+		if id == "" {
+			s.create(list, w, r)
+		} else {
+			http.Error(w, fmt.Sprintf("method %s not supported", r.Method), http.StatusMethodNotAllowed)
+		}
+	case "PATCH":
+		if id == "" {
+			http.NotFound(w, r)
+		} else {
+			s.patch(list, id, w, r)
+		}
 	case "DELETE":
 		if id == "" {
 			http.NotFound(w, r)
 		} else {
-			s.delete(id, w, r)
+			s.delete(list, id, w, r)
 		}
 	default:
 		http.Error(w, fmt.Sprintf("method %s not supported", r.Method), http.StatusMethodNotAllowed)
 	}
 }
 
-// Gets all tasks.
-func (s *server) getAll(w http.ResponseWriter, r *http.Request) {
-	if tasks, err := s.GetAll(); err != nil {
-		http.Error(w, fmt.Sprintf("failed to get all tasks: %s", err), http.StatusInternalServerError)
-	} else if err := json.NewEncoder(w).Encode(tasks); err != nil {
+// Routes requests for lists based on Method. The path is relative to the lists collection. The grants of a list are
+// routed by serveGrants, and its tasks by serveTasks, after checking that the list exists.
+func (s *server) serveLists(path string, w http.ResponseWriter, r *http.Request) {
+	id, rest := path, ""
+	if i := strings.Index(path, "/"); i >= 0 {
+		id, rest = path[:i], path[i+1:]
+		if rest == "grants" {
+			s.serveGrants(id, w, r)
+			return
+		}
+		if rest != "tasks" && !strings.HasPrefix(rest, "tasks/") {
+			http.NotFound(w, r)
+			return
+		}
+		if _, err := s.GetList(r.Context(), id); err != nil {
+			http.Error(w, fmt.Sprintf("failed to get list %s: %s", id, err), errorStatus(err))
+			return
+		}
+		s.serveTasks(id, strings.TrimPrefix(strings.TrimPrefix(rest, "tasks"), "/"), w, r)
+		return
+	}
+	switch r.Method {
+	case "GET":
+		if id == "" {
+			s.getLists(w, r)
+		} else {
+			s.getList(id, w, r)
+		}
+	case "PUT":
+		s.putList(id, w, r)
+	case "DELETE":
+		if id == "" {
+			http.NotFound(w, r)
+		} else {
+			s.deleteList(id, w, r)
+		}
+	default:
+		http.Error(w, fmt.Sprintf("method %s not supported", r.Method), http.StatusMethodNotAllowed)
+	}
+}
+
+// The taskPath function returns the path of the task with the given id, within list if it is not empty.
+func taskPath(list, id string) string {
+	if list == "" {
+		return "/" + id
+	}
+	return "/lists/" + list + "/tasks/" + id
+}
+
+// The inList method returns an error wrapping task.ErrNotFound if list is not empty, and the task with the given id does
+// not belong to it.
+func (s *server) inList(ctx context.Context, list, id string) error {
+	if list == "" {
+		return nil
+	}
+	t, err := s.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	return checkList(list, t)
+}
+
+// The checkList function returns an error wrapping task.ErrNotFound if list is not empty, and t does not belong to it.
+func checkList(list string, t *task.Task) error {
+	if list != "" && t.List != list {
+		return fmt.Errorf("task %q is not in list %q: %w", t.ID, list, task.ErrNotFound)
+	}
+	return nil
+}
+
+// Lists a page of tasks, optionally filtered, sorted, and continued from a cursor by query parameters. If there are more
+// tasks, the url of the next page is in a Link header.
+func (s *server) getAll(list string, w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	options, err := parseListOptions(query, time.Now())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid query: %s", err), http.StatusBadRequest)
+		return
+	}
+	if err := scopeFilter(list, &options.Filter); err != nil {
+		http.Error(w, fmt.Sprintf("invalid query: %s", err), http.StatusBadRequest)
+		return
+	}
+	page, err := s.List(r.Context(), options)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to list tasks: %s", err), errorStatus(err))
+		return
+	}
+	if page.Next != "" {
+		query.Set("cursor", page.Next)
+		w.Header().Set("Link", fmt.Sprintf(`<%s?%s>; rel="next"`, r.URL.Path, query.Encode()))
+	}
+	tasks := page.Tasks
+	if tasks == nil {
+		tasks = []task.Task{}
+	}
+	if err := json.NewEncoder(w).Encode(tasks); err != nil {
+		http.Error(w, fmt.Sprintf("failed to serialize tasks: %v", tasks), http.StatusInternalServerError)
+	}
+}
+
+// Lists the tasks matching the filter query parameters which are actionable, for the _actionable view, or all of them
+// in dependency order, for the _order view.
+func (s *server) getView(list, view string, w http.ResponseWriter, r *http.Request) {
+	filter, err := parseFilter(r.URL.Query(), time.Now())
+	if err == nil {
+		err = scopeFilter(list, &filter)
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid query: %s", err), http.StatusBadRequest)
+		return
+	}
+	get, name := s.Actionable, "actionable tasks"
+	if view == "_order" {
+		get, name = s.Ordered, "ordered tasks"
+	}
+	tasks, err := get(r.Context(), filter)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to get %s: %s", name, err), errorStatus(err))
+		return
+	}
+	if tasks == nil {
+		tasks = []task.Task{}
+	}
+	if err := json.NewEncoder(w).Encode(tasks); err != nil {
 		http.Error(w, fmt.Sprintf("failed to serialize tasks: %v", tasks), http.StatusInternalServerError)
 	}
 }
 
-// Gets a single task.
-func (s *server) get(id string, w http.ResponseWriter, r *http.Request) {
-	if task, err := s.Get(id); err != nil {
-		http.Error(w, fmt.Sprintf("failed to get task %s: %s", id, err), http.StatusInternalServerError)
-	} else if task == nil {
-		http.Error(w, fmt.Sprintf("no task found for id %q", id), http.StatusFound)
+// The defaultOccurrenceRange is the range of occurrences gotten when the to query parameter is omitted.
+const defaultOccurrenceRange = 7 * 24 * time.Hour
+
+// Lists the occurrences of the tasks matching the filter query parameters, from the from query parameter, or now, until
+// the to query parameter, or a week later.
+func (s *server) getOccurrences(list string, w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter, err := parseFilter(query, time.Now())
+	if err == nil {
+		err = scopeFilter(list, &filter)
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid query: %s", err), http.StatusBadRequest)
+		return
+	}
+	from, to := time.Now(), time.Time{}
+	for _, p := range []struct {
+		name string
+		dst  *time.Time
+	}{
+		{"from", &from},
+		{"to", &to},
+	} {
+		if v := query.Get(p.name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("invalid query: invalid %s time %q: %s", p.name, v, err), http.StatusBadRequest)
+				return
+			}
+			*p.dst = t
+		}
+	}
+	if to.IsZero() {
+		to = from.Add(defaultOccurrenceRange)
+	}
+	occurrences, err := s.Occurrences(r.Context(), from, to, filter)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to get occurrences: %s", err), errorStatus(err))
+		return
+	}
+	if occurrences == nil {
+		occurrences = []task.Occurrence{}
+	}
+	if err := json.NewEncoder(w).Encode(occurrences); err != nil {
+		http.Error(w, fmt.Sprintf("failed to serialize occurrences: %v", occurrences), http.StatusInternalServerError)
+	}
+}
+
+// Lists the tasks matching the filter query parameters whose titles or descriptions contain every word of the q query
+// parameter, ordered by rank, with highlighted snippets.
+func (s *server) search(list string, w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter, err := parseFilter(query, time.Now())
+	if err == nil {
+		err = scopeFilter(list, &filter)
+	}
+	if err == nil && query.Get("q") == "" {
+		err = errors.New("missing search query q")
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid query: %s", err), http.StatusBadRequest)
+		return
+	}
+	results, err := s.Search(r.Context(), query.Get("q"), filter)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to search tasks: %s", err), errorStatus(err))
+		return
+	}
+	if results == nil {
+		results = []task.SearchResult{}
+	}
+	if err := json.NewEncoder(w).Encode(results); err != nil {
+		http.Error(w, fmt.Sprintf("failed to serialize search results: %v", results), http.StatusInternalServerError)
+	}
+}
+
+// The scopeFilter function restricts filter to list, if it is not empty. Returns an error if filter is already
+// restricted to a different list.
+func scopeFilter(list string, filter *task.Filter) error {
+	if list == "" {
+		return nil
+	}
+	if filter.List != "" && filter.List != list {
+		return fmt.Errorf("list %q does not match path list %q", filter.List, list)
+	}
+	filter.List = list
+	return nil
+}
+
+// The parseListOptions function parses task.ListOptions from query parameters. The filter is parsed by parseFilter.
+func parseListOptions(query url.Values, now time.Time) (task.ListOptions, error) {
+	var options task.ListOptions
+	var err error
+	if options.Filter, err = parseFilter(query, now); err != nil {
+		return options, err
+	}
+	if limit := query.Get("limit"); limit != "" {
+		if options.Limit, err = strconv.Atoi(limit); err != nil || options.Limit < 1 {
+			return options, fmt.Errorf("invalid limit %q. must be a positive integer", limit)
+		}
+	}
+	options.Cursor = query.Get("cursor")
+	options.Sort = task.Sort(query.Get("sort"))
+	return options, nil
+}
+
+// The parseFilter function parses a task.Filter from query parameters. The special value due=today is resolved relative
+// to now.
+func parseFilter(query url.Values, now time.Time) (task.Filter, error) {
+	var filter task.Filter
+	switch due := query.Get("due"); due {
+	case "":
+	case "today":
+		filter = task.DueOn(now)
+	default:
+		return filter, fmt.Errorf("unsupported due value %q", due)
+	}
+	if overdue := query.Get("overdue"); overdue != "" {
+		if overdue != "true" && overdue != "false" {
+			return filter, fmt.Errorf("invalid overdue value %q", overdue)
+		}
+		filter.Overdue = overdue == "true"
+	}
+	for _, status := range query["status"] {
+		s := task.Status(status)
+		if s == "" || !s.Valid() {
+			return filter, fmt.Errorf("invalid status %q", status)
+		}
+		filter.Statuses = append(filter.Statuses, s)
+	}
+	for _, priority := range query["priority"] {
+		p := task.Priority(priority)
+		if priority == "none" {
+			p = ""
+		} else if p == "" || !p.Valid() {
+			return filter, fmt.Errorf("invalid priority %q. must be one of P0 to P4, or none", priority)
+		}
+		filter.Priorities = append(filter.Priorities, p)
+	}
+	filter.Tags = query["tag"]
+	filter.List = query.Get("list")
+	filter.Parent = query.Get("parent")
+	switch match := query.Get("tag_match"); match {
+	case "", "any":
+	case "all":
+		filter.AllTags = true
+	default:
+		return filter, fmt.Errorf("invalid tag_match value %q. must be 'any' or 'all'", match)
+	}
+	for _, p := range []struct {
+		name string
+		dst  **time.Time
+	}{
+		{"due_before", &filter.DueBefore},
+		{"due_after", &filter.DueAfter},
+		{"updated_since", &filter.UpdatedSince},
+	} {
+		if v := query.Get(p.name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return filter, fmt.Errorf("invalid %s time %q: %s", p.name, v, err)
+			}
+			*p.dst = &t
+		}
+	}
+	return filter, nil
+}
+
+// Gets every tag in use, with the number of tasks with it.
+func (s *server) getTags(w http.ResponseWriter, r *http.Request) {
+	if tags, err := s.Tags(r.Context()); err != nil {
+		http.Error(w, fmt.Sprintf("failed to get tags: %s", err), errorStatus(err))
+	} else if err := json.NewEncoder(w).Encode(tags); err != nil {
+		http.Error(w, fmt.Sprintf("failed to serialize tags: %v", tags), http.StatusInternalServerError)
+	}
+}
+
+// Gets a single task, with its revision as an ETag. Responds 304 Not Modified if the If-None-Match header has the ETag.
+func (s *server) get(list, id string, w http.ResponseWriter, r *http.Request) {
+	task, err := s.Get(r.Context(), id)
+	if err == nil {
+		err = checkList(list, task)
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to get task %s: %s", id, err), errorStatus(err))
+		return
+	}
+	w.Header().Set("ETag", etag(task.Revision))
+	if noneMatch(r.Header.Get("If-None-Match"), task.Revision) {
+		w.WriteHeader(http.StatusNotModified)
 	} else if err := json.NewEncoder(w).Encode(task); err != nil {
 		http.Error(w, fmt.Sprintf("failed to serialize task: %v", task), http.StatusInternalServerError)
 	}
 }
 
-// Puts a task.
-func (s *server) put(w http.ResponseWriter, r *http.Request) {
+// Puts a task, creating or replacing it. The id is taken from the path if not empty, otherwise from the task, and
+// likewise the list. Only replaces a task at the revision of the If-Match header, if any, and only creates a task if
+// the If-None-Match header is "*". A task in another list is not found under the path of the list.
+func (s *server) put(list, id string, w http.ResponseWriter, r *http.Request) {
+	createOnly := r.Header.Get("If-None-Match") == "*"
+	ctx, ok := s.conditional(w, r, createOnly)
+	if !ok {
+		return
+	}
+	if !createOnly && list != "" && id != "" {
+		if existing, err := s.Get(ctx, id); err == nil {
+			err = checkList(list, existing)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("failed to store task: %v", err), errorStatus(err))
+				return
+			}
+		} else if !errors.Is(err, task.ErrNotFound) {
+			http.Error(w, fmt.Sprintf("failed to store task: %v", err), errorStatus(err))
+			return
+		}
+	}
 	var task task.Task
 	if err := json.NewDecoder(r.Body).Decode(&task); err != nil {
 		http.Error(w, "failed to deserialize task", http.StatusBadRequest)
-	} else if id, err := s.Put(task); err != nil {
-		http.Error(w, fmt.Sprintf("failed to store task: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if !setList(list, &task, w) {
+		return
+	}
+	if id != "" {
+		if task.ID != "" && task.ID != id {
+			http.Error(w, fmt.Sprintf("task id %q does not match path id %q", task.ID, id), http.StatusBadRequest)
+			return
+		}
+		task.ID = id
+	}
+	var created bool
+	var err error
+	if createOnly {
+		id, err = s.Create(ctx, task)
+		created = true
+	} else {
+		id, created, err = s.Put(ctx, task)
+	}
+	if err != nil {
+		status := errorStatus(err)
+		if createOnly && status == http.StatusConflict {
+			// The task already exists, so the If-None-Match precondition failed.
+			status = http.StatusPreconditionFailed
+		}
+		http.Error(w, fmt.Sprintf("failed to store task: %v", err), status)
+		return
+	}
+	if created {
+		w.Header().Set("Location", taskPath(list, id))
+		w.WriteHeader(http.StatusCreated)
+	}
+	if _, err := io.WriteString(w, id); err != nil {
+		http.Error(w, fmt.Sprintf("failed writing response id %q: %s", id, err), http.StatusInternalServerError)
+	}
+}
+
+// Creates a new task. The backend generates an id if the task has none. The list is taken from the path if not empty.
+func (s *server) create(list string, w http.ResponseWriter, r *http.Request) {
+	var t task.Task
+	if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
+		http.Error(w, "failed to deserialize task", http.StatusBadRequest)
+		return
+	}
+	if !setList(list, &t, w) {
+		return
+	}
+	id, err := s.Create(r.Context(), t)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to store task: %v", err), errorStatus(err))
+		return
+	}
+	w.Header().Set("Location", taskPath(list, id))
+	w.WriteHeader(http.StatusCreated)
+	if _, err := io.WriteString(w, id); err != nil {
+		http.Error(w, fmt.Sprintf("failed writing response id %q: %s", id, err), http.StatusInternalServerError)
+	}
+}
+
+// The setList function sets the list of t from the path list, if it is not empty. Writes an error response and returns
+// false if t is already in a different list.
+func setList(list string, t *task.Task, w http.ResponseWriter) bool {
+	if list == "" {
+		return true
+	}
+	if t.List != "" && t.List != list {
+		http.Error(w, fmt.Sprintf("task list %q does not match path list %q", t.List, list), http.StatusBadRequest)
+		return false
+	}
+	t.List = list
+	return true
+}
+
+// The mergePatchType is the media type of RFC 7386 JSON merge patches.
+const mergePatchType = "application/merge-patch+json"
+
+// Patches a task.
+func (s *server) patch(list, id string, w http.ResponseWriter, r *http.Request) {
+	if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || (mediaType != mergePatchType && mediaType != "application/json") {
+		w.Header().Set("Accept-Patch", mergePatchType)
+		http.Error(w, fmt.Sprintf("unsupported patch content type %q", r.Header.Get("Content-Type")), http.StatusUnsupportedMediaType)
+		return
+	}
+	ctx, ok := s.conditional(w, r, false)
+	if !ok {
+		return
+	}
+	if patch, err := ioutil.ReadAll(r.Body); err != nil {
+		http.Error(w, "failed to read patch", http.StatusBadRequest)
+	} else if err := s.inList(ctx, list, id); err != nil {
+		http.Error(w, fmt.Sprintf("failed to update task %s: %s", id, err), errorStatus(err))
+	} else if task, err := s.Update(ctx, id, patch); err != nil {
+		http.Error(w, fmt.Sprintf("failed to update task %s: %s", id, err), errorStatus(err))
+	} else {
+		writeTask(w, task)
+	}
+}
+
+// Sets the status of a task.
+func (s *server) setStatus(list, id string, w http.ResponseWriter, r *http.Request) {
+	ctx, ok := s.conditional(w, r, false)
+	if !ok {
+		return
+	}
+	var status task.Status
+	if err := json.NewDecoder(r.Body).Decode(&status); err != nil {
+		http.Error(w, "failed to deserialize status", http.StatusBadRequest)
+	} else if !status.Valid() {
+		http.Error(w, fmt.Sprintf("%s: unknown status %q", task.ErrInvalid, status), http.StatusUnprocessableEntity)
+	} else if err := s.inList(ctx, list, id); err != nil {
+		http.Error(w, fmt.Sprintf("failed to set status of task %s: %s", id, err), errorStatus(err))
+	} else if task, err := s.SetStatus(ctx, id, status); err != nil {
+		http.Error(w, fmt.Sprintf("failed to set status of task %s: %s", id, err), errorStatus(err))
 	} else {
-		if _, err := io.WriteString(w, id); err != nil {
-			http.Error(w, fmt.Sprintf("failed writing response id %q: ", id, err), http.StatusInternalServerError)
+		writeTask(w, task)
+	}
+}
+
+// The writeTask function writes t as the response, with its revision as an ETag.
+func writeTask(w http.ResponseWriter, t *task.Task) {
+	w.Header().Set("ETag", etag(t.Revision))
+	if err := json.NewEncoder(w).Encode(t); err != nil {
+		http.Error(w, fmt.Sprintf("failed to serialize task: %v", t), http.StatusInternalServerError)
+	}
+}
+
+// The etag function returns the ETag of a task at revision.
+func etag(revision int64) string {
+	return `"` + strconv.FormatInt(revision, 10) + `"`
+}
+
+// The noneMatch function returns true if header, an If-None-Match header, matches the ETag of a task at revision.
+func noneMatch(header string, revision int64) bool {
+	if header == "" {
+		return false
+	}
+	tag := etag(revision)
+	for _, t := range strings.Split(header, ",") {
+		t = strings.TrimPrefix(strings.TrimSpace(t), "W/")
+		if t == "*" || t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// The conditional method returns the request context, carrying the revision expected by the If-Match header, if any.
+// Writes an error response and returns false if the header is not a single ETag, or if it is missing and the server
+// requires it, unless exempt.
+func (s *server) conditional(w http.ResponseWriter, r *http.Request, exempt bool) (context.Context, bool) {
+	ctx := r.Context()
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		if s.requireIfMatch && !exempt {
+			http.Error(w, "an If-Match header with the task revision is required", http.StatusPreconditionRequired)
+			return nil, false
 		}
+		return ctx, true
+	}
+	unquoted := strings.TrimSuffix(strings.TrimPrefix(header, `"`), `"`)
+	revision, err := strconv.ParseInt(unquoted, 10, 64)
+	if err != nil || revision < 1 || header != etag(revision) {
+		http.Error(w, fmt.Sprintf("invalid If-Match header %q: expected a single task revision ETag", header), http.StatusBadRequest)
+		return nil, false
+	}
+	return task.IfMatch(ctx, revision), true
+}
+
+// Gets the trees of the subtasks of a task.
+func (s *server) getChildren(list, id string, w http.ResponseWriter, r *http.Request) {
+	if err := s.inList(r.Context(), list, id); err != nil {
+		http.Error(w, fmt.Sprintf("failed to get subtasks of task %s: %s", id, err), errorStatus(err))
+		return
+	}
+	children, err := s.Children(r.Context(), id)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to get subtasks of task %s: %s", id, err), errorStatus(err))
+		return
+	}
+	if children == nil {
+		children = []task.Tree{}
+	}
+	if err := json.NewEncoder(w).Encode(children); err != nil {
+		http.Error(w, fmt.Sprintf("failed to serialize subtasks: %v", children), http.StatusInternalServerError)
+	}
+}
+
+// Deletes a task. Its subtasks are kept as top level tasks, unless the cascade query parameter is true, in which case
+// they are deleted too.
+func (s *server) delete(list, id string, w http.ResponseWriter, r *http.Request) {
+	del := s.Delete
+	switch cascade := r.URL.Query().Get("cascade"); cascade {
+	case "", "false":
+	case "true":
+		del = s.DeleteTree
+	default:
+		http.Error(w, fmt.Sprintf("invalid cascade value %q", cascade), http.StatusBadRequest)
+		return
+	}
+	ctx, ok := s.conditional(w, r, false)
+	if !ok {
+		return
+	}
+	if err := s.inList(ctx, list, id); err != nil {
+		http.Error(w, fmt.Sprintf("failed to delete task %s: %s", id, err), errorStatus(err))
+	} else if err := del(ctx, id); err != nil {
+		http.Error(w, fmt.Sprintf("failed to delete task %s: %s", id, err), errorStatus(err))
+	}
+}
+
+// Gets every list.
+func (s *server) getLists(w http.ResponseWriter, r *http.Request) {
+	lists, err := s.GetLists(r.Context())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to get lists: %s", err), errorStatus(err))
+		return
+	}
+	if lists == nil {
+		lists = []task.TaskList{}
+	}
+	if err := json.NewEncoder(w).Encode(lists); err != nil {
+		http.Error(w, fmt.Sprintf("failed to serialize lists: %v", lists), http.StatusInternalServerError)
+	}
+}
+
+// Gets a single list.
+func (s *server) getList(id string, w http.ResponseWriter, r *http.Request) {
+	if list, err := s.GetList(r.Context(), id); err != nil {
+		http.Error(w, fmt.Sprintf("failed to get list %s: %s", id, err), errorStatus(err))
+	} else if err := json.NewEncoder(w).Encode(list); err != nil {
+		http.Error(w, fmt.Sprintf("failed to serialize list: %v", list), http.StatusInternalServerError)
+	}
+}
+
+// Puts a list, creating or replacing it. The id is taken from the path if not empty, otherwise from the list.
+func (s *server) putList(id string, w http.ResponseWriter, r *http.Request) {
+	var list task.TaskList
+	if err := json.NewDecoder(r.Body).Decode(&list); err != nil {
+		http.Error(w, "failed to deserialize list", http.StatusBadRequest)
+		return
+	}
+	if id != "" {
+		if list.ID != "" && list.ID != id {
+			http.Error(w, fmt.Sprintf("list id %q does not match path id %q", list.ID, id), http.StatusBadRequest)
+			return
+		}
+		list.ID = id
+	}
+	id, created, err := s.PutList(r.Context(), list)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to store list: %v", err), errorStatus(err))
+		return
+	}
+	if created {
+		w.Header().Set("Location", "/lists/"+id)
+		w.WriteHeader(http.StatusCreated)
+	}
+	if _, err := io.WriteString(w, id); err != nil {
+		http.Error(w, fmt.Sprintf("failed writing response id %q: %s", id, err), http.StatusInternalServerError)
+	}
+}
+
+// Deletes a list, and its tasks.
+func (s *server) deleteList(id string, w http.ResponseWriter, r *http.Request) {
+	if err := s.DeleteList(r.Context(), id); err != nil {
+		http.Error(w, fmt.Sprintf("failed to delete list %s: %s", id, err), errorStatus(err))
+	}
+}
+
+// Routes requests for the grants of a list based on Method.
+func (s *server) serveGrants(id string, w http.ResponseWriter, r *http.Request) {
+	var list *task.TaskList
+	var err error
+	switch r.Method {
+	case "PUT":
+		var grant task.Grant
+		if err := json.NewDecoder(r.Body).Decode(&grant); err != nil {
+			http.Error(w, "failed to deserialize grant", http.StatusBadRequest)
+			return
+		}
+		list, err = s.Share(r.Context(), id, grant)
+	case "DELETE":
+		query := r.URL.Query()
+		list, err = s.Unshare(r.Context(), id, task.Grantee{User: query.Get("user"), Group: query.Get("group")})
+	default:
+		http.Error(w, fmt.Sprintf("method %s not supported", r.Method), http.StatusMethodNotAllowed)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to update grants of list %s: %s", id, err), errorStatus(err))
+	} else if err := json.NewEncoder(w).Encode(list); err != nil {
+		http.Error(w, fmt.Sprintf("failed to serialize list: %v", list), http.StatusInternalServerError)
+	}
+}
+
+// The keepAlive is the interval between comments sent to idle event streams, so that proxies do not close them.
+const keepAlive = 30 * time.Second
+
+// Streams the events of changes to tasks as Server-Sent Events, resuming after the event with the id of the
+// Last-Event-ID header, or the after query parameter, if any.
+func (s *server) watch(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	last := r.Header.Get("Last-Event-ID")
+	if last == "" {
+		last = r.URL.Query().Get("after")
+	}
+	var after int64
+	if last != "" {
+		var err error
+		if after, err = strconv.ParseInt(last, 10, 64); err != nil {
+			http.Error(w, fmt.Sprintf("invalid event id %q", last), http.StatusBadRequest)
+			return
+		}
+	}
+	events, err := s.Watch(r.Context(), after)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to watch events: %s", err), errorStatus(err))
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ticker := time.NewTicker(keepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+			bs, err := json.Marshal(e)
+			if err != nil {
+				return
+			}
+			if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, bs); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if _, err := io.WriteString(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
 	}
 }
 
-// Deletes a task.
-func (s *server) delete(id string, w http.ResponseWriter, r *http.Request) {
-	if err := s.Delete(id); err != nil {
-		http.Error(w, fmt.Sprintf("failed to delete task %s", id), http.StatusInternalServerError)
+// The errorStatus function returns the http status code for an error returned by a task.TaskInterface.
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, task.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, task.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, task.ErrInvalid):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, task.ErrListsUnsupported):
+		return http.StatusNotImplemented
+	case errors.Is(err, task.ErrRevisionMismatch):
+		return http.StatusPreconditionFailed
+	case errors.Is(err, task.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, task.ErrExpired):
+		return http.StatusGone
+	default:
+		return http.StatusInternalServerError
 	}
 }
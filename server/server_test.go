@@ -2,12 +2,20 @@ package server
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/gorilla/websocket"
 	"github.com/jmank88/todo/task"
 )
 
@@ -20,7 +28,7 @@ func TestGet(t *testing.T) {
 	}
 
 	ts := httptest.NewServer(NewServer(&mockTaskInterface{
-		get: func(id string) (*task.Task, error) {
+		get: func(ctx context.Context, id string) (*task.Task, error) {
 			if id != expected.ID {
 				t.Fatalf("expected %q but got %q", expected.ID, id)
 			}
@@ -41,12 +49,87 @@ func TestGet(t *testing.T) {
 		var got task.Task
 		if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
 			t.Fatal("unexpected error decoding response: ", err)
-		} else if got != expected {
+		} else if !reflect.DeepEqual(got, expected) {
 			t.Fatalf("expected %v but got %v", expected, got)
 		}
 	}
 }
 
+// Tests that a get request responds with the task revision as an ETag, and 304 Not Modified if it matches.
+func TestGetNotModified(t *testing.T) {
+	ts := httptest.NewServer(NewServer(&mockTaskInterface{
+		get: func(ctx context.Context, id string) (*task.Task, error) {
+			return &task.Task{ID: id, Revision: 3}, nil
+		},
+	}))
+	defer ts.Close()
+
+	for _, test := range []struct {
+		ifNoneMatch string
+		status      int
+	}{
+		{``, http.StatusOK},
+		{`"2"`, http.StatusOK},
+		{`"3"`, http.StatusNotModified},
+		{`"2", W/"3"`, http.StatusNotModified},
+		{`*`, http.StatusNotModified},
+	} {
+		req, err := http.NewRequest("GET", ts.URL+"/id", nil)
+		if err != nil {
+			t.Fatal("unexpected error building request: ", err)
+		}
+		if test.ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", test.ifNoneMatch)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal("unexpected error: ", err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != test.status {
+			t.Errorf("%s: expected %d but got %d", test.ifNoneMatch, test.status, resp.StatusCode)
+		}
+		if etag := resp.Header.Get("ETag"); etag != `"3"` {
+			t.Errorf("%s: expected ETag %q but got %q", test.ifNoneMatch, `"3"`, etag)
+		}
+	}
+}
+
+// Tests that a request's context is cancelled when the client disconnects.
+func TestGetCancelled(t *testing.T) {
+	cancelled := make(chan struct{})
+	ts := httptest.NewServer(NewServer(&mockTaskInterface{
+		get: func(ctx context.Context, id string) (*task.Task, error) {
+			<-ctx.Done()
+			close(cancelled)
+			return nil, ctx.Err()
+		},
+	}))
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(ctx, "GET", ts.URL+"/id", nil)
+	if err != nil {
+		t.Fatal("unexpected error building request: ", err)
+	}
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	if resp, err := http.DefaultClient.Do(req); err == nil {
+		resp.Body.Close()
+		t.Fatal("expected error from cancelled request")
+	}
+
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("expected handler context to be cancelled")
+	}
+}
+
 // Tests a get all request.
 func TestGetAll(t *testing.T) {
 	expected := []task.Task{
@@ -63,8 +146,11 @@ func TestGetAll(t *testing.T) {
 	}
 
 	ts := httptest.NewServer(NewServer(&mockTaskInterface{
-		getAll: func() ([]task.Task, error) {
-			return expected, nil
+		list: func(ctx context.Context, options task.ListOptions) (*task.Page, error) {
+			if !reflect.DeepEqual(options, task.ListOptions{}) {
+				t.Fatalf("expected empty options but got %v", options)
+			}
+			return &task.Page{Tasks: expected}, nil
 		},
 	}))
 	defer ts.Close()
@@ -87,7 +173,7 @@ func TestGetAll(t *testing.T) {
 			expectedMap := indexByID(expected)
 			gotMap := indexByID(got)
 			for id, task := range expectedMap {
-				if gotTask, ok := gotMap[id]; !ok || gotTask != task {
+				if gotTask, ok := gotMap[id]; !ok || !reflect.DeepEqual(gotTask, task) {
 					t.Fatalf("epected %v but got %v", expected, got)
 				}
 			}
@@ -95,6 +181,345 @@ func TestGetAll(t *testing.T) {
 	}
 }
 
+// Tests a get all request with filter query parameters.
+func TestFind(t *testing.T) {
+	before := time.Date(2016, 1, 2, 3, 4, 5, 0, time.UTC)
+	ts := httptest.NewServer(NewServer(&mockTaskInterface{
+		list: func(ctx context.Context, options task.ListOptions) (*task.Page, error) {
+			if !options.Overdue {
+				t.Fatal("expected overdue filter")
+			}
+			if options.DueBefore == nil || !options.DueBefore.Equal(before) {
+				t.Fatalf("expected due before %s but got %v", before, options.DueBefore)
+			}
+			if options.DueAfter != nil {
+				t.Fatalf("expected no due after but got %s", options.DueAfter)
+			}
+			return &task.Page{}, nil
+		},
+	}))
+	defer ts.Close()
+
+	if resp, err := http.Get(ts.URL + "/?overdue=true&due_before=" + before.Format(time.RFC3339)); err != nil {
+		t.Fatal("unexpected error sending request: ", err)
+	} else {
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected %d but got %d", http.StatusOK, resp.StatusCode)
+		}
+	}
+}
+
+// Tests a paginated get all request, and the link to the next page.
+func TestList(t *testing.T) {
+	ts := httptest.NewServer(NewServer(&mockTaskInterface{
+		list: func(ctx context.Context, options task.ListOptions) (*task.Page, error) {
+			if options.Limit != 2 || options.Sort != "-due" || options.Cursor != "" {
+				t.Fatalf("expected limit 2 and sort -due but got %v", options)
+			}
+			return &task.Page{Tasks: []task.Task{{ID: "1"}, {ID: "2"}}, Next: "nextCursor"}, nil
+		},
+	}))
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/?limit=2&sort=-due")
+	if err != nil {
+		t.Fatal("unexpected error sending request: ", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected %d but got %d", http.StatusOK, resp.StatusCode)
+	}
+	if expected, got := `</?cursor=nextCursor&limit=2&sort=-due>; rel="next"`, resp.Header.Get("Link"); got != expected {
+		t.Fatalf("expected link %q but got %q", expected, got)
+	}
+	var got []task.Task
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatal("unexpected error decoding response: ", err)
+	} else if len(got) != 2 {
+		t.Fatalf("expected 2 tasks but got %v", got)
+	}
+
+	for _, query := range []string{"limit=0", "limit=ten"} {
+		if resp, err := http.Get(ts.URL + "/?" + query); err != nil {
+			t.Fatal("unexpected error sending request: ", err)
+		} else if resp.Body.Close(); resp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("expected %d for %q but got %d", http.StatusBadRequest, query, resp.StatusCode)
+		}
+	}
+}
+
+// Tests a search request, filtered by status.
+func TestSearch(t *testing.T) {
+	expected := []task.SearchResult{{Task: task.Task{ID: "1", Title: "Buy milk"}, Rank: 1, Snippet: "Buy **milk**"}}
+	ts := httptest.NewServer(NewServer(&mockTaskInterface{
+		search: func(ctx context.Context, query string, filter task.Filter) ([]task.SearchResult, error) {
+			if query != "milk" {
+				t.Fatalf("expected query %q but got %q", "milk", query)
+			}
+			if !reflect.DeepEqual(filter.Statuses, []task.Status{task.StatusOpen}) {
+				t.Fatalf("expected open status filter but got %v", filter)
+			}
+			return expected, nil
+		},
+	}))
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/_search?q=milk&status=open")
+	if err != nil {
+		t.Fatal("unexpected error sending request: ", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected %d but got %d", http.StatusOK, resp.StatusCode)
+	}
+	var got []task.SearchResult
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatal("unexpected error decoding response: ", err)
+	} else if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected %v but got %v", expected, got)
+	}
+
+	if resp, err := http.Get(ts.URL + "/_search"); err != nil {
+		t.Fatal("unexpected error sending request: ", err)
+	} else if resp.Body.Close(); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected %d without a query but got %d", http.StatusBadRequest, resp.StatusCode)
+	}
+}
+
+// Tests getting the tag counts, and filtering by tags.
+func TestTags(t *testing.T) {
+	expected := []task.TagCount{{Tag: "ops", Count: 2}}
+	ts := httptest.NewServer(NewServer(&mockTaskInterface{
+		tags: func(ctx context.Context) ([]task.TagCount, error) {
+			return expected, nil
+		},
+		list: func(ctx context.Context, options task.ListOptions) (*task.Page, error) {
+			if !reflect.DeepEqual(options.Tags, []string{"ops", "home"}) || !options.AllTags {
+				t.Fatalf("expected all of tags [ops home] but got %v", options)
+			}
+			return &task.Page{}, nil
+		},
+	}))
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/_tags")
+	if err != nil {
+		t.Fatal("unexpected error sending request: ", err)
+	}
+	defer resp.Body.Close()
+	var got []task.TagCount
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatal("unexpected error decoding response: ", err)
+	} else if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected %v but got %v", expected, got)
+	}
+
+	if resp, err := http.Get(ts.URL + "/?tag=ops&tag=home&tag_match=all"); err != nil {
+		t.Fatal("unexpected error sending request: ", err)
+	} else if resp.Body.Close(); resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected %d but got %d", http.StatusOK, resp.StatusCode)
+	}
+	if resp, err := http.Get(ts.URL + "/?tag=ops&tag_match=some"); err != nil {
+		t.Fatal("unexpected error sending request: ", err)
+	} else if resp.Body.Close(); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected %d but got %d", http.StatusBadRequest, resp.StatusCode)
+	}
+}
+
+// Tests parsing the status and priority filters.
+func TestParseFilterPriority(t *testing.T) {
+	filter, err := parseFilter(url.Values{"status": {"open", "in-progress"}, "priority": {"P0", "none"}}, time.Now())
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if expected := []task.Status{task.StatusOpen, task.StatusInProgress}; !reflect.DeepEqual(filter.Statuses, expected) {
+		t.Fatalf("expected statuses %v but got %v", expected, filter.Statuses)
+	}
+	if expected := []task.Priority{task.PriorityP0, ""}; !reflect.DeepEqual(filter.Priorities, expected) {
+		t.Fatalf("expected priorities %v but got %v", expected, filter.Priorities)
+	}
+
+	if _, err := parseFilter(url.Values{"priority": {"P5"}}, time.Now()); err == nil {
+		t.Fatal("expected error for invalid priority")
+	}
+	if _, err := parseFilter(url.Values{"status": {"finished"}}, time.Now()); err == nil {
+		t.Fatal("expected error for invalid status")
+	}
+}
+
+// Tests parsing the due=today filter.
+func TestParseFilterToday(t *testing.T) {
+	now := time.Date(2016, 1, 2, 3, 4, 5, 0, time.UTC)
+	filter, err := parseFilter(url.Values{"due": {"today"}}, now)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	start := time.Date(2016, 1, 2, 0, 0, 0, 0, time.UTC)
+	if filter.DueAfter == nil || !filter.DueAfter.Equal(start) {
+		t.Fatalf("expected due after %s but got %v", start, filter.DueAfter)
+	}
+	if end := start.AddDate(0, 0, 1); filter.DueBefore == nil || !filter.DueBefore.Equal(end) {
+		t.Fatalf("expected due before %s but got %v", end, filter.DueBefore)
+	}
+
+	if _, err := parseFilter(url.Values{"due_after": {"yesterday"}}, now); err == nil {
+		t.Fatal("expected error for invalid time")
+	}
+}
+
+// Tests parsing the updated_since filter, with a fraction of a second.
+func TestParseFilterUpdatedSince(t *testing.T) {
+	filter, err := parseFilter(url.Values{"updated_since": {"2016-01-02T03:04:05.123456Z"}}, time.Now())
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if expected := time.Date(2016, 1, 2, 3, 4, 5, 123456000, time.UTC); filter.UpdatedSince == nil || !filter.UpdatedSince.Equal(expected) {
+		t.Fatalf("expected updated since %s but got %v", expected, filter.UpdatedSince)
+	}
+	if _, err := parseFilter(url.Values{"updated_since": {"3d"}}, time.Now()); err == nil {
+		t.Fatal("expected error for invalid time")
+	}
+}
+
+// Tests routing requests for the tasks of a list.
+func TestListTasks(t *testing.T) {
+	ts := httptest.NewServer(NewServer(&mockTaskInterface{
+		getList: func(ctx context.Context, id string) (*task.TaskList, error) {
+			if id != "shopping" {
+				return nil, task.ErrNotFound
+			}
+			return &task.TaskList{ID: id, Name: "Shopping"}, nil
+		},
+		getLists: func(ctx context.Context) ([]task.TaskList, error) {
+			return []task.TaskList{{ID: "shopping", Name: "Shopping"}}, nil
+		},
+		get: func(ctx context.Context, id string) (*task.Task, error) {
+			if id == "milk" {
+				return nil, fmt.Errorf("failed to get task %q: %w", id, task.ErrNotFound)
+			}
+			return &task.Task{ID: id, List: "work"}, nil
+		},
+		put: func(ctx context.Context, tsk task.Task) (string, bool, error) {
+			if tsk.ID != "milk" {
+				t.Fatalf("unexpected put of task %q in another list", tsk.ID)
+			}
+			if tsk.List != "shopping" {
+				t.Fatalf("expected list %q but got %q", "shopping", tsk.List)
+			}
+			return tsk.ID, true, nil
+		},
+		list: func(ctx context.Context, options task.ListOptions) (*task.Page, error) {
+			if options.List != "shopping" {
+				t.Fatalf("expected list %q but got %q", "shopping", options.List)
+			}
+			return &task.Page{}, nil
+		},
+		delete: func(ctx context.Context, id string) error {
+			t.Fatal("unexpected delete of task in another list")
+			return nil
+		},
+	}))
+	defer ts.Close()
+
+	for _, test := range []struct {
+		method, path, body string
+		status             int
+	}{
+		{"GET", "/lists", "", http.StatusOK},
+		{"GET", "/lists/shopping", "", http.StatusOK},
+		{"GET", "/lists/shopping/tasks", "", http.StatusOK},
+		{"GET", "/lists/missing/tasks", "", http.StatusNotFound},
+		{"GET", "/lists/shopping/other", "", http.StatusNotFound},
+		{"GET", "/lists/shopping/tasks/a", "", http.StatusNotFound},
+		{"DELETE", "/lists/shopping/tasks/a", "", http.StatusNotFound},
+		{"PUT", "/lists/shopping/tasks/a", `{"title":"moved"}`, http.StatusNotFound},
+		{"PUT", "/lists/shopping/tasks/milk", `{"title":"milk"}`, http.StatusCreated},
+		{"PUT", "/lists/shopping/tasks/milk", `{"list":"work"}`, http.StatusBadRequest},
+	} {
+		req, err := http.NewRequest(test.method, ts.URL+test.path, strings.NewReader(test.body))
+		if err != nil {
+			t.Fatal("unexpected error building request: ", err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal("unexpected error: ", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != test.status {
+			t.Errorf("%s %s: expected %d but got %d", test.method, test.path, test.status, resp.StatusCode)
+		}
+		if resp.StatusCode == http.StatusCreated {
+			if location := resp.Header.Get("Location"); location != "/lists/shopping/tasks/milk" {
+				t.Errorf("expected location %q but got %q", "/lists/shopping/tasks/milk", location)
+			}
+		}
+	}
+}
+
+// Tests getting the subtasks of a task, and deleting a task with and without its subtasks.
+func TestChildren(t *testing.T) {
+	expected := []task.Tree{{Task: task.Task{ID: "eggs", Parent: "list"}, Children: []task.Tree{{Task: task.Task{ID: "free-range", Parent: "eggs"}}}}}
+	var deleted, deletedTree []string
+	ts := httptest.NewServer(NewServer(&mockTaskInterface{
+		children: func(ctx context.Context, id string) ([]task.Tree, error) {
+			if id != "list" {
+				t.Fatalf("expected %q but got %q", "list", id)
+			}
+			return expected, nil
+		},
+		delete: func(ctx context.Context, id string) error {
+			deleted = append(deleted, id)
+			return nil
+		},
+		delTree: func(ctx context.Context, id string) error {
+			deletedTree = append(deletedTree, id)
+			return nil
+		},
+	}))
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/list/children")
+	if err != nil {
+		t.Fatal("unexpected error sending request: ", err)
+	}
+	defer resp.Body.Close()
+	var got []task.Tree
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatal("unexpected error decoding response: ", err)
+	} else if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected %v but got %v", expected, got)
+	}
+
+	for _, test := range []struct {
+		query  string
+		status int
+	}{
+		{"", http.StatusOK},
+		{"?cascade=true", http.StatusOK},
+		{"?cascade=maybe", http.StatusBadRequest},
+	} {
+		req, err := http.NewRequest("DELETE", ts.URL+"/list"+test.query, nil)
+		if err != nil {
+			t.Fatal("unexpected error building request: ", err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal("unexpected error: ", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != test.status {
+			t.Errorf("expected %d but got %d", test.status, resp.StatusCode)
+		}
+	}
+	if !reflect.DeepEqual(deleted, []string{"list"}) || !reflect.DeepEqual(deletedTree, []string{"list"}) {
+		t.Fatalf("expected one delete and one tree delete but got %v and %v", deleted, deletedTree)
+	}
+}
+
 // Tests a put request.
 func TestPut(t *testing.T) {
 	testTask := task.Task{
@@ -104,11 +529,11 @@ func TestPut(t *testing.T) {
 	}
 
 	ts := httptest.NewServer(NewServer(&mockTaskInterface{
-		put: func(task task.Task) (string, error) {
-			if task != testTask {
+		put: func(ctx context.Context, task task.Task) (string, bool, error) {
+			if !reflect.DeepEqual(task, testTask) {
 				t.Fatalf("expected %v but got %v", testTask, task)
 			}
-			return testTask.ID, nil
+			return testTask.ID, false, nil
 		},
 	}))
 	defer ts.Close()
@@ -139,11 +564,113 @@ func TestPut(t *testing.T) {
 	}
 }
 
+// Tests a put request to a task path creating a new task.
+func TestPutCreated(t *testing.T) {
+	const testId = "id"
+	ts := httptest.NewServer(NewServer(&mockTaskInterface{
+		put: func(ctx context.Context, task task.Task) (string, bool, error) {
+			if task.ID != testId {
+				t.Fatalf("expected %q but got %q", testId, task.ID)
+			}
+			return task.ID, true, nil
+		},
+	}))
+	defer ts.Close()
+
+	req, err := http.NewRequest("PUT", ts.URL+"/"+testId, bytes.NewReader([]byte(`{"title":"test title"}`)))
+	if err != nil {
+		t.Fatal("unexpected error building request: ", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected %d but got %d", http.StatusCreated, resp.StatusCode)
+	}
+	if loc := resp.Header.Get("Location"); loc != "/"+testId {
+		t.Fatalf("expected location %q but got %q", "/"+testId, loc)
+	}
+}
+
+// Tests a put request with mismatched path and task ids.
+func TestPutMismatchedId(t *testing.T) {
+	ts := httptest.NewServer(NewServer(&mockTaskInterface{}))
+	defer ts.Close()
+
+	req, err := http.NewRequest("PUT", ts.URL+"/id", bytes.NewReader([]byte(`{"id":"other"}`)))
+	if err != nil {
+		t.Fatal("unexpected error building request: ", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected %d but got %d", http.StatusBadRequest, resp.StatusCode)
+	}
+}
+
+// Tests a post request.
+func TestCreate(t *testing.T) {
+	const generatedId = "generated"
+	ts := httptest.NewServer(NewServer(&mockTaskInterface{
+		create: func(ctx context.Context, task task.Task) (string, error) {
+			if task.ID != "" {
+				t.Fatalf("expected no id but got %q", task.ID)
+			}
+			return generatedId, nil
+		},
+	}))
+	defer ts.Close()
+
+	resp, err := http.Post(ts.URL, "application/json", bytes.NewReader([]byte(`{"title":"test title"}`)))
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected %d but got %d", http.StatusCreated, resp.StatusCode)
+	}
+	if got, err := ioutil.ReadAll(resp.Body); err != nil {
+		t.Fatal("unexpected error reading response: ", err)
+	} else if string(got) != generatedId {
+		t.Fatalf("expected id %q but got %q", generatedId, got)
+	}
+}
+
+// Tests a post request conflicting with an existing task.
+func TestCreateConflict(t *testing.T) {
+	ts := httptest.NewServer(NewServer(&mockTaskInterface{
+		create: func(ctx context.Context, t task.Task) (string, error) {
+			return "", fmt.Errorf("failed to create task %q: %w", t.ID, task.ErrConflict)
+		},
+	}))
+	defer ts.Close()
+
+	resp, err := http.Post(ts.URL, "application/json", bytes.NewReader([]byte(`{"id":"id"}`)))
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusConflict {
+		t.Fatalf("expected %d but got %d", http.StatusConflict, resp.StatusCode)
+	}
+}
+
 // Tests a delete request.
 func TestDelete(t *testing.T) {
 	const testId = "id"
 	ts := httptest.NewServer(NewServer(&mockTaskInterface{
-		delete: func(id string) error {
+		delete: func(ctx context.Context, id string) error {
 			if id != testId {
 				t.Fatalf("expected %q but got %q", testId, id)
 			}
@@ -168,27 +695,688 @@ func TestDelete(t *testing.T) {
 	}
 }
 
-type mockTaskInterface struct {
-	get    func(string) (*task.Task, error)
-	getAll func() ([]task.Task, error)
-	put    func(task.Task) (string, error)
-	delete func(string) error
+// Tests a delete request conditional on the task revision.
+func TestDeleteIfMatch(t *testing.T) {
+	ts := httptest.NewServer(NewServer(&mockTaskInterface{
+		delete: func(ctx context.Context, id string) error {
+			if revision, ok := task.ExpectedRevision(ctx); !ok || revision != 2 {
+				t.Fatalf("expected revision 2 but got %d", revision)
+			}
+			return task.CheckRevision(ctx, id, 3)
+		},
+	}))
+	defer ts.Close()
+
+	for _, test := range []struct {
+		ifMatch string
+		status  int
+	}{
+		{`"2"`, http.StatusPreconditionFailed},
+		{`2`, http.StatusBadRequest},
+		{`W/"2"`, http.StatusBadRequest},
+		{`"2", "3"`, http.StatusBadRequest},
+		{`*`, http.StatusBadRequest},
+	} {
+		req, err := http.NewRequest("DELETE", ts.URL+"/id", nil)
+		if err != nil {
+			t.Fatal("unexpected error building request: ", err)
+		}
+		req.Header.Set("If-Match", test.ifMatch)
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal("unexpected error: ", err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != test.status {
+			t.Errorf("%s: expected %d but got %d", test.ifMatch, test.status, resp.StatusCode)
+		}
+	}
+}
+
+// Tests that writes without an If-Match header are rejected when the server requires one.
+func TestRequireIfMatch(t *testing.T) {
+	ts := httptest.NewServer(NewServer(&mockTaskInterface{
+		create: func(ctx context.Context, t task.Task) (string, error) {
+			return t.ID, nil
+		},
+	}, RequireIfMatch()))
+	defer ts.Close()
+
+	for _, test := range []struct {
+		method, path, body string
+		header             http.Header
+		status             int
+	}{
+		{"PUT", "/id", `{}`, nil, http.StatusPreconditionRequired},
+		{"PUT", "/id", `{}`, http.Header{"If-None-Match": {"*"}}, http.StatusCreated},
+		{"PATCH", "/id", `{}`, http.Header{"Content-Type": {mergePatchType}}, http.StatusPreconditionRequired},
+		{"PUT", "/id/status", `"done"`, nil, http.StatusPreconditionRequired},
+		{"DELETE", "/id", ``, nil, http.StatusPreconditionRequired},
+	} {
+		req, err := http.NewRequest(test.method, ts.URL+test.path, strings.NewReader(test.body))
+		if err != nil {
+			t.Fatal("unexpected error building request: ", err)
+		}
+		for k, v := range test.header {
+			req.Header[k] = v
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal("unexpected error: ", err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != test.status {
+			t.Errorf("%s %s: expected %d but got %d", test.method, test.path, test.status, resp.StatusCode)
+		}
+	}
+}
+
+// Tests that a put request with "If-None-Match: *" only creates the task.
+func TestPutIfNoneMatch(t *testing.T) {
+	ts := httptest.NewServer(NewServer(&mockTaskInterface{
+		create: func(ctx context.Context, t task.Task) (string, error) {
+			return "", fmt.Errorf("failed to create task %q: %w", t.ID, task.ErrConflict)
+		},
+	}))
+	defer ts.Close()
+
+	req, err := http.NewRequest("PUT", ts.URL+"/id", strings.NewReader(`{}`))
+	if err != nil {
+		t.Fatal("unexpected error building request: ", err)
+	}
+	req.Header.Set("If-None-Match", "*")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusPreconditionFailed {
+		t.Fatalf("expected %d but got %d", http.StatusPreconditionFailed, resp.StatusCode)
+	}
+}
+
+// Tests a patch request.
+func TestPatch(t *testing.T) {
+	const testId = "id"
+	const patch = `{"title":"new title"}`
+	ts := httptest.NewServer(NewServer(&mockTaskInterface{
+		update: func(ctx context.Context, id string, p []byte) (*task.Task, error) {
+			if id != testId {
+				t.Fatalf("expected %q but got %q", testId, id)
+			}
+			if string(p) != patch {
+				t.Fatalf("expected patch %s but got %s", patch, p)
+			}
+			return &task.Task{ID: id, Title: "new title"}, nil
+		},
+	}))
+	defer ts.Close()
+
+	req, err := http.NewRequest("PATCH", ts.URL+"/"+testId, bytes.NewReader([]byte(patch)))
+	if err != nil {
+		t.Fatal("unexpected error building request: ", err)
+	}
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected %d but got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	var got task.Task
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatal("unexpected error decoding response: ", err)
+	} else if got.Title != "new title" {
+		t.Fatalf("expected title %q but got %q", "new title", got.Title)
+	}
+}
+
+// Tests a patch request with an unsupported content type.
+func TestPatchUnsupported(t *testing.T) {
+	ts := httptest.NewServer(NewServer(&mockTaskInterface{}))
+	defer ts.Close()
+
+	req, err := http.NewRequest("PATCH", ts.URL+"/id", bytes.NewReader([]byte(`[]`)))
+	if err != nil {
+		t.Fatal("unexpected error building request: ", err)
+	}
+	req.Header.Set("Content-Type", "application/json-patch+json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusUnsupportedMediaType {
+		t.Fatalf("expected %d but got %d", http.StatusUnsupportedMediaType, resp.StatusCode)
+	}
+}
+
+// Tests a set status request.
+func TestSetStatus(t *testing.T) {
+	const testId = "id"
+	ts := httptest.NewServer(NewServer(&mockTaskInterface{
+		setStatus: func(ctx context.Context, id string, status task.Status) (*task.Task, error) {
+			if id != testId {
+				t.Fatalf("expected %q but got %q", testId, id)
+			}
+			if status != task.StatusDone {
+				t.Fatalf("expected %q but got %q", task.StatusDone, status)
+			}
+			return &task.Task{ID: id, Status: status}, nil
+		},
+	}))
+	defer ts.Close()
+
+	req, err := http.NewRequest("PUT", ts.URL+"/"+testId+"/status", bytes.NewReader([]byte(`"done"`)))
+	if err != nil {
+		t.Fatal("unexpected error building request: ", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected %d but got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	var got task.Task
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatal("unexpected error decoding response: ", err)
+	} else if got.Status != task.StatusDone {
+		t.Fatalf("expected status %q but got %q", task.StatusDone, got.Status)
+	}
+}
+
+// Tests a set status request with an unknown status.
+func TestSetStatusInvalid(t *testing.T) {
+	ts := httptest.NewServer(NewServer(&mockTaskInterface{}))
+	defer ts.Close()
+
+	req, err := http.NewRequest("PUT", ts.URL+"/id/status", bytes.NewReader([]byte(`"finished"`)))
+	if err != nil {
+		t.Fatal("unexpected error building request: ", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusUnprocessableEntity {
+		t.Fatalf("expected %d but got %d", http.StatusUnprocessableEntity, resp.StatusCode)
+	}
+}
+
+// Tests the _actionable and _order views.
+func TestViews(t *testing.T) {
+	actionable := []task.Task{{ID: "eggs"}}
+	ordered := []task.Task{{ID: "eggs"}, {ID: "omelette", BlockedBy: []string{"eggs"}}}
+	var filters []task.Filter
+	ts := httptest.NewServer(NewServer(&mockTaskInterface{
+		actionable: func(ctx context.Context, filter task.Filter) ([]task.Task, error) {
+			filters = append(filters, filter)
+			return actionable, nil
+		},
+		ordered: func(ctx context.Context, filter task.Filter) ([]task.Task, error) {
+			filters = append(filters, filter)
+			return ordered, nil
+		},
+		getList: func(ctx context.Context, id string) (*task.TaskList, error) {
+			return &task.TaskList{ID: id}, nil
+		},
+	}))
+	defer ts.Close()
+
+	for _, test := range []struct {
+		path     string
+		expected []task.Task
+		filter   task.Filter
+	}{
+		{"/_actionable?tag=breakfast", actionable, task.Filter{Tags: []string{"breakfast"}}},
+		{"/_order", ordered, task.Filter{}},
+		{"/lists/groceries/tasks/_order", ordered, task.Filter{List: "groceries"}},
+	} {
+		resp, err := http.Get(ts.URL + test.path)
+		if err != nil {
+			t.Fatal("unexpected error sending request: ", err)
+		}
+		var got []task.Task
+		err = json.NewDecoder(resp.Body).Decode(&got)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal("unexpected error decoding response: ", err)
+		} else if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("%s: expected %v but got %v", test.path, test.expected, got)
+		}
+		if filter := filters[len(filters)-1]; !reflect.DeepEqual(filter, test.filter) {
+			t.Errorf("%s: expected filter %+v but got %+v", test.path, test.filter, filter)
+		}
+	}
+
+	resp, err := http.Post(ts.URL+"/_order", "application/json", strings.NewReader("{}"))
+	if err != nil {
+		t.Fatal("unexpected error sending request: ", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("expected %d but got %d", http.StatusMethodNotAllowed, resp.StatusCode)
+	}
+}
+
+// Tests the _occurrences view, with the default and explicit ranges.
+func TestOccurrences(t *testing.T) {
+	due := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+	expected := []task.Occurrence{{Due: due, Task: task.Task{ID: "trash", Due: &due, Recurrence: "FREQ=WEEKLY"}}}
+	var from, to time.Time
+	ts := httptest.NewServer(NewServer(&mockTaskInterface{
+		occurrences: func(ctx context.Context, f, t time.Time, filter task.Filter) ([]task.Occurrence, error) {
+			from, to = f, t
+			return expected, nil
+		},
+	}))
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/_occurrences?from=2026-01-05T00:00:00Z&to=2026-02-05T00:00:00Z")
+	if err != nil {
+		t.Fatal("unexpected error sending request: ", err)
+	}
+	var got []task.Occurrence
+	err = json.NewDecoder(resp.Body).Decode(&got)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal("unexpected error decoding response: ", err)
+	} else if len(got) != 1 || !got[0].Due.Equal(due) || got[0].Task.ID != "trash" {
+		t.Fatalf("expected %v but got %v", expected, got)
+	}
+	if !from.Equal(due.Add(-9*time.Hour)) || !to.Equal(from.AddDate(0, 1, 0)) {
+		t.Fatalf("expected range from %s but got %s to %s", due.Add(-9*time.Hour), from, to)
+	}
+
+	resp, err = http.Get(ts.URL + "/_occurrences")
+	if err != nil {
+		t.Fatal("unexpected error sending request: ", err)
+	}
+	resp.Body.Close()
+	if to.Sub(from) != 7*24*time.Hour {
+		t.Fatalf("expected a week but got %s to %s", from, to)
+	}
+
+	resp, err = http.Get(ts.URL + "/_occurrences?from=monday")
+	if err != nil {
+		t.Fatal("unexpected error sending request: ", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected %d but got %d", http.StatusBadRequest, resp.StatusCode)
+	}
+}
+
+// Tests mapping task errors to status codes.
+func TestErrorStatus(t *testing.T) {
+	for _, test := range []struct {
+		err    error
+		status int
+	}{
+		{fmt.Errorf("failed: %w", task.ErrNotFound), http.StatusNotFound},
+		{fmt.Errorf("failed: %w", task.ErrConflict), http.StatusConflict},
+		{fmt.Errorf("failed: %w", task.ErrInvalid), http.StatusUnprocessableEntity},
+		{fmt.Errorf("failed: %w", task.ErrListsUnsupported), http.StatusNotImplemented},
+		{fmt.Errorf("failed: %w", task.ErrForbidden), http.StatusForbidden},
+		{fmt.Errorf("failed: %w", task.ErrExpired), http.StatusGone},
+		{errors.New("failed"), http.StatusInternalServerError},
+	} {
+		ts := httptest.NewServer(NewServer(&mockTaskInterface{
+			get: func(ctx context.Context, id string) (*task.Task, error) {
+				return nil, test.err
+			},
+		}))
+
+		resp, err := http.Get(ts.URL + "/id")
+		if err != nil {
+			t.Fatal("unexpected error sending request: ", err)
+		}
+		resp.Body.Close()
+		ts.Close()
+
+		if resp.StatusCode != test.status {
+			t.Errorf("expected %d for %q but got %d", test.status, test.err, resp.StatusCode)
+		}
+	}
+}
+
+// Tests routing the grants of a list to Share and Unshare.
+func TestGrants(t *testing.T) {
+	var grants []task.Grant
+	ts := httptest.NewServer(NewServer(&mockTaskInterface{
+		share: func(ctx context.Context, list string, grant task.Grant) (*task.TaskList, error) {
+			grants = task.Share(grants, grant)
+			return &task.TaskList{ID: list, Grants: grants}, nil
+		},
+		unshare: func(ctx context.Context, list string, grantee task.Grantee) (*task.TaskList, error) {
+			grants = task.Unshare(grants, grantee)
+			return &task.TaskList{ID: list, Grants: grants}, nil
+		},
+	}))
+	defer ts.Close()
+
+	req, err := http.NewRequest("PUT", ts.URL+"/lists/l/grants", strings.NewReader(`{"group":"team","role":"editor"}`))
+	if err != nil {
+		t.Fatal("unexpected error creating request: ", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal("unexpected error sending request: ", err)
+	}
+	var list task.TaskList
+	err = json.NewDecoder(resp.Body).Decode(&list)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal("unexpected error decoding response: ", err)
+	}
+	expected := task.TaskList{ID: "l", Grants: []task.Grant{{Grantee: task.Grantee{Group: "team"}, Role: task.RoleEditor}}}
+	if !reflect.DeepEqual(list, expected) {
+		t.Fatalf("expected %v but got %v", expected, list)
+	}
+
+	req, err = http.NewRequest("DELETE", ts.URL+"/lists/l/grants?group=team", nil)
+	if err != nil {
+		t.Fatal("unexpected error creating request: ", err)
+	}
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal("unexpected error sending request: ", err)
+	}
+	list = task.TaskList{}
+	err = json.NewDecoder(resp.Body).Decode(&list)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal("unexpected error decoding response: ", err)
+	}
+	if expected := (task.TaskList{ID: "l"}); !reflect.DeepEqual(list, expected) {
+		t.Fatalf("expected %v but got %v", expected, list)
+	}
+}
+
+// Tests streaming events as Server-Sent Events, resuming after the Last-Event-ID.
+func TestEvents(t *testing.T) {
+	var resumed int64
+	ts := httptest.NewServer(NewServer(&mockTaskInterface{
+		watch: func(ctx context.Context, after int64) (<-chan task.Event, error) {
+			resumed = after
+			events := make(chan task.Event, 2)
+			events <- task.Event{ID: 8, Type: task.EventCreated, Task: task.Task{ID: "a", Title: "new"}}
+			events <- task.Event{ID: 9, Type: task.EventDeleted, Task: task.Task{ID: "a"}}
+			close(events)
+			return events, nil
+		},
+	}))
+	defer ts.Close()
+
+	req, err := http.NewRequest("GET", ts.URL+"/events", nil)
+	if err != nil {
+		t.Fatal("unexpected error creating request: ", err)
+	}
+	req.Header.Set("Last-Event-ID", "7")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal("unexpected error sending request: ", err)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal("unexpected error reading response: ", err)
+	}
+	if resumed != 7 {
+		t.Errorf("expected to resume after event 7 but got %d", resumed)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("expected event stream but got %q", ct)
+	}
+	expected := "id: 8\nevent: created\ndata: {\"id\":8,\"type\":\"created\",\"task\":{\"id\":\"a\",\"title\":\"new\",\"description\":\"\"}}\n\n" +
+		"id: 9\nevent: deleted\ndata: {\"id\":9,\"type\":\"deleted\",\"task\":{\"id\":\"a\",\"title\":\"\",\"description\":\"\"}}\n\n"
+	if string(body) != expected {
+		t.Fatalf("expected %q but got %q", expected, body)
+	}
+
+	resp, err = http.Get(ts.URL + "/events?after=x")
+	if err != nil {
+		t.Fatal("unexpected error sending request: ", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected %d but got %d", http.StatusBadRequest, resp.StatusCode)
+	}
+}
+
+// Tests subscribing to a list, and writing tasks, over a sync connection, with replies matched by request id.
+func TestSync(t *testing.T) {
+	var resumed int64
+	ts := httptest.NewServer(NewServer(&mockTaskInterface{
+		getList: func(ctx context.Context, id string) (*task.TaskList, error) {
+			if id != "l" {
+				return nil, task.ErrNotFound
+			}
+			return &task.TaskList{ID: id}, nil
+		},
+		watch: func(ctx context.Context, after int64) (<-chan task.Event, error) {
+			resumed = after
+			events := make(chan task.Event, 2)
+			events <- task.Event{ID: 8, Type: task.EventCreated, Task: task.Task{ID: "b", List: "m"}}
+			events <- task.Event{ID: 9, Type: task.EventCreated, Task: task.Task{ID: "a", List: "l"}}
+			go func() {
+				<-ctx.Done()
+				close(events)
+			}()
+			return events, nil
+		},
+		create: func(ctx context.Context, t task.Task) (string, error) {
+			return "a", nil
+		},
+		update: func(ctx context.Context, id string, patch []byte) (*task.Task, error) {
+			if revision, ok := task.ExpectedRevision(ctx); !ok || revision != 3 {
+				return nil, task.ErrRevisionMismatch
+			}
+			return &task.Task{ID: id, Title: "new", Revision: 4}, nil
+		},
+	}, RequireIfMatch()))
+	defer ts.Close()
+
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/_sync", nil)
+	if err != nil {
+		t.Fatal("unexpected error dialing: ", err)
+	}
+	defer ws.Close()
+	roundTrip := func(req string) syncReply {
+		t.Helper()
+		if err := ws.WriteMessage(websocket.TextMessage, []byte(req)); err != nil {
+			t.Fatal("unexpected error writing request: ", err)
+		}
+		var reply syncReply
+		if err := ws.ReadJSON(&reply); err != nil {
+			t.Fatal("unexpected error reading reply: ", err)
+		}
+		return reply
+	}
+
+	// The event may be pushed before the subscription is acknowledged.
+	var got []syncReply
+	got = append(got, roundTrip(`{"id":"1","op":"subscribe","list":"l","after":7}`))
+	var reply syncReply
+	if err := ws.ReadJSON(&reply); err != nil {
+		t.Fatal("unexpected error reading reply: ", err)
+	}
+	got = append(got, reply)
+	if got[0].ID == "" {
+		got[0], got[1] = got[1], got[0]
+	}
+	event := task.Event{ID: 9, Type: task.EventCreated, Task: task.Task{ID: "a", List: "l"}}
+	if expected := []syncReply{{ID: "1", OK: true}, {Subscription: "1", Event: &event}}; !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected %+v but got %+v", expected, got)
+	}
+	if resumed != 7 {
+		t.Errorf("expected to resume after event 7 but got %d", resumed)
+	}
+
+	for _, test := range []struct {
+		req      string
+		expected syncReply
+	}{
+		{`{"id":"2","op":"subscribe","list":"x"}`, syncReply{ID: "2", Status: http.StatusNotFound}},
+		{`{"id":"3","op":"create","task":{"title":"new"}}`, syncReply{ID: "3", OK: true, TaskID: "a", Created: true}},
+		{`{"id":"4","op":"update","task_id":"a","patch":{"title":"new"},"revision":3}`,
+			syncReply{ID: "4", OK: true, Task: &task.Task{ID: "a", Title: "new", Revision: 4}}},
+		{`{"id":"5","op":"update","task_id":"a","patch":{"title":"new"},"revision":2}`,
+			syncReply{ID: "5", Status: http.StatusPreconditionFailed}},
+		{`{"id":"6","op":"delete","task_id":"a"}`, syncReply{ID: "6", Status: http.StatusPreconditionRequired}},
+		{`{"id":"7","op":"archive"}`, syncReply{ID: "7", Status: http.StatusBadRequest}},
+		{`{"id":8}`, syncReply{Status: http.StatusBadRequest}},
+		{`{"id":"9","op":"unsubscribe","subscription":"1"}`, syncReply{ID: "9", OK: true}},
+		{`{"id":"10","op":"unsubscribe","subscription":"1"}`, syncReply{ID: "10", Status: http.StatusNotFound}},
+	} {
+		reply := roundTrip(test.req)
+		if (reply.Error == "") != test.expected.OK {
+			t.Errorf("%s: expected ok %t but got error %q", test.req, test.expected.OK, reply.Error)
+		}
+		reply.Error = ""
+		if !reflect.DeepEqual(reply, test.expected) {
+			t.Errorf("%s: expected %+v but got %+v", test.req, test.expected, reply)
+		}
+	}
+}
+
+type mockTaskInterface struct {
+	get         func(context.Context, string) (*task.Task, error)
+	getAll      func(context.Context) ([]task.Task, error)
+	find        func(context.Context, task.Filter) ([]task.Task, error)
+	list        func(context.Context, task.ListOptions) (*task.Page, error)
+	actionable  func(context.Context, task.Filter) ([]task.Task, error)
+	ordered     func(context.Context, task.Filter) ([]task.Task, error)
+	occurrences func(context.Context, time.Time, time.Time, task.Filter) ([]task.Occurrence, error)
+	tags        func(context.Context) ([]task.TagCount, error)
+	search      func(context.Context, string, task.Filter) ([]task.SearchResult, error)
+	put         func(context.Context, task.Task) (string, bool, error)
+	create      func(context.Context, task.Task) (string, error)
+	update      func(context.Context, string, []byte) (*task.Task, error)
+	setStatus   func(context.Context, string, task.Status) (*task.Task, error)
+	delete      func(context.Context, string) error
+	children    func(context.Context, string) ([]task.Tree, error)
+	delTree     func(context.Context, string) error
+	getList     func(context.Context, string) (*task.TaskList, error)
+	getLists    func(context.Context) ([]task.TaskList, error)
+	putList     func(context.Context, task.TaskList) (string, bool, error)
+	delList     func(context.Context, string) error
+	share       func(context.Context, string, task.Grant) (*task.TaskList, error)
+	unshare     func(context.Context, string, task.Grantee) (*task.TaskList, error)
+	watch       func(context.Context, int64) (<-chan task.Event, error)
+}
+
+func (m *mockTaskInterface) Get(ctx context.Context, id string) (*task.Task, error) {
+	return m.get(ctx, id)
+}
+
+func (m *mockTaskInterface) GetAll(ctx context.Context) ([]task.Task, error) {
+	return m.getAll(ctx)
+}
+
+func (m *mockTaskInterface) Find(ctx context.Context, filter task.Filter) ([]task.Task, error) {
+	return m.find(ctx, filter)
+}
+
+func (m *mockTaskInterface) List(ctx context.Context, options task.ListOptions) (*task.Page, error) {
+	return m.list(ctx, options)
+}
+
+func (m *mockTaskInterface) Actionable(ctx context.Context, filter task.Filter) ([]task.Task, error) {
+	return m.actionable(ctx, filter)
+}
+
+func (m *mockTaskInterface) Ordered(ctx context.Context, filter task.Filter) ([]task.Task, error) {
+	return m.ordered(ctx, filter)
+}
+
+func (m *mockTaskInterface) Occurrences(ctx context.Context, from, to time.Time, filter task.Filter) ([]task.Occurrence, error) {
+	return m.occurrences(ctx, from, to, filter)
+}
+
+func (m *mockTaskInterface) Search(ctx context.Context, query string, filter task.Filter) ([]task.SearchResult, error) {
+	return m.search(ctx, query, filter)
+}
+
+func (m *mockTaskInterface) Tags(ctx context.Context) ([]task.TagCount, error) {
+	return m.tags(ctx)
+}
+
+func (m *mockTaskInterface) Put(ctx context.Context, task task.Task) (string, bool, error) {
+	return m.put(ctx, task)
+}
+
+func (m *mockTaskInterface) Create(ctx context.Context, task task.Task) (string, error) {
+	return m.create(ctx, task)
+}
+
+func (m *mockTaskInterface) Update(ctx context.Context, id string, patch []byte) (*task.Task, error) {
+	return m.update(ctx, id, patch)
+}
+
+func (m *mockTaskInterface) SetStatus(ctx context.Context, id string, status task.Status) (*task.Task, error) {
+	return m.setStatus(ctx, id, status)
+}
+
+func (m *mockTaskInterface) Delete(ctx context.Context, id string) error {
+	return m.delete(ctx, id)
+}
+
+func (m *mockTaskInterface) Children(ctx context.Context, id string) ([]task.Tree, error) {
+	return m.children(ctx, id)
+}
+
+func (m *mockTaskInterface) DeleteTree(ctx context.Context, id string) error {
+	return m.delTree(ctx, id)
+}
+
+func (m *mockTaskInterface) GetList(ctx context.Context, id string) (*task.TaskList, error) {
+	return m.getList(ctx, id)
+}
+
+func (m *mockTaskInterface) GetLists(ctx context.Context) ([]task.TaskList, error) {
+	return m.getLists(ctx)
+}
+
+func (m *mockTaskInterface) PutList(ctx context.Context, list task.TaskList) (string, bool, error) {
+	return m.putList(ctx, list)
 }
 
-func (m *mockTaskInterface) Get(id string) (*task.Task, error) {
-	return m.get(id)
+func (m *mockTaskInterface) DeleteList(ctx context.Context, id string) error {
+	return m.delList(ctx, id)
 }
 
-func (m *mockTaskInterface) GetAll() ([]task.Task, error) {
-	return m.getAll()
+func (m *mockTaskInterface) Share(ctx context.Context, list string, grant task.Grant) (*task.TaskList, error) {
+	return m.share(ctx, list, grant)
 }
 
-func (m *mockTaskInterface) Put(task task.Task) (string, error) {
-	return m.put(task)
+func (m *mockTaskInterface) Unshare(ctx context.Context, list string, grantee task.Grantee) (*task.TaskList, error) {
+	return m.unshare(ctx, list, grantee)
 }
 
-func (m *mockTaskInterface) Delete(id string) error {
-	return m.delete(id)
+func (m *mockTaskInterface) Watch(ctx context.Context, after int64) (<-chan task.Event, error) {
+	return m.watch(ctx, after)
 }
 
 func indexByID(tasks []task.Task) map[string]task.Task {
@@ -0,0 +1,282 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/jmank88/todo/task"
+)
+
+// The syncWriteTimeout is the time allowed to write a message to a sync connection before it is closed.
+const syncWriteTimeout = 10 * time.Second
+
+// A syncRequest is a command sent by a client over a sync connection. The id is chosen by the client, and echoed by the
+// reply, so that replies can be matched to requests.
+type syncRequest struct {
+	ID string `json:"id"`
+	Op string `json:"op"`
+	// The list to subscribe to, or all tasks if empty.
+	List string `json:"list,omitempty"`
+	// The id of the event to resume a subscription after.
+	After int64 `json:"after,omitempty"`
+	// The id of the subscribe request to unsubscribe from.
+	Subscription string `json:"subscription,omitempty"`
+	// The task to put or create.
+	Task *task.Task `json:"task,omitempty"`
+	// The id of the task to update, delete, or set the status of.
+	TaskID string `json:"task_id,omitempty"`
+	// The JSON merge patch to update the task with.
+	Patch json.RawMessage `json:"patch,omitempty"`
+	// The status to set the task to.
+	Status task.Status `json:"status,omitempty"`
+	// The revision the task is expected to be at, like an If-Match header.
+	Revision int64 `json:"revision,omitempty"`
+	// Whether to delete the subtasks of the task too.
+	Cascade bool `json:"cascade,omitempty"`
+}
+
+// A syncReply is a message sent to a client over a sync connection. Replies to requests have the id of the request, and
+// either ok or an error with the http status code it corresponds to. Events have the id of the subscribe request as
+// their subscription, and a subscription which has ended has an error instead.
+type syncReply struct {
+	ID           string      `json:"id,omitempty"`
+	OK           bool        `json:"ok,omitempty"`
+	Error        string      `json:"error,omitempty"`
+	Status       int         `json:"status,omitempty"`
+	TaskID       string      `json:"task_id,omitempty"`
+	Created      bool        `json:"created,omitempty"`
+	Task         *task.Task  `json:"task,omitempty"`
+	Subscription string      `json:"subscription,omitempty"`
+	Event        *task.Event `json:"event,omitempty"`
+}
+
+// A syncConn is a WebSocket connection of a client syncing tasks.
+type syncConn struct {
+	*server
+	ws *websocket.Conn
+
+	// Guards writes to ws, which are made by the request loop, subscriptions, and keep-alives.
+	writeMu sync.Mutex
+
+	// Guards subscriptions.
+	mu sync.Mutex
+	// The subscriptions, by subscribe request id.
+	subscriptions map[string]*subscription
+}
+
+// The upgrader upgrades sync requests to WebSocket connections. Cross-origin requests are rejected.
+var upgrader = websocket.Upgrader{}
+
+// Syncs tasks over a WebSocket connection. Each request is executed in order, as the user of the request which opened
+// the connection, and replied to with its id. Subscriptions push the events of changes to tasks in a list, or to all
+// tasks, until they are unsubscribed or the connection is closed.
+func (s *server) sync(w http.ResponseWriter, r *http.Request) {
+	ws, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader has already responded with an error.
+		return
+	}
+	defer ws.Close()
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	c := &syncConn{server: s, ws: ws, subscriptions: make(map[string]*subscription)}
+	ws.SetReadDeadline(time.Now().Add(2 * keepAlive))
+	ws.SetPongHandler(func(string) error {
+		return ws.SetReadDeadline(time.Now().Add(2 * keepAlive))
+	})
+	go c.keepAlive(ctx)
+
+	for {
+		_, msg, err := ws.ReadMessage()
+		if err != nil {
+			// The connection is closed or broken.
+			return
+		}
+		var reply syncReply
+		var req syncRequest
+		if err := json.Unmarshal(msg, &req); err != nil {
+			reply = syncReply{Error: fmt.Sprintf("failed to deserialize request: %s", err), Status: http.StatusBadRequest}
+		} else {
+			reply = c.handle(ctx, req)
+		}
+		if !c.send(reply) {
+			return
+		}
+	}
+}
+
+// The keepAlive method pings the client until ctx is done, so that proxies do not close the idle connection, and broken
+// connections are detected.
+func (c *syncConn) keepAlive(ctx context.Context) {
+	ticker := time.NewTicker(keepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(syncWriteTimeout)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// The send method writes reply to the client. Returns false if the connection failed.
+func (c *syncConn) send(reply syncReply) bool {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.ws.SetWriteDeadline(time.Now().Add(syncWriteTimeout))
+	return c.ws.WriteJSON(reply) == nil
+}
+
+// The handle method executes req and returns the reply.
+func (c *syncConn) handle(ctx context.Context, req syncRequest) syncReply {
+	if req.ID == "" {
+		return syncReply{Error: "missing request id", Status: http.StatusBadRequest}
+	}
+	reply := syncReply{ID: req.ID}
+	fail := func(err error, status int) syncReply {
+		reply.Error, reply.Status = err.Error(), status
+		return reply
+	}
+	if req.Op == "put" || req.Op == "update" || req.Op == "status" || req.Op == "delete" {
+		if req.Revision == 0 && c.requireIfMatch {
+			return fail(errors.New("a revision is required"), http.StatusPreconditionRequired)
+		}
+	}
+	if req.Revision < 0 {
+		return fail(fmt.Errorf("invalid revision %d", req.Revision), http.StatusBadRequest)
+	} else if req.Revision > 0 {
+		ctx = task.IfMatch(ctx, req.Revision)
+	}
+	switch req.Op {
+	case "subscribe":
+		if err := c.subscribe(ctx, req); err != nil {
+			return fail(fmt.Errorf("failed to subscribe: %w", err), errorStatus(err))
+		}
+	case "unsubscribe":
+		if !c.unsubscribe(req.Subscription, nil) {
+			err := fmt.Errorf("subscription %q: %w", req.Subscription, task.ErrNotFound)
+			return fail(err, errorStatus(err))
+		}
+	case "put", "create":
+		if req.Task == nil {
+			return fail(errors.New("missing task"), http.StatusBadRequest)
+		}
+		var err error
+		if req.Op == "create" {
+			reply.TaskID, err = c.Create(ctx, *req.Task)
+			reply.Created = err == nil
+		} else {
+			reply.TaskID, reply.Created, err = c.Put(ctx, *req.Task)
+		}
+		if err != nil {
+			return fail(fmt.Errorf("failed to store task: %w", err), errorStatus(err))
+		}
+	case "update", "status", "delete":
+		if req.TaskID == "" {
+			return fail(errors.New("missing task_id"), http.StatusBadRequest)
+		}
+		var err error
+		switch req.Op {
+		case "update":
+			if len(req.Patch) == 0 {
+				return fail(errors.New("missing patch"), http.StatusBadRequest)
+			}
+			if reply.Task, err = c.Update(ctx, req.TaskID, req.Patch); err != nil {
+				return fail(fmt.Errorf("failed to update task %s: %w", req.TaskID, err), errorStatus(err))
+			}
+		case "status":
+			if !req.Status.Valid() {
+				return fail(fmt.Errorf("%w: unknown status %q", task.ErrInvalid, req.Status), http.StatusUnprocessableEntity)
+			}
+			if reply.Task, err = c.SetStatus(ctx, req.TaskID, req.Status); err != nil {
+				return fail(fmt.Errorf("failed to set status of task %s: %w", req.TaskID, err), errorStatus(err))
+			}
+		case "delete":
+			del := c.Delete
+			if req.Cascade {
+				del = c.DeleteTree
+			}
+			if err := del(ctx, req.TaskID); err != nil {
+				return fail(fmt.Errorf("failed to delete task %s: %w", req.TaskID, err), errorStatus(err))
+			}
+		}
+	default:
+		return fail(fmt.Errorf("unsupported op %q", req.Op), http.StatusBadRequest)
+	}
+	reply.OK = true
+	return reply
+}
+
+// The subscribe method starts a subscription to the events of the tasks in the list of req, or of all tasks if it has
+// none, resuming after the event with id req.After, if not zero. The subscription is identified by the id of req.
+func (c *syncConn) subscribe(ctx context.Context, req syncRequest) error {
+	if req.List != "" {
+		if _, err := c.GetList(ctx, req.List); err != nil {
+			return err
+		}
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	sub := &subscription{cancel: cancel}
+	c.mu.Lock()
+	_, exists := c.subscriptions[req.ID]
+	if !exists {
+		c.subscriptions[req.ID] = sub
+	}
+	c.mu.Unlock()
+	if exists {
+		cancel()
+		return fmt.Errorf("%w: subscription %q already exists", task.ErrConflict, req.ID)
+	}
+	events, err := c.Watch(ctx, req.After)
+	if err != nil {
+		c.unsubscribe(req.ID, sub)
+		return err
+	}
+
+	go func() {
+		for e := range events {
+			if req.List != "" && e.Task.List != req.List {
+				continue
+			}
+			e := e
+			if !c.send(syncReply{Subscription: req.ID, Event: &e}) {
+				break
+			}
+		}
+		// If the subscription was not cancelled, the watch ended, so the client must resubscribe.
+		ended := ctx.Err() == nil
+		if c.unsubscribe(req.ID, sub) && ended {
+			c.send(syncReply{Subscription: req.ID, Error: "subscription ended", Status: http.StatusGone})
+		}
+	}()
+	return nil
+}
+
+// A subscription is a subscription of a sync connection to events.
+type subscription struct {
+	cancel context.CancelFunc
+}
+
+// The unsubscribe method cancels the subscription with the given id, if it is sub, or any subscription if sub is nil.
+// Returns false if there is no such subscription.
+func (c *syncConn) unsubscribe(id string, sub *subscription) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s, ok := c.subscriptions[id]
+	if !ok || sub != nil && s != sub {
+		return false
+	}
+	delete(c.subscriptions, id)
+	s.cancel()
+	return true
+}
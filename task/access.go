@@ -0,0 +1,181 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// A Role is the access a user has to a TaskList, and its tasks. Each role allows everything the roles before it do.
+type Role string
+
+// The Roles.
+const (
+	// RoleViewer may read a list, and its tasks.
+	RoleViewer Role = "viewer"
+	// RoleEditor may also create, change, and delete the tasks of a list, and rename it.
+	RoleEditor Role = "editor"
+	// RoleOwner may also share, unshare, and delete a list.
+	RoleOwner Role = "owner"
+)
+
+// The rank method returns the order of r among the roles, or 0 if r is not a role.
+func (r Role) rank() int {
+	switch r {
+	case RoleViewer:
+		return 1
+	case RoleEditor:
+		return 2
+	case RoleOwner:
+		return 3
+	default:
+		return 0
+	}
+}
+
+// The Valid method returns true if r is one of the roles.
+func (r Role) Valid() bool {
+	return r.rank() > 0
+}
+
+// The Allows method returns true if r allows everything other does.
+func (r Role) Allows(other Role) bool {
+	return r.rank() >= other.rank()
+}
+
+// The Authorize function returns nil if the role have allows the role want, an error wrapping ErrNotFound if have is
+// empty, since the user may not even see the task or list, or ErrForbidden otherwise.
+func Authorize(have, want Role) error {
+	switch {
+	case have.Allows(want):
+		return nil
+	case have == "":
+		return ErrNotFound
+	default:
+		return fmt.Errorf("%w: %s role required, but have %s", ErrForbidden, want, have)
+	}
+}
+
+// A Grantee is the user, or group of users, a list is shared with. Exactly one field is set.
+type Grantee struct {
+	User  string `json:"user,omitempty"`
+	Group string `json:"group,omitempty"`
+}
+
+// The Validate method returns an error wrapping ErrInvalid unless exactly one of g's fields is set.
+func (g Grantee) Validate() error {
+	if (g.User == "") == (g.Group == "") {
+		return fmt.Errorf("%w: grantee must have either a user or a group", ErrInvalid)
+	}
+	return nil
+}
+
+// The String method returns "user:<user>" or "group:<group>".
+func (g Grantee) String() string {
+	if g.Group != "" {
+		return "group:" + g.Group
+	}
+	return "user:" + g.User
+}
+
+// A Grant gives a Grantee a Role on a list.
+type Grant struct {
+	Grantee
+	Role Role `json:"role"`
+}
+
+// The Validate method returns an error wrapping ErrInvalid if g does not have a valid grantee, and a role other than
+// owner, since each list has a single owner.
+func (g Grant) Validate() error {
+	if err := g.Grantee.Validate(); err != nil {
+		return err
+	}
+	if g.Role != RoleViewer && g.Role != RoleEditor {
+		return fmt.Errorf("%w: unknown role %q for %s. must be %q or %q", ErrInvalid, g.Role, g.Grantee, RoleViewer, RoleEditor)
+	}
+	return nil
+}
+
+// The NormalizeGrants function returns grants ordered with users before groups, each by name, or nil if there are none.
+func NormalizeGrants(grants []Grant) []Grant {
+	if len(grants) == 0 {
+		return nil
+	}
+	sorted := append([]Grant(nil), grants...)
+	sort.Slice(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if (a.Group == "") != (b.Group == "") {
+			return a.Group == ""
+		}
+		return a.User+a.Group < b.User+b.Group
+	})
+	return sorted
+}
+
+// The Share function returns grants with grant added, replacing any grant to the same grantee, normalized.
+func Share(grants []Grant, grant Grant) []Grant {
+	return NormalizeGrants(append(Unshare(grants, grant.Grantee), grant))
+}
+
+// The Unshare function returns grants without the grant to grantee, normalized.
+func Unshare(grants []Grant, grantee Grantee) []Grant {
+	var kept []Grant
+	for _, g := range grants {
+		if g.Grantee != grantee {
+			kept = append(kept, g)
+		}
+	}
+	return NormalizeGrants(kept)
+}
+
+// A groupsKey is the context.Context key of the groups of the user making a request.
+type groupsKey struct{}
+
+// The WithGroups function returns a copy of ctx carrying groups, the names of the groups the user of ctx belongs to.
+func WithGroups(ctx context.Context, groups []string) context.Context {
+	return context.WithValue(ctx, groupsKey{}, groups)
+}
+
+// The Groups function returns the groups carried by ctx, or nil if there are none. See WithGroups.
+func Groups(ctx context.Context) []string {
+	groups, _ := ctx.Value(groupsKey{}).([]string)
+	return groups
+}
+
+// The ListRole function returns the role of the user of ctx on l: RoleOwner if l is Visible to ctx, otherwise the
+// greatest role granted to the user, or to one of their groups, or empty if there is none.
+func ListRole(ctx context.Context, l TaskList) Role {
+	if Visible(ctx, l.Owner) {
+		return RoleOwner
+	}
+	user, groups := User(ctx), Groups(ctx)
+	var role Role
+	for _, g := range l.Grants {
+		if g.Role.rank() > role.rank() && (g.User != "" && g.User == user || g.Group != "" && contains(groups, g.Group)) {
+			role = g.Role
+		}
+	}
+	return role
+}
+
+// The TaskRole function returns the role of the user of ctx on t, which belongs to list, or nil if it belongs to no
+// list: RoleOwner if t is Visible to ctx, otherwise their ListRole on list, or empty if there is none.
+func TaskRole(ctx context.Context, t Task, list *TaskList) Role {
+	if Visible(ctx, t.Owner) {
+		return RoleOwner
+	}
+	if list == nil {
+		return ""
+	}
+	return ListRole(ctx, *list)
+}
+
+// The contains function returns true if s is one of ss.
+func contains(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// Tests that roles are authorized in order, and that no role at all hides the task or list.
+func TestAuthorize(t *testing.T) {
+	for _, test := range []struct {
+		have, want Role
+		expected   error
+	}{
+		{RoleOwner, RoleOwner, nil},
+		{RoleOwner, RoleViewer, nil},
+		{RoleEditor, RoleEditor, nil},
+		{RoleEditor, RoleOwner, ErrForbidden},
+		{RoleViewer, RoleEditor, ErrForbidden},
+		{"", RoleViewer, ErrNotFound},
+	} {
+		if err := Authorize(test.have, test.want); !errors.Is(err, test.expected) || err != nil && test.expected == nil {
+			t.Errorf("%q for %q: expected %v but got %v", test.have, test.want, test.expected, err)
+		}
+	}
+}
+
+// Tests sharing and unsharing grants, and validating them.
+func TestShare(t *testing.T) {
+	bob := Grant{Grantee: Grantee{User: "bob"}, Role: RoleViewer}
+	team := Grant{Grantee: Grantee{Group: "team"}, Role: RoleEditor}
+	carol := Grant{Grantee: Grantee{User: "carol"}, Role: RoleEditor}
+
+	grants := Share(Share(Share(nil, team), carol), bob)
+	if expected := []Grant{bob, carol, team}; !reflect.DeepEqual(grants, expected) {
+		t.Fatalf("expected %v but got %v", expected, grants)
+	}
+	promoted := Grant{Grantee: bob.Grantee, Role: RoleEditor}
+	grants = Share(grants, promoted)
+	if expected := []Grant{promoted, carol, team}; !reflect.DeepEqual(grants, expected) {
+		t.Fatalf("expected %v but got %v", expected, grants)
+	}
+	grants = Unshare(Unshare(grants, carol.Grantee), team.Grantee)
+	if expected := []Grant{promoted}; !reflect.DeepEqual(grants, expected) {
+		t.Fatalf("expected %v but got %v", expected, grants)
+	}
+	if grants = Unshare(grants, bob.Grantee); grants != nil {
+		t.Fatalf("expected no grants but got %v", grants)
+	}
+
+	for _, g := range []Grant{
+		{Grantee: Grantee{User: "bob"}, Role: RoleOwner},
+		{Grantee: Grantee{User: "bob"}, Role: "admin"},
+		{Grantee: Grantee{User: "bob", Group: "team"}, Role: RoleViewer},
+		{Role: RoleViewer},
+	} {
+		if err := g.Validate(); !errors.Is(err, ErrInvalid) {
+			t.Errorf("%v: expected %v but got %v", g, ErrInvalid, err)
+		}
+	}
+}
+
+// Tests the roles of owners, grantees, group members, admins, and strangers on a list and its tasks.
+func TestRoles(t *testing.T) {
+	l := TaskList{ID: "l", Owner: "alice", Grants: []Grant{
+		{Grantee: Grantee{User: "bob"}, Role: RoleViewer},
+		{Grantee: Grantee{Group: "team"}, Role: RoleEditor},
+	}}
+	ctx := context.Background()
+	for _, test := range []struct {
+		name     string
+		ctx      context.Context
+		expected Role
+	}{
+		{"owner", WithUser(ctx, "alice"), RoleOwner},
+		{"viewer", WithUser(ctx, "bob"), RoleViewer},
+		{"viewer in group", WithGroups(WithUser(ctx, "bob"), []string{"team"}), RoleEditor},
+		{"group member", WithGroups(WithUser(ctx, "carol"), []string{"ops", "team"}), RoleEditor},
+		{"admin", AsAdmin(WithUser(ctx, "root")), RoleOwner},
+		{"unscoped", ctx, RoleOwner},
+		{"stranger", WithGroups(WithUser(ctx, "dave"), []string{"ops"}), ""},
+	} {
+		if got := ListRole(test.ctx, l); got != test.expected {
+			t.Errorf("%s: expected list role %q but got %q", test.name, test.expected, got)
+		}
+		if got := TaskRole(test.ctx, Task{ID: "a", Owner: "alice", List: "l"}, &l); got != test.expected {
+			t.Errorf("%s: expected task role %q but got %q", test.name, test.expected, got)
+		}
+	}
+
+	dave := WithUser(ctx, "dave")
+	if got := TaskRole(dave, Task{ID: "d", Owner: "dave", List: "l"}, &l); got != RoleOwner {
+		t.Errorf("expected the owner of a task in the list to own it but got %q", got)
+	}
+	if got := TaskRole(WithUser(ctx, "bob"), Task{ID: "a", Owner: "alice"}, nil); got != "" {
+		t.Errorf("expected no role on a task in no list but got %q", got)
+	}
+}
@@ -0,0 +1,127 @@
+package task
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// The NormalizeIDs function returns ids sorted, and without duplicates, or nil if there are none. Tasks' BlockedBy are
+// sets, so stores normalize them before writing.
+func NormalizeIDs(ids []string) []string {
+	return NormalizeTags(ids)
+}
+
+// The Blocks method returns true if the task with id blocks t.
+func (t *Task) Blocks(id string) bool {
+	i := sort.SearchStrings(t.BlockedBy, id)
+	return i < len(t.BlockedBy) && t.BlockedBy[i] == id
+}
+
+// The CheckBlockers function returns an error wrapping ErrInvalid if t is blocked by a task which does not exist, or
+// ErrCycle if t would depend on itself, through the tasks blocking it. The blockers function looks up the BlockedBy of
+// a task, and whether the task exists.
+func CheckBlockers(t Task, blockers func(id string) ([]string, bool)) error {
+	for _, b := range t.BlockedBy {
+		if _, ok := blockers(b); !ok && b != t.ID {
+			return fmt.Errorf("%w: unknown blocker %q", ErrInvalid, b)
+		}
+	}
+	// Search depth first from each blocker, for a path back to t.
+	done := make(map[string]bool)
+	var path []string
+	var search func(id string) bool
+	search = func(id string) bool {
+		path = append(path, id)
+		if id == t.ID {
+			return true
+		}
+		if !done[id] {
+			done[id] = true
+			next, _ := blockers(id)
+			for _, n := range next {
+				if search(n) {
+					return true
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		return false
+	}
+	for _, b := range t.BlockedBy {
+		if search(b) {
+			return fmt.Errorf("%w: %s", ErrCycle, strings.Join(append([]string{t.ID}, path...), " -> "))
+		}
+	}
+	return nil
+}
+
+// The ActionableTasks function returns the tasks which are not closed, and whose blockers are all closed, in the same
+// order. Blockers must be included in tasks, and blockers which are not are ignored.
+func ActionableTasks(tasks []Task) []Task {
+	closed := make(map[string]bool, len(tasks))
+	for _, t := range tasks {
+		closed[t.ID] = t.Status.Closed()
+	}
+	var actionable []Task
+	for _, t := range tasks {
+		if closed[t.ID] {
+			continue
+		}
+		blocked := false
+		for _, b := range t.BlockedBy {
+			if c, ok := closed[b]; ok && !c {
+				blocked = true
+				break
+			}
+		}
+		if !blocked {
+			actionable = append(actionable, t)
+		}
+	}
+	return actionable
+}
+
+// The OrderTasks function returns tasks ordered so that every task follows the tasks blocking it. Otherwise, tasks are
+// ordered by id. Blockers which are not included in tasks are ignored. Returns an error wrapping ErrCycle if the
+// blockers form a cycle.
+func OrderTasks(tasks []Task) ([]Task, error) {
+	byID := make(map[string]Task, len(tasks))
+	for _, t := range tasks {
+		byID[t.ID] = t
+	}
+	// Kahn's algorithm, taking the least ready id each time.
+	waiting := make(map[string]int, len(tasks))
+	blocks := make(map[string][]string)
+	var ready []string
+	for _, t := range tasks {
+		for _, b := range t.BlockedBy {
+			if _, ok := byID[b]; ok {
+				waiting[t.ID]++
+				blocks[b] = append(blocks[b], t.ID)
+			}
+		}
+		if waiting[t.ID] == 0 {
+			ready = append(ready, t.ID)
+		}
+	}
+	sort.Strings(ready)
+	ordered := make([]Task, 0, len(tasks))
+	for len(ready) > 0 {
+		id := ready[0]
+		ready = ready[1:]
+		ordered = append(ordered, byID[id])
+		for _, blocked := range blocks[id] {
+			if waiting[blocked]--; waiting[blocked] == 0 {
+				i := sort.SearchStrings(ready, blocked)
+				ready = append(ready, "")
+				copy(ready[i+1:], ready[i:])
+				ready[i] = blocked
+			}
+		}
+	}
+	if len(ordered) < len(byID) {
+		return nil, fmt.Errorf("%w: %d tasks are blocked by each other", ErrCycle, len(byID)-len(ordered))
+	}
+	return ordered, nil
+}
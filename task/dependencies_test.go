@@ -0,0 +1,75 @@
+package task
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// Tests that unknown blockers are rejected, and blockers which would form a cycle are rejected with ErrCycle.
+func TestCheckBlockers(t *testing.T) {
+	graph := map[string][]string{"a": nil, "b": {"a"}, "c": {"b"}}
+	blockers := func(id string) ([]string, bool) {
+		b, ok := graph[id]
+		return b, ok
+	}
+	for _, tk := range []Task{{ID: "new"}, {ID: "new", BlockedBy: []string{"a", "c"}}, {ID: "c", BlockedBy: []string{"a"}}} {
+		if err := CheckBlockers(tk, blockers); err != nil {
+			t.Errorf("unexpected error for %v: %s", tk, err)
+		}
+	}
+	if err := CheckBlockers(Task{ID: "new", BlockedBy: []string{"missing"}}, blockers); !errors.Is(err, ErrInvalid) || errors.Is(err, ErrCycle) {
+		t.Errorf("expected %v but got %v", ErrInvalid, err)
+	}
+	err := CheckBlockers(Task{ID: "a", BlockedBy: []string{"c"}}, blockers)
+	if !errors.Is(err, ErrCycle) || !errors.Is(err, ErrInvalid) {
+		t.Fatalf("expected %v but got %v", ErrCycle, err)
+	}
+	if expected := "invalid task: dependency cycle: a -> c -> b -> a"; err.Error() != expected {
+		t.Errorf("expected %q but got %q", expected, err)
+	}
+}
+
+// Tests that only open tasks without open blockers are actionable.
+func TestActionableTasks(t *testing.T) {
+	tasks := []Task{
+		{ID: "a", Status: StatusDone},
+		{ID: "b", BlockedBy: []string{"a"}},
+		{ID: "c", BlockedBy: []string{"b"}},
+		{ID: "d", Status: StatusCancelled, BlockedBy: []string{"b"}},
+		{ID: "e", BlockedBy: []string{"missing"}},
+	}
+	if got := ids(ActionableTasks(tasks)); !reflect.DeepEqual(got, []string{"b", "e"}) {
+		t.Fatalf("expected [b e] but got %v", got)
+	}
+}
+
+// Tests that tasks are ordered after their blockers, and otherwise by id, and that cycles are detected.
+func TestOrderTasks(t *testing.T) {
+	tasks := []Task{
+		{ID: "a", BlockedBy: []string{"d"}},
+		{ID: "b"},
+		{ID: "c", BlockedBy: []string{"a", "b"}},
+		{ID: "d"},
+		{ID: "e", BlockedBy: []string{"missing"}},
+	}
+	if got, err := OrderTasks(tasks); err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if got := ids(got); !reflect.DeepEqual(got, []string{"b", "d", "a", "c", "e"}) {
+		t.Fatalf("expected [b d a c e] but got %v", got)
+	}
+
+	tasks[3].BlockedBy = []string{"c"}
+	if _, err := OrderTasks(tasks); !errors.Is(err, ErrCycle) {
+		t.Fatalf("expected %v but got %v", ErrCycle, err)
+	}
+}
+
+// The ids function returns the ids of tasks.
+func ids(tasks []Task) []string {
+	ids := make([]string, len(tasks))
+	for i := range tasks {
+		ids[i] = tasks[i].ID
+	}
+	return ids
+}
@@ -0,0 +1,37 @@
+package task
+
+import (
+	"errors"
+	"fmt"
+)
+
+// The TaskInterface errors. Implementations wrap these errors with additional context, so they should be checked with
+// errors.Is.
+var (
+	// ErrNotFound is returned when no task or list exists with the requested id.
+	ErrNotFound = errors.New("task not found")
+
+	// ErrConflict is returned when creating a task with the id of an existing task.
+	ErrConflict = errors.New("task already exists")
+
+	// ErrInvalid is returned when a task, list, id, or patch is malformed, or fails validation.
+	ErrInvalid = errors.New("invalid task")
+
+	// ErrListsUnsupported is returned by implementations which cannot store lists, when a list is written.
+	ErrListsUnsupported = errors.New("lists not supported")
+
+	// ErrRevisionMismatch is returned when a conditional write expects a different revision of a task. See IfMatch.
+	ErrRevisionMismatch = errors.New("task revision mismatch")
+
+	// ErrUnauthenticated is returned when a request has no credentials, or invalid ones.
+	ErrUnauthenticated = errors.New("unauthenticated")
+
+	// ErrForbidden is returned when a user may see a task or list, but their Role does not allow the change requested.
+	ErrForbidden = errors.New("forbidden")
+
+	// ErrExpired is returned when watching for events after one which is no longer retained. See Watch.
+	ErrExpired = errors.New("events expired")
+
+	// ErrCycle is returned when a task would depend on itself, through the tasks blocking it. It wraps ErrInvalid.
+	ErrCycle = fmt.Errorf("%w: dependency cycle", ErrInvalid)
+)
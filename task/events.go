@@ -0,0 +1,132 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// An EventType is the kind of change an Event describes.
+type EventType string
+
+// The EventTypes.
+const (
+	EventCreated EventType = "created"
+	EventUpdated EventType = "updated"
+	EventDeleted EventType = "deleted"
+)
+
+// An Event describes a change to a task. See Watch.
+type Event struct {
+
+	// ID identifies this event. The ids of later events are greater.
+	ID int64 `json:"id"`
+
+	// Type is the kind of change.
+	Type EventType `json:"type"`
+
+	// Task is the task after the change, or before it was deleted.
+	Task Task `json:"task"`
+}
+
+// DefaultFeedSize is the number of events a Feed retains by default.
+const DefaultFeedSize = 1000
+
+// A Feed broadcasts Events in process, for stores without a change feed of their own. It retains its latest events, so
+// that watchers may resume after disconnecting. The ids of its events start from the time it was created, in
+// microseconds, so that they keep increasing when a store is restarted with a new Feed. It is safe for concurrent use.
+type Feed struct {
+	mu       sync.Mutex
+	size     int
+	last     int64
+	history  []Event
+	watchers map[chan Event]struct{}
+}
+
+// The NewFeed function returns a Feed which retains the latest size events.
+func NewFeed(size int) *Feed {
+	return &Feed{size: size, last: time.Now().UnixNano() / int64(time.Microsecond), watchers: make(map[chan Event]struct{})}
+}
+
+// The Publish method records an event of typ for t, and delivers it to the watchers. It never blocks. Watchers which
+// fall too far behind are dropped instead.
+func (f *Feed) Publish(typ EventType, t Task) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.last++
+	e := Event{ID: f.last, Type: typ, Task: t}
+	f.history = append(f.history, e)
+	if len(f.history) >= 2*f.size {
+		// Copy, rather than reslicing, so that expired events are freed.
+		f.history = append([]Event(nil), f.history[len(f.history)-f.size:]...)
+	}
+	for in := range f.watchers {
+		select {
+		case in <- e:
+		default:
+			delete(f.watchers, in)
+			close(in)
+		}
+	}
+}
+
+// The Watch method implements Watch for the events published to f, for which visible returns true. The function visible
+// is called without f's lock held.
+func (f *Feed) Watch(ctx context.Context, after int64, visible func(Event) bool) (<-chan Event, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	history := f.history
+	if len(history) > f.size {
+		history = history[len(history)-f.size:]
+	}
+	i := len(history)
+	if after != 0 {
+		i = sort.Search(len(history), func(i int) bool { return history[i].ID > after })
+		if i == 0 || history[i-1].ID != after {
+			f.mu.Unlock()
+			return nil, fmt.Errorf("failed to watch after event %d: %w", after, ErrExpired)
+		}
+	}
+	in := make(chan Event, 2*f.size)
+	for _, e := range history[i:] {
+		in <- e
+	}
+	f.watchers[in] = struct{}{}
+	f.mu.Unlock()
+
+	out := make(chan Event)
+	go func() {
+		defer close(out)
+		defer f.remove(in)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case e, ok := <-in:
+				if !ok {
+					return
+				}
+				if !visible(e) {
+					continue
+				}
+				select {
+				case out <- e:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out, nil
+}
+
+// The remove method stops delivering events to in, unless it was already dropped.
+func (f *Feed) remove(in chan Event) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.watchers, in)
+}
@@ -0,0 +1,46 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// Tests that a Feed only resumes after the events it retains, and drops watchers which fall too far behind.
+func TestFeed(t *testing.T) {
+	f := NewFeed(2)
+	all := func(Event) bool { return true }
+	for _, id := range []string{"a", "b", "c"} {
+		f.Publish(EventCreated, Task{ID: id})
+	}
+	first := f.history[0].ID
+	if _, err := f.Watch(context.Background(), first, all); !errors.Is(err, ErrExpired) {
+		t.Fatalf("expected %v but got %v", ErrExpired, err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := f.Watch(ctx, first+1, all)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if e := <-events; e.ID != first+2 || e.Task.ID != "c" {
+		t.Fatalf("expected event %d for %q but got %d for %q", first+2, "c", e.ID, e.Task.ID)
+	}
+
+	hidden := func(e Event) bool { return e.Task.ID != "hidden" }
+	slow, err := f.Watch(ctx, 0, hidden)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	// The watcher buffers four events, and may hold one more, so the last event drops it either way.
+	for _, id := range []string{"hidden", "d", "e", "f", "g", "h", "i"} {
+		f.Publish(EventUpdated, Task{ID: id})
+	}
+	var got []string
+	for e := range slow {
+		got = append(got, e.Task.ID)
+	}
+	if len(got) == 0 || len(got) == 6 || got[0] != "d" {
+		t.Fatalf("expected the buffered events after %q, and then to be dropped, but got %v", "hidden", got)
+	}
+}
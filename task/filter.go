@@ -0,0 +1,121 @@
+package task
+
+import "time"
+
+// A Filter restricts which tasks are listed. The zero Filter matches all tasks.
+type Filter struct {
+
+	// Overdue restricts to tasks which are past due, and not yet done or cancelled.
+	Overdue bool
+
+	// DueBefore restricts to tasks due strictly before this time.
+	DueBefore *time.Time
+
+	// DueAfter restricts to tasks due at or after this time.
+	DueAfter *time.Time
+
+	// Statuses restricts to tasks with any of these statuses.
+	Statuses []Status
+
+	// Priorities restricts to tasks with any of these priorities. The empty priority matches tasks without one.
+	Priorities []Priority
+
+	// Tags restricts to tasks with any of these tags, or with all of them if AllTags is set.
+	Tags []string
+
+	// AllTags restricts to tasks with all of Tags, rather than any.
+	AllTags bool
+
+	// List restricts to tasks in the TaskList with this id.
+	List string
+
+	// Parent restricts to the direct subtasks of the task with this id.
+	Parent string
+
+	// UpdatedSince restricts to tasks last written at or after this time.
+	UpdatedSince *time.Time
+}
+
+// The DueOn function returns a Filter matching tasks due on the same calendar day as day, in day's location.
+func DueOn(day time.Time) Filter {
+	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	end := start.AddDate(0, 0, 1)
+	return Filter{DueAfter: &start, DueBefore: &end}
+}
+
+// The Match method returns true if t satisfies f at time now.
+func (f Filter) Match(t Task, now time.Time) bool {
+	if f.Overdue && !t.Overdue(now) {
+		return false
+	}
+	if f.DueBefore != nil && (t.Due == nil || !t.Due.Before(*f.DueBefore)) {
+		return false
+	}
+	if f.DueAfter != nil && (t.Due == nil || t.Due.Before(*f.DueAfter)) {
+		return false
+	}
+	if len(f.Statuses) > 0 && !f.matchStatus(t) {
+		return false
+	}
+	if len(f.Priorities) > 0 && !f.matchPriority(t) {
+		return false
+	}
+	if len(f.Tags) > 0 && !f.matchTags(t) {
+		return false
+	}
+	if f.List != "" && t.List != f.List {
+		return false
+	}
+	if f.Parent != "" && t.Parent != f.Parent {
+		return false
+	}
+	if f.UpdatedSince != nil && (t.UpdatedAt == nil || t.UpdatedAt.Before(*f.UpdatedSince)) {
+		return false
+	}
+	return true
+}
+
+// The Apply method returns the tasks which satisfy f at time now, in the same order.
+func (f Filter) Apply(tasks []Task, now time.Time) []Task {
+	var matched []Task
+	for _, t := range tasks {
+		if f.Match(t, now) {
+			matched = append(matched, t)
+		}
+	}
+	return matched
+}
+
+// The matchStatus method returns true if t has any of f.Statuses. The empty status is treated as StatusOpen.
+func (f Filter) matchStatus(t Task) bool {
+	status := t.Status
+	if status == "" {
+		status = StatusOpen
+	}
+	for _, s := range f.Statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// The matchPriority method returns true if t has any of f.Priorities.
+func (f Filter) matchPriority(t Task) bool {
+	for _, p := range f.Priorities {
+		if p == t.Priority {
+			return true
+		}
+	}
+	return false
+}
+
+// The matchTags method returns true if t has any of f.Tags, or all of them if f.AllTags is set.
+func (f Filter) matchTags(t Task) bool {
+	for _, tag := range f.Tags {
+		if t.HasTag(tag) != f.AllTags {
+			return !f.AllTags
+		}
+	}
+	return f.AllTags
+}
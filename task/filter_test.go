@@ -0,0 +1,52 @@
+package task
+
+import (
+	"testing"
+	"time"
+)
+
+// Tests matching tasks against filters.
+func TestFilterMatch(t *testing.T) {
+	now := time.Date(2016, 1, 2, 12, 0, 0, 0, time.UTC)
+	yesterday := now.AddDate(0, 0, -1)
+	tonight := now.Add(6 * time.Hour)
+
+	for _, test := range []struct {
+		name   string
+		filter Filter
+		task   Task
+		match  bool
+	}{
+		{"empty", Filter{}, Task{}, true},
+		{"overdue", Filter{Overdue: true}, Task{Due: &yesterday}, true},
+		{"overdue done", Filter{Overdue: true}, Task{Due: &yesterday, Status: StatusDone}, false},
+		{"overdue no due", Filter{Overdue: true}, Task{}, false},
+		{"overdue future", Filter{Overdue: true}, Task{Due: &tonight}, false},
+		{"due today", DueOn(now), Task{Due: &tonight}, true},
+		{"due today yesterday", DueOn(now), Task{Due: &yesterday}, false},
+		{"due before no due", Filter{DueBefore: &now}, Task{}, false},
+		{"due after inclusive", Filter{DueAfter: &now}, Task{Due: &now}, true},
+		{"due before exclusive", Filter{DueBefore: &now}, Task{Due: &now}, false},
+		{"status", Filter{Statuses: []Status{StatusOpen, StatusInProgress}}, Task{Status: StatusInProgress}, true},
+		{"status empty is open", Filter{Statuses: []Status{StatusOpen}}, Task{}, true},
+		{"status closed", Filter{Statuses: []Status{StatusOpen}}, Task{Status: StatusDone}, false},
+		{"priority", Filter{Priorities: []Priority{PriorityP0, PriorityP1}}, Task{Priority: PriorityP1}, true},
+		{"priority lower", Filter{Priorities: []Priority{PriorityP0, PriorityP1}}, Task{Priority: PriorityP2}, false},
+		{"priority none", Filter{Priorities: []Priority{""}}, Task{}, true},
+		{"parent", Filter{Parent: "a"}, Task{Parent: "a"}, true},
+		{"other parent", Filter{Parent: "a"}, Task{Parent: "b"}, false},
+		{"updated since inclusive", Filter{UpdatedSince: &now}, Task{UpdatedAt: &now}, true},
+		{"updated before", Filter{UpdatedSince: &now}, Task{UpdatedAt: &yesterday}, false},
+		{"updated since never", Filter{UpdatedSince: &now}, Task{}, false},
+		{"list", Filter{List: "work"}, Task{List: "work"}, true},
+		{"other list", Filter{List: "work"}, Task{}, false},
+		{"any tag", Filter{Tags: []string{"ops", "home"}}, Task{Tags: []string{"home"}}, true},
+		{"any tag none", Filter{Tags: []string{"ops", "home"}}, Task{Tags: []string{"work"}}, false},
+		{"all tags", Filter{Tags: []string{"ops", "home"}, AllTags: true}, Task{Tags: []string{"home", "ops", "work"}}, true},
+		{"all tags missing", Filter{Tags: []string{"ops", "home"}, AllTags: true}, Task{Tags: []string{"home"}}, false},
+	} {
+		if got := test.filter.Match(test.task, now); got != test.match {
+			t.Errorf("%s: expected %t but got %t", test.name, test.match, got)
+		}
+	}
+}
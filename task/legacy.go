@@ -0,0 +1,521 @@
+package task
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// The LegacyTaskInterface is the TaskInterface as it was before contexts were added, with the same methods but without
+// context.Context arguments. The Legacy and FromLegacy functions adapt between the two.
+type LegacyTaskInterface interface {
+	Get(id string) (*Task, error)
+	GetAll() ([]Task, error)
+	Find(Filter) ([]Task, error)
+	Put(Task) (id string, created bool, err error)
+	Create(Task) (id string, err error)
+	Update(id string, patch []byte) (*Task, error)
+	SetStatus(id string, status Status) (*Task, error)
+	Delete(id string) error
+}
+
+// The Legacy function adapts ti to a LegacyTaskInterface, which calls ti with context.Background().
+func Legacy(ti TaskInterface) LegacyTaskInterface {
+	return &legacy{ti}
+}
+
+// A legacy implements LegacyTaskInterface by calling a TaskInterface with context.Background().
+type legacy struct {
+	ti TaskInterface
+}
+
+func (l *legacy) Get(id string) (*Task, error) {
+	return l.ti.Get(context.Background(), id)
+}
+
+func (l *legacy) GetAll() ([]Task, error) {
+	return l.ti.GetAll(context.Background())
+}
+
+func (l *legacy) Find(filter Filter) ([]Task, error) {
+	return l.ti.Find(context.Background(), filter)
+}
+
+func (l *legacy) Put(task Task) (string, bool, error) {
+	return l.ti.Put(context.Background(), task)
+}
+
+func (l *legacy) Create(task Task) (string, error) {
+	return l.ti.Create(context.Background(), task)
+}
+
+func (l *legacy) Update(id string, patch []byte) (*Task, error) {
+	return l.ti.Update(context.Background(), id, patch)
+}
+
+func (l *legacy) SetStatus(id string, status Status) (*Task, error) {
+	return l.ti.SetStatus(context.Background(), id, status)
+}
+
+func (l *legacy) Delete(id string) error {
+	return l.ti.Delete(context.Background(), id)
+}
+
+// The FromLegacy function adapts lti to a TaskInterface. Contexts are only checked before calling lti, since lti cannot
+// be cancelled once called. Tasks are stamped by lti, which has no context, so their CreatedBy is never recorded.
+// Revisions of conditional writes are checked before calling lti, which has no transactions, so a task may still change
+// before it is written. Likewise, tasks are scoped to the user of ctx around calls to lti, which stores their owners,
+// but cannot check them. See Scope. Only changes made through the returned TaskInterface are watched.
+func FromLegacy(lti LegacyTaskInterface) TaskInterface {
+	return &fromLegacy{lti: lti, feed: NewFeed(DefaultFeedSize)}
+}
+
+// A fromLegacy implements TaskInterface by calling a LegacyTaskInterface.
+type fromLegacy struct {
+	lti  LegacyTaskInterface
+	feed *Feed
+}
+
+func (f *fromLegacy) Get(ctx context.Context, id string) (*Task, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return f.get(ctx, id)
+}
+
+// The get method gets the task with the given id from lti, and returns an error wrapping ErrNotFound if it is not
+// visible to ctx.
+func (f *fromLegacy) get(ctx context.Context, id string) (*Task, error) {
+	t, err := f.lti.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if !Visible(ctx, t.Owner) {
+		return nil, fmt.Errorf("failed to get task %q: %w", id, ErrNotFound)
+	}
+	return t, nil
+}
+
+func (f *fromLegacy) GetAll(ctx context.Context) ([]Task, error) {
+	return f.Find(ctx, Filter{})
+}
+
+func (f *fromLegacy) Find(ctx context.Context, filter Filter) ([]Task, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	tasks, err := f.lti.Find(filter)
+	if err != nil {
+		return nil, err
+	}
+	return visibleTasks(ctx, tasks), nil
+}
+
+// The visibleTasks function returns the tasks visible to ctx, in order.
+func visibleTasks(ctx context.Context, tasks []Task) []Task {
+	var visible []Task
+	for _, t := range tasks {
+		if Visible(ctx, t.Owner) {
+			visible = append(visible, t)
+		}
+	}
+	return visible
+}
+
+func (f *fromLegacy) List(ctx context.Context, options ListOptions) (*Page, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	tasks, err := f.Find(ctx, options.Filter)
+	if err != nil {
+		return nil, err
+	}
+	return Paginate(tasks, options, time.Now())
+}
+
+func (f *fromLegacy) Actionable(ctx context.Context, filter Filter) ([]Task, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	tasks, err := f.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return filter.Apply(ActionableTasks(tasks), time.Now()), nil
+}
+
+func (f *fromLegacy) Ordered(ctx context.Context, filter Filter) ([]Task, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	tasks, err := f.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if tasks, err = OrderTasks(tasks); err != nil {
+		return nil, err
+	}
+	return filter.Apply(tasks, time.Now()), nil
+}
+
+func (f *fromLegacy) Occurrences(ctx context.Context, from, to time.Time, filter Filter) ([]Occurrence, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	tasks, err := f.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return ExpandOccurrences(tasks, from, to)
+}
+
+func (f *fromLegacy) Tags(ctx context.Context) ([]TagCount, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	tasks, err := f.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return CountTags(tasks), nil
+}
+
+func (f *fromLegacy) Search(ctx context.Context, query string, filter Filter) ([]SearchResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if _, err := SearchTerms(query); err != nil {
+		return nil, err
+	}
+	tasks, err := f.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return SearchTasks(tasks, query)
+}
+
+func (f *fromLegacy) Put(ctx context.Context, task Task) (string, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return "", false, err
+	}
+	if err := f.checkRevision(ctx, task.ID); err != nil {
+		return "", false, err
+	}
+	var existing *Task
+	if task.ID != "" {
+		var err error
+		if existing, err = f.lti.Get(task.ID); err != nil && !errors.Is(err, ErrNotFound) {
+			return "", false, err
+		}
+	}
+	if existing != nil {
+		if !Visible(ctx, existing.Owner) {
+			return "", false, fmt.Errorf("failed to put task %q: %w", task.ID, ErrConflict)
+		}
+		task.Owner = AssignOwner(ctx, task.Owner, existing.Owner, true)
+	} else {
+		task.Owner = AssignOwner(ctx, task.Owner, "", false)
+	}
+	id, created, err := f.lti.Put(task)
+	if err != nil {
+		return "", false, err
+	}
+	typ := EventUpdated
+	if created {
+		typ = EventCreated
+	}
+	return id, created, f.publish(typ, id)
+}
+
+func (f *fromLegacy) Create(ctx context.Context, task Task) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	task.Owner = AssignOwner(ctx, task.Owner, "", false)
+	id, err := f.lti.Create(task)
+	if err != nil {
+		return "", err
+	}
+	return id, f.publish(EventCreated, id)
+}
+
+// The publish method publishes an event of typ for the task with the given id, as stored by lti.
+func (f *fromLegacy) publish(typ EventType, id string) error {
+	t, err := f.lti.Get(id)
+	if err != nil {
+		return fmt.Errorf("failed to get task %q for its event: %w", id, err)
+	}
+	f.feed.Publish(typ, *t)
+	return nil
+}
+
+func (f *fromLegacy) Update(ctx context.Context, id string, patch []byte) (*Task, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return f.recur(ctx, id, func() (*Task, error) {
+		return f.lti.Update(id, patch)
+	})
+}
+
+func (f *fromLegacy) SetStatus(ctx context.Context, id string, status Status) (*Task, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return f.recur(ctx, id, func() (*Task, error) {
+		return f.lti.SetStatus(id, status)
+	})
+}
+
+// The recur method applies modify to the task with the given id, if it is at the revision expected by ctx, and creates
+// its NextOccurrence if it was just done. A LegacyTaskInterface has no transactions, so a failure may leave the task done
+// without a next occurrence.
+func (f *fromLegacy) recur(ctx context.Context, id string, modify func() (*Task, error)) (*Task, error) {
+	old, err := f.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := CheckRevision(ctx, id, old.Revision); err != nil {
+		return nil, err
+	}
+	t, err := modify()
+	if err != nil {
+		return nil, err
+	}
+	next, err := NextOccurrence(*old, *t)
+	if err != nil {
+		return nil, err
+	} else if next == nil {
+		f.feed.Publish(EventUpdated, *t)
+		return t, nil
+	}
+	nextID, err := f.lti.Create(*next)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create next occurrence of task %q: %w", id, err)
+	}
+	if err := f.publish(EventCreated, nextID); err != nil {
+		return nil, err
+	}
+	bs, err := json.Marshal(map[string]string{"next": nextID})
+	if err != nil {
+		return nil, err
+	}
+	if t, err = f.lti.Update(id, bs); err != nil {
+		return nil, err
+	}
+	f.feed.Publish(EventUpdated, *t)
+	return t, nil
+}
+
+func (f *fromLegacy) Children(ctx context.Context, id string) ([]Tree, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if _, err := f.get(ctx, id); err != nil {
+		return nil, err
+	}
+	tasks, err := f.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return ChildTrees(tasks, id), nil
+}
+
+// The Delete method clears the parent of the task's subtasks, and removes it from the tasks it blocks, before deleting
+// it. A LegacyTaskInterface has no transactions, so a failure may leave some tasks changed and the task undeleted.
+func (f *fromLegacy) Delete(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := ValidateID(id); err != nil {
+		return err
+	}
+	if _, err := f.get(ctx, id); err != nil {
+		return err
+	}
+	if err := f.checkRevision(ctx, id); err != nil {
+		return err
+	}
+	return f.delete(ctx, id)
+}
+
+// The delete method deletes the task with the given id, regardless of its revision. Like other implementations, it
+// publishes the deleted event before the updated events of its subtasks and the tasks it blocked.
+func (f *fromLegacy) delete(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	tasks, err := f.lti.GetAll()
+	if err != nil {
+		return err
+	}
+	var updated []Task
+	for _, t := range tasks {
+		patch := make(map[string]interface{})
+		if t.Parent == id {
+			patch["parent"] = nil
+		}
+		if t.Blocks(id) {
+			var blockers []string
+			for _, b := range t.BlockedBy {
+				if b != id {
+					blockers = append(blockers, b)
+				}
+			}
+			patch["blocked_by"] = blockers
+		}
+		if len(patch) == 0 {
+			continue
+		}
+		bs, err := json.Marshal(patch)
+		if err != nil {
+			return err
+		}
+		u, err := f.lti.Update(t.ID, bs)
+		if err != nil {
+			return err
+		}
+		updated = append(updated, *u)
+	}
+	t, err := f.lti.Get(id)
+	if err != nil {
+		return err
+	}
+	if err := f.lti.Delete(id); err != nil {
+		return err
+	}
+	f.feed.Publish(EventDeleted, *t)
+	for _, u := range updated {
+		f.feed.Publish(EventUpdated, u)
+	}
+	return nil
+}
+
+// The DeleteTree method deletes the task's subtasks, deepest first, and then the task, unless one of them is not Visible
+// to ctx. A LegacyTaskInterface has no transactions, so a failure may leave the tree partially deleted.
+func (f *fromLegacy) DeleteTree(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if _, err := f.get(ctx, id); err != nil {
+		return err
+	}
+	if err := f.checkRevision(ctx, id); err != nil {
+		return err
+	}
+	tasks, err := f.lti.GetAll()
+	if err != nil {
+		return err
+	}
+	trees := ChildTrees(tasks, id)
+	var checkTrees func([]Tree) error
+	checkTrees = func(trees []Tree) error {
+		for _, t := range trees {
+			if !Visible(ctx, t.Owner) {
+				return fmt.Errorf("failed to delete task %q: %w: may not delete all of its subtasks", id, ErrForbidden)
+			}
+			if err := checkTrees(t.Children); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := checkTrees(trees); err != nil {
+		return err
+	}
+	var deleteTrees func([]Tree) error
+	deleteTrees = func(trees []Tree) error {
+		for _, t := range trees {
+			if err := deleteTrees(t.Children); err != nil {
+				return err
+			}
+			if err := f.delete(ctx, t.ID); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := deleteTrees(trees); err != nil {
+		return err
+	}
+	return f.delete(ctx, id)
+}
+
+// The checkRevision method returns an error wrapping ErrRevisionMismatch if ctx expects a different revision of the task
+// with the given id.
+func (f *fromLegacy) checkRevision(ctx context.Context, id string) error {
+	if _, ok := ExpectedRevision(ctx); !ok {
+		return nil
+	}
+	var current int64
+	if id != "" {
+		t, err := f.lti.Get(id)
+		if err == nil {
+			current = t.Revision
+		} else if !errors.Is(err, ErrNotFound) {
+			return err
+		}
+	}
+	return CheckRevision(ctx, id, current)
+}
+
+// The GetList method never finds a list, since a LegacyTaskInterface has no lists.
+func (f *fromLegacy) GetList(ctx context.Context, id string) (*TaskList, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := ValidateID(id); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("failed to get list %q: %w", id, ErrNotFound)
+}
+
+func (f *fromLegacy) GetLists(ctx context.Context) ([]TaskList, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// The PutList method returns an error wrapping ErrListsUnsupported for a valid list, since a LegacyTaskInterface cannot
+// store lists.
+func (f *fromLegacy) PutList(ctx context.Context, list TaskList) (string, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return "", false, err
+	}
+	if list.ID != "" {
+		if err := list.Validate(); err != nil {
+			return "", false, err
+		}
+	}
+	return "", false, fmt.Errorf("failed to put list %q: %w by legacy task interfaces", list.ID, ErrListsUnsupported)
+}
+
+func (f *fromLegacy) DeleteList(ctx context.Context, id string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := ValidateID(id); err != nil {
+		return err
+	}
+	return fmt.Errorf("failed to delete list %q: %w", id, ErrNotFound)
+}
+
+// The Share method never finds a list, since a LegacyTaskInterface has no lists.
+func (f *fromLegacy) Share(ctx context.Context, list string, grant Grant) (*TaskList, error) {
+	return f.GetList(ctx, list)
+}
+
+// The Unshare method never finds a list, since a LegacyTaskInterface has no lists.
+func (f *fromLegacy) Unshare(ctx context.Context, list string, grantee Grantee) (*TaskList, error) {
+	return f.GetList(ctx, list)
+}
+
+// The Watch method watches the changes made through f, since a LegacyTaskInterface has no change feed.
+func (f *fromLegacy) Watch(ctx context.Context, after int64) (<-chan Event, error) {
+	return f.feed.Watch(ctx, after, func(e Event) bool {
+		return Visible(ctx, e.Task.Owner)
+	})
+}
@@ -0,0 +1,227 @@
+package task_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/jmank88/todo/memstore"
+	"github.com/jmank88/todo/task"
+	"github.com/jmank88/todo/tasktest"
+)
+
+// A mapLegacy is a minimal LegacyTaskInterface backed by a map, which counts its calls.
+type mapLegacy struct {
+	tasks map[string]task.Task
+	calls int
+}
+
+func newMapLegacy() *mapLegacy {
+	return &mapLegacy{tasks: make(map[string]task.Task)}
+}
+
+func (m *mapLegacy) Get(id string) (*task.Task, error) {
+	m.calls++
+	t, ok := m.tasks[id]
+	if !ok {
+		return nil, fmt.Errorf("failed to get task %q: %w", id, task.ErrNotFound)
+	}
+	return &t, nil
+}
+
+func (m *mapLegacy) GetAll() ([]task.Task, error) {
+	return m.Find(task.Filter{})
+}
+
+func (m *mapLegacy) Find(filter task.Filter) ([]task.Task, error) {
+	m.calls++
+	var tasks []task.Task
+	for _, t := range m.tasks {
+		if filter.Match(t, time.Now()) {
+			tasks = append(tasks, t)
+		}
+	}
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].ID < tasks[j].ID })
+	return tasks, nil
+}
+
+func (m *mapLegacy) Put(t task.Task) (string, bool, error) {
+	m.calls++
+	_, exists := m.tasks[t.ID]
+	m.tasks[t.ID] = t
+	return t.ID, !exists, nil
+}
+
+func (m *mapLegacy) Create(t task.Task) (string, error) {
+	m.calls++
+	if _, exists := m.tasks[t.ID]; exists {
+		return "", fmt.Errorf("failed to create task %q: %w", t.ID, task.ErrConflict)
+	}
+	m.tasks[t.ID] = t
+	return t.ID, nil
+}
+
+func (m *mapLegacy) Update(id string, patch []byte) (*task.Task, error) {
+	t, err := m.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	patched, err := task.MergePatch(*t, patch, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	m.tasks[id] = patched
+	return &patched, nil
+}
+
+func (m *mapLegacy) SetStatus(id string, status task.Status) (*task.Task, error) {
+	t, err := m.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	t.SetStatus(status, time.Now())
+	m.tasks[id] = *t
+	return t, nil
+}
+
+func (m *mapLegacy) Delete(id string) error {
+	if _, err := m.Get(id); err != nil {
+		return err
+	}
+	delete(m.tasks, id)
+	return nil
+}
+
+// Tests that FromLegacy calls the LegacyTaskInterface, and checks contexts before calling it.
+func TestFromLegacy(t *testing.T) {
+	ctx := context.Background()
+	lti := newMapLegacy()
+	ti := task.FromLegacy(lti)
+
+	yesterday := time.Now().AddDate(0, 0, -1)
+	if _, created, err := ti.Put(ctx, task.Task{ID: "overdue", Due: &yesterday}); err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if !created {
+		t.Fatal("expected task to be created")
+	}
+	if _, err := ti.Create(ctx, task.Task{ID: "undated"}); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if _, err := ti.Create(ctx, task.Task{ID: "undated"}); !errors.Is(err, task.ErrConflict) {
+		t.Fatalf("expected %v but got %v", task.ErrConflict, err)
+	}
+	if got, err := ti.Find(ctx, task.Filter{Overdue: true}); err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if len(got) != 1 || got[0].ID != "overdue" {
+		t.Fatalf("expected overdue task but got %v", got)
+	}
+	if got, err := ti.GetAll(ctx); err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if len(got) != 2 {
+		t.Fatalf("expected 2 tasks but got %v", got)
+	}
+	if got, err := ti.Update(ctx, "undated", []byte(`{"title":"newTitle"}`)); err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if got.Title != "newTitle" {
+		t.Fatalf("expected title %q but got %q", "newTitle", got.Title)
+	}
+	if err := ti.Delete(ctx, "overdue"); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if _, err := ti.Get(ctx, "overdue"); !errors.Is(err, task.ErrNotFound) {
+		t.Fatalf("expected %v but got %v", task.ErrNotFound, err)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	calls := lti.calls
+	if _, err := ti.Get(cancelled, "undated"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected %v but got %v", context.Canceled, err)
+	}
+	if _, err := ti.GetAll(cancelled); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected %v but got %v", context.Canceled, err)
+	}
+	if lti.calls != calls {
+		t.Fatalf("expected no calls with a cancelled context but got %d", lti.calls-calls)
+	}
+}
+
+// Tests that Legacy adapts a TaskInterface back to a LegacyTaskInterface.
+func TestLegacy(t *testing.T) {
+	lti := task.Legacy(task.FromLegacy(newMapLegacy()))
+
+	if id, err := lti.Create(task.Task{ID: "testId", Title: "testTitle"}); err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if id != "testId" {
+		t.Fatalf("expected id %q but got %q", "testId", id)
+	}
+	if got, err := lti.SetStatus("testId", task.StatusDone); err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if got.Status != task.StatusDone || got.Completed == nil {
+		t.Fatalf("expected completed task but got %v", got)
+	}
+	if got, err := lti.Get("testId"); err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if got.Title != "testTitle" || got.Status != task.StatusDone {
+		t.Fatalf("expected done task %q but got %v", "testTitle", got)
+	}
+	if err := lti.Delete("testId"); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if _, err := lti.Get("testId"); !errors.Is(err, task.ErrNotFound) {
+		t.Fatalf("expected %v but got %v", task.ErrNotFound, err)
+	}
+}
+
+// Runs the conformance suite against a memstore adapted to a LegacyTaskInterface and back.
+func TestConformance(t *testing.T) {
+	tasktest.RunConformance(t, func(t *testing.T) task.TaskInterface {
+		return task.FromLegacy(task.Legacy(memstore.NewMemstore()))
+	})
+}
+
+// Tests that FromLegacy only finds the tasks visible to the user of the context, and only deletes them with their
+// subtasks if all are. The isolation suite needs lists, which a LegacyTaskInterface cannot store.
+func TestFromLegacyIsolation(t *testing.T) {
+	ti := task.FromLegacy(task.Legacy(memstore.NewMemstore()))
+	alice := task.WithUser(context.Background(), "alice")
+	bob := task.WithUser(context.Background(), "bob")
+	if _, _, err := ti.Put(alice, task.Task{ID: "a", Title: "Buy milk"}); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if _, _, err := ti.Put(bob, task.Task{ID: "b", Title: "Buy milk"}); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+
+	if got, err := ti.Find(alice, task.Filter{}); err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if len(got) != 1 || got[0].ID != "a" || got[0].Owner != "alice" {
+		t.Fatalf("expected only task %q owned by alice but got %v", "a", got)
+	}
+	if got, err := ti.GetAll(bob); err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if len(got) != 1 || got[0].ID != "b" {
+		t.Fatalf("expected only task %q but got %v", "b", got)
+	}
+	if got, err := ti.GetAll(task.AsAdmin(alice)); err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if len(got) != 2 {
+		t.Fatalf("expected all tasks but got %v", got)
+	}
+	if _, err := ti.Get(alice, "b"); !errors.Is(err, task.ErrNotFound) {
+		t.Fatalf("expected %v but got %v", task.ErrNotFound, err)
+	}
+
+	if _, _, err := ti.Put(bob, task.Task{ID: "b1", Parent: "a"}); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if err := ti.DeleteTree(alice, "a"); !errors.Is(err, task.ErrForbidden) {
+		t.Fatalf("expected %v but got %v", task.ErrForbidden, err)
+	}
+	if _, err := ti.Get(bob, "b1"); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+}
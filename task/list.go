@@ -0,0 +1,234 @@
+package task
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The DefaultLimit is the number of tasks listed per page if no limit is specified.
+const DefaultLimit = 100
+
+// The MaxLimit is the largest number of tasks listed per page. Larger limits are reduced to it.
+const MaxLimit = 1000
+
+// A Sort is the key which listed tasks are ordered by. Prefixing the key with '-' reverses the order. Ties are broken
+// by id, in the same direction, so that the order is stable. Strings are compared byte-wise, and tasks without a due
+// time, priority, or timestamp are ordered after those with one.
+type Sort string
+
+// The Sort keys.
+const (
+	SortID    Sort = "id"
+	SortTitle Sort = "title"
+	SortDue   Sort = "due"
+	// SortPriority orders the most urgent tasks first, and tasks without a priority last.
+	SortPriority Sort = "priority"
+	// SortCreated orders by CreatedAt, so "-created" lists the newest tasks first.
+	SortCreated Sort = "created"
+	// SortUpdated orders by UpdatedAt, so "updated" lists the stalest tasks first.
+	SortUpdated Sort = "updated"
+)
+
+// The Key method returns the key of s, without direction. The empty Sort has key SortID.
+func (s Sort) Key() Sort {
+	if s == "" {
+		return SortID
+	}
+	return Sort(strings.TrimPrefix(string(s), "-"))
+}
+
+// The Desc method returns true if s is in descending order.
+func (s Sort) Desc() bool {
+	return strings.HasPrefix(string(s), "-")
+}
+
+// The Valid method returns true if s has a known key. The empty Sort is valid, and orders by id.
+func (s Sort) Valid() bool {
+	switch s.Key() {
+	case SortID, SortTitle, SortDue, SortPriority, SortCreated, SortUpdated:
+		return true
+	default:
+		return false
+	}
+}
+
+// The Less method returns true if a is ordered before b.
+func (s Sort) Less(a, b Task) bool {
+	if s.Desc() {
+		a, b = b, a
+	}
+	switch s.Key() {
+	case SortTitle:
+		if a.Title != b.Title {
+			return a.Title < b.Title
+		}
+	case SortDue:
+		if less, ok := lessTime(a.Due, b.Due); ok {
+			return less
+		}
+	case SortPriority:
+		if a.Priority != b.Priority {
+			return a.Priority.Before(b.Priority)
+		}
+	case SortCreated:
+		if less, ok := lessTime(a.CreatedAt, b.CreatedAt); ok {
+			return less
+		}
+	case SortUpdated:
+		if less, ok := lessTime(a.UpdatedAt, b.UpdatedAt); ok {
+			return less
+		}
+	}
+	return a.ID < b.ID
+}
+
+// The lessTime function compares optional times a and b, with nil ordered last. Returns whether a is before b, and
+// false for ok if they are equal.
+func lessTime(a, b *time.Time) (less, ok bool) {
+	switch {
+	case a == nil && b == nil:
+		return false, false
+	case a == nil:
+		return false, true
+	case b == nil:
+		return true, true
+	case a.Equal(*b):
+		return false, false
+	default:
+		return a.Before(*b), true
+	}
+}
+
+// ListOptions select a single page of tasks to list.
+type ListOptions struct {
+	Filter
+
+	// Limit is the maximum number of tasks in the page. Zero means DefaultLimit.
+	Limit int
+
+	// Cursor continues listing after the end of a previous page, from its Next field. The other options must be the
+	// same as for the previous page.
+	Cursor string
+
+	// Sort orders the tasks. The default is by id.
+	Sort Sort
+}
+
+// The PageLimit method returns the effective limit of o.
+func (o ListOptions) PageLimit() int {
+	switch {
+	case o.Limit <= 0:
+		return DefaultLimit
+	case o.Limit > MaxLimit:
+		return MaxLimit
+	default:
+		return o.Limit
+	}
+}
+
+// The After method returns the last task of the previous page, decoded from o.Cursor, or nil if there is no cursor. The
+// returned task has only the id and the sort key set. Returns an error wrapping ErrInvalid if o is not valid.
+func (o ListOptions) After() (*Task, error) {
+	if !o.Sort.Valid() {
+		return nil, fmt.Errorf("%w: unknown sort %q", ErrInvalid, o.Sort)
+	}
+	if o.Limit < 0 {
+		return nil, fmt.Errorf("%w: negative limit %d", ErrInvalid, o.Limit)
+	}
+	if o.Cursor == "" {
+		return nil, nil
+	}
+	bs, err := base64.RawURLEncoding.DecodeString(o.Cursor)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor: %s", ErrInvalid, err)
+	}
+	var c cursor
+	if err := json.Unmarshal(bs, &c); err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor: %s", ErrInvalid, err)
+	}
+	if c.Sort.Key() != o.Sort.Key() || c.Sort.Desc() != o.Sort.Desc() {
+		return nil, fmt.Errorf("%w: cursor is for sort %q, not %q", ErrInvalid, c.Sort, o.Sort)
+	}
+	return &Task{ID: c.ID, Title: c.Title, Due: c.Due, Priority: c.Priority, CreatedAt: c.Created, UpdatedAt: c.Updated}, nil
+}
+
+// A cursor is the json form of ListOptions.Cursor. It holds the sort, and the sort key of the last task listed.
+type cursor struct {
+	Sort     Sort       `json:"s"`
+	ID       string     `json:"i"`
+	Title    string     `json:"t,omitempty"`
+	Due      *time.Time `json:"d,omitempty"`
+	Priority Priority   `json:"p,omitempty"`
+	Created  *time.Time `json:"c,omitempty"`
+	Updated  *time.Time `json:"u,omitempty"`
+}
+
+// The encodeCursor function returns a cursor continuing after t in order s.
+func encodeCursor(t Task, s Sort) string {
+	c := cursor{Sort: s, ID: t.ID}
+	switch s.Key() {
+	case SortTitle:
+		c.Title = t.Title
+	case SortDue:
+		c.Due = t.Due
+	case SortPriority:
+		c.Priority = t.Priority
+	case SortCreated:
+		c.Created = t.CreatedAt
+	case SortUpdated:
+		c.Updated = t.UpdatedAt
+	}
+	bs, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(bs)
+}
+
+// A Page is a single page of listed tasks.
+type Page struct {
+
+	// Tasks are the tasks in the page, in order.
+	Tasks []Task
+
+	// Next is the cursor for the next page, or empty if this is the last page.
+	Next string
+}
+
+// The NewPage function returns a page from tasks, which are the ordered tasks following the previous page. Stores
+// should fetch one more than the limit, so that the last page can be detected without another query.
+func NewPage(tasks []Task, o ListOptions) *Page {
+	limit := o.PageLimit()
+	if len(tasks) <= limit {
+		return &Page{Tasks: tasks}
+	}
+	tasks = tasks[:limit]
+	return &Page{Tasks: tasks, Next: encodeCursor(tasks[limit-1], o.Sort)}
+}
+
+// The Paginate function returns the page of tasks selected by o, at time now. Tasks may be in any order, and are
+// sorted in place. Paginate is for stores which hold all of their tasks in memory.
+func Paginate(tasks []Task, o ListOptions, now time.Time) (*Page, error) {
+	after, err := o.After()
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		return o.Sort.Less(tasks[i], tasks[j])
+	})
+	var selected []Task
+	for _, t := range tasks {
+		if after != nil && !o.Sort.Less(*after, t) {
+			continue
+		}
+		if !o.Filter.Match(t, now) {
+			continue
+		}
+		selected = append(selected, t)
+		if len(selected) > o.PageLimit() {
+			break
+		}
+	}
+	return NewPage(selected, o), nil
+}
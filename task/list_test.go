@@ -0,0 +1,76 @@
+package task
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Tests paging through tasks in each order.
+func TestPaginate(t *testing.T) {
+	now := time.Date(2016, 1, 2, 3, 4, 5, 0, time.UTC)
+	early, late := now.Add(-time.Hour), now.Add(time.Hour)
+	tasks := []Task{
+		{ID: "c", Title: "a", Due: &late, CreatedAt: &early, UpdatedAt: &late},
+		{ID: "a", Title: "b", CreatedAt: &now, UpdatedAt: &now},
+		{ID: "e", Title: "b", Due: &early, CreatedAt: &early, UpdatedAt: &early},
+		{ID: "b", Title: "c", Due: &early, CreatedAt: &late, UpdatedAt: &late},
+		{ID: "d", Title: "a"},
+	}
+
+	for _, test := range []struct {
+		sort     Sort
+		expected []string
+	}{
+		{"", []string{"a", "b", "c", "d", "e"}},
+		{"-id", []string{"e", "d", "c", "b", "a"}},
+		{SortTitle, []string{"c", "d", "a", "e", "b"}},
+		{"-title", []string{"b", "e", "a", "d", "c"}},
+		{SortDue, []string{"b", "e", "c", "a", "d"}},
+		{"-due", []string{"d", "a", "c", "e", "b"}},
+		{SortCreated, []string{"c", "e", "a", "b", "d"}},
+		{"-created", []string{"d", "b", "a", "e", "c"}},
+		{SortUpdated, []string{"e", "a", "b", "c", "d"}},
+	} {
+		var got []string
+		options := ListOptions{Limit: 2, Sort: test.sort}
+		for pages := 0; ; pages++ {
+			if pages > len(tasks) {
+				t.Fatalf("%q: too many pages", test.sort)
+			}
+			page, err := Paginate(append([]Task(nil), tasks...), options, now)
+			if err != nil {
+				t.Fatalf("%q: unexpected error: %s", test.sort, err)
+			}
+			for _, task := range page.Tasks {
+				got = append(got, task.ID)
+			}
+			if page.Next == "" {
+				break
+			}
+			options.Cursor = page.Next
+		}
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("%q: expected %v but got %v", test.sort, test.expected, got)
+		}
+	}
+}
+
+// Tests that invalid options are rejected with ErrInvalid.
+func TestListOptionsInvalid(t *testing.T) {
+	page, err := Paginate([]Task{{ID: "a"}, {ID: "b"}}, ListOptions{Limit: 1}, time.Now())
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	for _, options := range []ListOptions{
+		{Sort: "urgency"},
+		{Limit: -1},
+		{Cursor: "not a cursor"},
+		{Cursor: page.Next, Sort: "-id"},
+	} {
+		if _, err := options.After(); !errors.Is(err, ErrInvalid) {
+			t.Errorf("expected %v for %+v but got %v", ErrInvalid, options, err)
+		}
+	}
+}
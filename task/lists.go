@@ -0,0 +1,29 @@
+package task
+
+import "fmt"
+
+// A TaskList is a named collection of tasks, like a project, or a shopping list. Tasks belong to at most one list, by
+// their List field.
+type TaskList struct {
+
+	// ID is the unique id of this list. It follows the same rules as task ids.
+	ID string `json:"id"`
+
+	// Name is the display name of this list.
+	Name string `json:"name"`
+
+	// Owner is the user this list belongs to, or empty if it belongs to no one. It is assigned like the Owner of a Task.
+	Owner string `json:"owner,omitempty"`
+
+	// Grants share this list, and its tasks, with other users and groups. They are only changed by sharing and
+	// unsharing, and are kept when the list is replaced. See NormalizeGrants.
+	Grants []Grant `json:"grants,omitempty"`
+}
+
+// The Validate method returns an error wrapping ErrInvalid if l is not a valid list.
+func (l *TaskList) Validate() error {
+	if err := ValidateID(l.ID); err != nil {
+		return fmt.Errorf("invalid list id: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// A userKey is the context.Context key of the user making a request.
+type userKey struct{}
+
+// The WithUser function returns a copy of ctx carrying user, the name of the user making a request. Stores record it as
+// the CreatedBy of tasks created with the returned context.
+func WithUser(ctx context.Context, user string) context.Context {
+	return context.WithValue(ctx, userKey{}, user)
+}
+
+// The User function returns the user carried by ctx, or empty if there is none. See WithUser.
+func User(ctx context.Context) string {
+	user, _ := ctx.Value(userKey{}).(string)
+	return user
+}
+
+// An adminKey is the context.Context key marking requests made by an admin.
+type adminKey struct{}
+
+// The AsAdmin function returns a copy of ctx whose user is an admin, who may read and write every task and list, and
+// assign their owners.
+func AsAdmin(ctx context.Context) context.Context {
+	return context.WithValue(ctx, adminKey{}, true)
+}
+
+// The IsAdmin function returns true if the user of ctx is an admin. See AsAdmin.
+func IsAdmin(ctx context.Context) bool {
+	admin, _ := ctx.Value(adminKey{}).(bool)
+	return admin
+}
+
+// The Scope function returns the user whose tasks and lists are the only ones visible to ctx, and true, or false if
+// every task and list is visible, since ctx is an admin's, or carries no user at all, like the requests of servers
+// without authentication.
+func Scope(ctx context.Context) (string, bool) {
+	user := User(ctx)
+	if user == "" || IsAdmin(ctx) {
+		return "", false
+	}
+	return user, true
+}
+
+// The Visible function returns true if tasks and lists belonging to owner may be read and written with ctx. See Scope.
+// Stores report tasks and lists which are not visible as not found, so that their ids cannot be probed.
+func Visible(ctx context.Context, owner string) bool {
+	user, scoped := Scope(ctx)
+	return !scoped || owner == user
+}
+
+// The AssignOwner function returns the owner of a task or list being written with ctx, given the owner supplied by the
+// client, and the existing owner, if exists. The supplied owner is only kept if ctx is not scoped to a user, so admins
+// may create tasks for others, or give tasks away by replacing them. Otherwise an existing owner is kept, and new tasks
+// belong to the user of ctx.
+func AssignOwner(ctx context.Context, supplied, existing string, exists bool) string {
+	_, scoped := Scope(ctx)
+	switch {
+	case !scoped && supplied != "":
+		return supplied
+	case exists:
+		return existing
+	default:
+		return User(ctx)
+	}
+}
+
+// A revisionKey is the context.Context key of the revision expected by a conditional write.
+type revisionKey struct{}
+
+// The IfMatch function returns a copy of ctx which makes writes conditional on the current revision of the task being
+// revision. See CheckRevision.
+func IfMatch(ctx context.Context, revision int64) context.Context {
+	return context.WithValue(ctx, revisionKey{}, revision)
+}
+
+// The ExpectedRevision function returns the revision expected by ctx, and whether there is one. See IfMatch.
+func ExpectedRevision(ctx context.Context) (int64, bool) {
+	revision, ok := ctx.Value(revisionKey{}).(int64)
+	return revision, ok
+}
+
+// The CheckRevision function returns an error wrapping ErrRevisionMismatch if ctx expects a revision other than
+// current, the revision of the task with the given id, or zero if it does not exist.
+func CheckRevision(ctx context.Context, id string, current int64) error {
+	expected, ok := ExpectedRevision(ctx)
+	switch {
+	case !ok || expected == current:
+		return nil
+	case current == 0:
+		return fmt.Errorf("%w: task %q does not exist, expected revision %d", ErrRevisionMismatch, id, expected)
+	default:
+		return fmt.Errorf("%w: task %q is at revision %d, not %d", ErrRevisionMismatch, id, current, expected)
+	}
+}
+
+// The Stamp method sets the server-assigned metadata of t, which is being written at time now by user. If t replaces
+// existing, then existing's CreatedAt and CreatedBy are kept, otherwise they are set to now and user. UpdatedAt is
+// always set to now, and Revision to one more than existing's. Any metadata supplied by the client is overwritten.
+// Times are truncated to microseconds, which is the precision stores are able to persist.
+func (t *Task) Stamp(existing *Task, now time.Time, user string) {
+	now = now.Truncate(time.Microsecond)
+	if existing != nil && existing.CreatedAt != nil {
+		created := *existing.CreatedAt
+		t.CreatedAt, t.CreatedBy = &created, existing.CreatedBy
+	} else {
+		t.CreatedAt, t.CreatedBy = &now, user
+	}
+	updated := now
+	t.UpdatedAt = &updated
+	t.Revision = 1
+	if existing != nil {
+		t.Revision = existing.Revision + 1
+	}
+}
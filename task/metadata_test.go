@@ -0,0 +1,106 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// Tests that stamping a new task sets all of its metadata, and stamping a replacement keeps the creation metadata.
+func TestStamp(t *testing.T) {
+	created := time.Date(2026, 1, 2, 3, 4, 5, 6789, time.UTC)
+	updated := created.Add(time.Hour)
+	forged := created.AddDate(-1, 0, 0)
+
+	tk := Task{ID: "a", CreatedAt: &forged, UpdatedAt: &forged, CreatedBy: "mallory"}
+	tk.Stamp(nil, created, "alice")
+	if expected := created.Truncate(time.Microsecond); !tk.CreatedAt.Equal(expected) || !tk.UpdatedAt.Equal(expected) {
+		t.Errorf("expected created and updated at %s but got %s and %s", expected, tk.CreatedAt, tk.UpdatedAt)
+	}
+	if tk.CreatedBy != "alice" || tk.Revision != 1 {
+		t.Errorf("expected revision 1 created by %q but got revision %d created by %q", "alice", tk.Revision, tk.CreatedBy)
+	}
+
+	replacement := Task{ID: "a", CreatedBy: "mallory", Revision: 7}
+	replacement.Stamp(&tk, updated, "bob")
+	if !replacement.CreatedAt.Equal(*tk.CreatedAt) || replacement.CreatedBy != "alice" {
+		t.Errorf("expected created at %s by %q but got %s by %q", tk.CreatedAt, "alice", replacement.CreatedAt, replacement.CreatedBy)
+	}
+	if expected := updated.Truncate(time.Microsecond); !replacement.UpdatedAt.Equal(expected) {
+		t.Errorf("expected updated at %s but got %s", expected, replacement.UpdatedAt)
+	}
+	if replacement.Revision != 2 {
+		t.Errorf("expected revision 2 but got %d", replacement.Revision)
+	}
+}
+
+// Tests that conditional writes only match the expected revision.
+func TestCheckRevision(t *testing.T) {
+	ctx := context.Background()
+	if err := CheckRevision(ctx, "a", 3); err != nil {
+		t.Errorf("unexpected error for unconditional write: %s", err)
+	}
+	if err := CheckRevision(IfMatch(ctx, 3), "a", 3); err != nil {
+		t.Errorf("unexpected error for matching revision: %s", err)
+	}
+	for _, current := range []int64{0, 2, 4} {
+		if err := CheckRevision(IfMatch(ctx, 3), "a", current); !errors.Is(err, ErrRevisionMismatch) {
+			t.Errorf("expected %v for revision %d but got %v", ErrRevisionMismatch, current, err)
+		}
+	}
+}
+
+// Tests carrying the user in a context.
+func TestUser(t *testing.T) {
+	if user := User(context.Background()); user != "" {
+		t.Errorf("expected no user but got %q", user)
+	}
+	if user := User(WithUser(context.Background(), "alice")); user != "alice" {
+		t.Errorf("expected user %q but got %q", "alice", user)
+	}
+}
+
+// Tests which owners are visible to users and admins, and which owners are assigned by their writes.
+func TestOwners(t *testing.T) {
+	ctx := context.Background()
+	alice := WithUser(ctx, "alice")
+	admin := AsAdmin(WithUser(ctx, "root"))
+	for _, test := range []struct {
+		name    string
+		ctx     context.Context
+		visible map[string]bool
+	}{
+		{"anonymous", ctx, map[string]bool{"": true, "alice": true, "bob": true}},
+		{"alice", alice, map[string]bool{"": false, "alice": true, "bob": false}},
+		{"admin", admin, map[string]bool{"": true, "alice": true, "bob": true}},
+	} {
+		for owner, expected := range test.visible {
+			if got := Visible(test.ctx, owner); got != expected {
+				t.Errorf("%s: expected visible %t for owner %q but got %t", test.name, expected, owner, got)
+			}
+		}
+	}
+
+	for _, test := range []struct {
+		name     string
+		ctx      context.Context
+		supplied string
+		existing string
+		exists   bool
+		expected string
+	}{
+		{"new", alice, "", "", false, "alice"},
+		{"forged new", alice, "bob", "", false, "alice"},
+		{"forged replacement", alice, "bob", "alice", true, "alice"},
+		{"anonymous", ctx, "", "", false, ""},
+		{"admin new", admin, "", "", false, "root"},
+		{"admin for other", admin, "bob", "", false, "bob"},
+		{"admin give away", admin, "bob", "alice", true, "bob"},
+		{"admin replacement", admin, "", "alice", true, "alice"},
+	} {
+		if got := AssignOwner(test.ctx, test.supplied, test.existing, test.exists); got != test.expected {
+			t.Errorf("%s: expected owner %q but got %q", test.name, test.expected, got)
+		}
+	}
+}
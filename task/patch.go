@@ -0,0 +1,74 @@
+package task
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// The MergePatch function applies an RFC 7386 JSON merge patch to t, and returns the patched task. A patch may not
+// change the id of a task, and changes to its server-assigned CreatedAt, UpdatedAt, CreatedBy, Revision, and Owner are
+// ignored. A change of status is applied with SetStatus, so the completed time is kept consistent. Returns ErrInvalid if
+// the patch is malformed, or results in an invalid task.
+func MergePatch(t Task, patch []byte, now time.Time) (Task, error) {
+	var p map[string]interface{}
+	if err := json.Unmarshal(patch, &p); err != nil {
+		return t, fmt.Errorf("%w: malformed merge patch: %s", ErrInvalid, err)
+	}
+	if p == nil {
+		return t, fmt.Errorf("%w: merge patch must be a json object", ErrInvalid)
+	}
+	if id, ok := p["id"]; ok && id != t.ID {
+		return t, fmt.Errorf("%w: merge patch cannot change id of task %q", ErrInvalid, t.ID)
+	}
+
+	bs, err := json.Marshal(t)
+	if err != nil {
+		return t, fmt.Errorf("failed to serialize task %q: %s", t.ID, err)
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(bs, &doc); err != nil {
+		return t, fmt.Errorf("failed to deserialize task %q: %s", t.ID, err)
+	}
+	if bs, err = json.Marshal(mergePatch(doc, p)); err != nil {
+		return t, fmt.Errorf("failed to serialize patched task %q: %s", t.ID, err)
+	}
+	var patched Task
+	if err := json.Unmarshal(bs, &patched); err != nil {
+		return t, fmt.Errorf("%w: merge patch for task %q: %s", ErrInvalid, t.ID, err)
+	}
+	patched.CreatedAt, patched.UpdatedAt = t.CreatedAt, t.UpdatedAt
+	patched.CreatedBy, patched.Revision, patched.Owner = t.CreatedBy, t.Revision, t.Owner
+
+	if err := patched.Validate(); err != nil {
+		return t, err
+	}
+	patched.Tags = NormalizeTags(patched.Tags)
+	patched.BlockedBy = NormalizeIDs(patched.BlockedBy)
+	if patched.Status != t.Status {
+		status := patched.Status
+		patched.Status, patched.Completed = t.Status, t.Completed
+		patched.SetStatus(status, now)
+	}
+	return patched, nil
+}
+
+// The mergePatch function implements the RFC 7386 MergePatch algorithm on decoded json values.
+func mergePatch(target interface{}, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = make(map[string]interface{})
+	}
+	for k, v := range p {
+		if v == nil {
+			delete(t, k)
+		} else {
+			t[k] = mergePatch(t[k], v)
+		}
+	}
+	return t
+}
@@ -0,0 +1,58 @@
+package task
+
+import (
+	"testing"
+	"time"
+)
+
+// Tests applying merge patches to a task.
+func TestMergePatch(t *testing.T) {
+	now := time.Date(2016, 1, 2, 12, 0, 0, 0, time.UTC)
+	due := now.AddDate(0, 0, 1)
+	original := Task{
+		ID:          "id",
+		Title:       "title",
+		Description: "description",
+		Status:      StatusOpen,
+		Due:         &due,
+		CreatedAt:   &now,
+		CreatedBy:   "alice",
+	}
+
+	got, err := MergePatch(original, []byte(`{"title":"new title","due":null,"status":"done","created_at":null,"created_by":"bob"}`), now)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if got.Title != "new title" {
+		t.Errorf("expected title %q but got %q", "new title", got.Title)
+	}
+	if got.Description != original.Description {
+		t.Errorf("expected description %q but got %q", original.Description, got.Description)
+	}
+	if got.Due != nil {
+		t.Errorf("expected no due time but got %s", got.Due)
+	}
+	if got.Status != StatusDone || got.Completed == nil || !got.Completed.Equal(now) {
+		t.Errorf("expected done at %s but got %q at %v", now, got.Status, got.Completed)
+	}
+	if got.CreatedAt != original.CreatedAt || got.CreatedBy != original.CreatedBy {
+		t.Errorf("expected created at %s by %q but got %v by %q", now, original.CreatedBy, got.CreatedAt, got.CreatedBy)
+	}
+}
+
+// Tests rejecting invalid merge patches.
+func TestMergePatchInvalid(t *testing.T) {
+	original := Task{ID: "id", Status: StatusOpen}
+	for _, patch := range []string{
+		`not json`,
+		`null`,
+		`["title"]`,
+		`{"id":"other"}`,
+		`{"status":"finished"}`,
+		`{"title":7}`,
+	} {
+		if _, err := MergePatch(original, []byte(patch), time.Now()); err == nil {
+			t.Errorf("expected error for patch %s", patch)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package task
+
+// A Priority is the urgency of a task, from PriorityP0, the most urgent, to PriorityP4, the least. The empty Priority
+// means the task has no priority, and is less urgent than any other.
+type Priority string
+
+const (
+	PriorityP0 Priority = "P0"
+	PriorityP1 Priority = "P1"
+	PriorityP2 Priority = "P2"
+	PriorityP3 Priority = "P3"
+	PriorityP4 Priority = "P4"
+)
+
+// The Valid method returns true if p is a known priority, or empty.
+func (p Priority) Valid() bool {
+	switch p {
+	case "", PriorityP0, PriorityP1, PriorityP2, PriorityP3, PriorityP4:
+		return true
+	default:
+		return false
+	}
+}
+
+// The Before method returns true if p is more urgent than o.
+func (p Priority) Before(o Priority) bool {
+	if p == "" || o == "" {
+		return p != "" && o == ""
+	}
+	return p < o
+}
@@ -0,0 +1,22 @@
+package task
+
+import "testing"
+
+// Tests ordering priorities by urgency.
+func TestPriorityBefore(t *testing.T) {
+	for _, test := range []struct {
+		p, o   Priority
+		before bool
+	}{
+		{PriorityP0, PriorityP1, true},
+		{PriorityP1, PriorityP0, false},
+		{PriorityP4, "", true},
+		{"", PriorityP4, false},
+		{"", "", false},
+		{PriorityP2, PriorityP2, false},
+	} {
+		if got := test.p.Before(test.o); got != test.before {
+			t.Errorf("expected %q before %q to be %t but got %t", test.p, test.o, test.before, got)
+		}
+	}
+}
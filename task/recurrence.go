@@ -0,0 +1,409 @@
+package task
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A Frequency is how often a Rule repeats.
+type Frequency string
+
+// The supported frequencies.
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+)
+
+// A Weekday is a day of the week in a Rule's BYDAY. For Monthly rules, N selects the Nth such day of the month, or the
+// Nth from last if negative, and zero selects every such day.
+type Weekday struct {
+	Day time.Weekday
+	N   int
+}
+
+// The weekdays are the RFC 5545 abbreviations of each time.Weekday.
+var weekdays = [...]string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// The String method returns d in RFC 5545 form, like "MO" or "-1FR".
+func (d Weekday) String() string {
+	if d.N == 0 {
+		return weekdays[d.Day]
+	}
+	return strconv.Itoa(d.N) + weekdays[d.Day]
+}
+
+// A Rule is a recurrence rule, from the RFC 5545 RRULE subset supported by Task.Recurrence. The series of a Rule starts
+// at a given time, which is always the first occurrence.
+type Rule struct {
+	Freq Frequency
+
+	// Interval is the number of periods between repeats, e.g. 2 with Weekly for every other week. Zero means 1.
+	Interval int
+
+	// ByDay restricts Daily rules to these days of the week, and selects the days of the week of Weekly and Monthly
+	// rules. Weekly rules repeat on the day of the week of the start by default, and Monthly rules on its day of the
+	// month.
+	ByDay []Weekday
+
+	// Until is the last time of the series, inclusive, if not nil.
+	Until *time.Time
+
+	// Count is the number of occurrences in the series, including the start, if not zero.
+	Count int
+}
+
+// The untilLayouts are the accepted layouts of UNTIL values, which are UTC. A date is the last day of the series.
+var untilLayouts = []string{"20060102T150405Z", "20060102"}
+
+// The ParseRule function parses an RFC 5545 RRULE value, like "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE;COUNT=10", with an
+// optional "RRULE:" prefix. FREQ may be DAILY, WEEKLY, or MONTHLY, and INTERVAL, BYDAY, UNTIL, and COUNT are supported.
+// Returns an error wrapping ErrInvalid if s is malformed or unsupported.
+func ParseRule(s string) (*Rule, error) {
+	var r Rule
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(strings.TrimPrefix(s, "RRULE:"), ";") {
+		i := strings.Index(part, "=")
+		if i < 0 {
+			return nil, fmt.Errorf("%w: malformed recurrence rule part %q", ErrInvalid, part)
+		}
+		name, value := strings.ToUpper(part[:i]), part[i+1:]
+		if seen[name] {
+			return nil, fmt.Errorf("%w: duplicate recurrence rule part %q", ErrInvalid, name)
+		}
+		seen[name] = true
+		switch name {
+		case "FREQ":
+			r.Freq = Frequency(strings.ToUpper(value))
+			if r.Freq != Daily && r.Freq != Weekly && r.Freq != Monthly {
+				return nil, fmt.Errorf("%w: unsupported recurrence frequency %q. must be DAILY, WEEKLY, or MONTHLY", ErrInvalid, value)
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("%w: invalid recurrence interval %q. must be a positive integer", ErrInvalid, value)
+			}
+			r.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("%w: invalid recurrence count %q. must be a positive integer", ErrInvalid, value)
+			}
+			r.Count = n
+		case "UNTIL":
+			until, err := parseUntil(value)
+			if err != nil {
+				return nil, err
+			}
+			r.Until = &until
+		case "BYDAY":
+			for _, day := range strings.Split(value, ",") {
+				d, err := parseWeekday(day)
+				if err != nil {
+					return nil, err
+				}
+				r.ByDay = append(r.ByDay, d)
+			}
+		default:
+			return nil, fmt.Errorf("%w: unsupported recurrence rule part %q", ErrInvalid, name)
+		}
+	}
+	if r.Freq == "" {
+		return nil, fmt.Errorf("%w: recurrence rule %q has no FREQ", ErrInvalid, s)
+	}
+	if r.Count != 0 && r.Until != nil {
+		return nil, fmt.Errorf("%w: recurrence rule %q has both COUNT and UNTIL", ErrInvalid, s)
+	}
+	for _, d := range r.ByDay {
+		if d.N != 0 && r.Freq != Monthly {
+			return nil, fmt.Errorf("%w: recurrence day %q may only have an ordinal with FREQ=MONTHLY", ErrInvalid, d)
+		}
+	}
+	return &r, nil
+}
+
+// The parseUntil function parses an UNTIL value with the first matching layout from untilLayouts.
+func parseUntil(s string) (time.Time, error) {
+	if t, err := time.Parse(untilLayouts[0], s); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(untilLayouts[1], s); err == nil {
+		return t.AddDate(0, 0, 1).Add(-time.Nanosecond), nil
+	}
+	return time.Time{}, fmt.Errorf("%w: invalid recurrence until %q. must be like 20060102T150405Z or 20060102", ErrInvalid, s)
+}
+
+// The parseWeekday function parses a BYDAY value, like "MO", "2TU", or "-1FR".
+func parseWeekday(s string) (Weekday, error) {
+	var d Weekday
+	if len(s) < 2 {
+		return d, fmt.Errorf("%w: invalid recurrence day %q", ErrInvalid, s)
+	}
+	day := strings.ToUpper(s[len(s)-2:])
+	for i, w := range weekdays {
+		if w == day {
+			d.Day = time.Weekday(i)
+			if n := s[:len(s)-2]; n != "" {
+				var err error
+				if d.N, err = strconv.Atoi(n); err != nil || d.N == 0 || d.N < -5 || d.N > 5 {
+					return d, fmt.Errorf("%w: invalid recurrence day ordinal %q. must be 1 to 5, or -1 to -5", ErrInvalid, s)
+				}
+			}
+			return d, nil
+		}
+	}
+	return d, fmt.Errorf("%w: invalid recurrence day %q. must be one of SU, MO, TU, WE, TH, FR, or SA", ErrInvalid, s)
+}
+
+// The String method returns r as an RRULE value, without the "RRULE:" prefix.
+func (r Rule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, d := range r.ByDay {
+			days[i] = d.String()
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format(untilLayouts[0]))
+	}
+	if r.Count != 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	return strings.Join(parts, ";")
+}
+
+// The maxPeriods bounds the periods searched for occurrences, so that a rule which never matches again cannot search
+// forever.
+const maxPeriods = 10000
+
+// The each method calls fn with each occurrence of the series of r beginning at start, in order, until fn returns false
+// or the series ends. Occurrences have the same time of day, in the same location, as start.
+func (r Rule) each(start time.Time, fn func(time.Time) bool) {
+	n := 0
+	emit := func(t time.Time) bool {
+		if r.Until != nil && t.After(*r.Until) {
+			return false
+		}
+		n++
+		return fn(t) && (r.Count == 0 || n < r.Count)
+	}
+	if !emit(start) {
+		return
+	}
+	for k := 0; k < maxPeriods; k++ {
+		for _, t := range r.period(start, k) {
+			if t.After(start) && !emit(t) {
+				return
+			}
+		}
+	}
+}
+
+// The period method returns the candidate occurrences of the kth period of the series of r beginning at start, in order.
+func (r Rule) period(start time.Time, k int) []time.Time {
+	interval := r.Interval
+	if interval < 1 {
+		interval = 1
+	}
+	switch r.Freq {
+	case Daily:
+		day := start.AddDate(0, 0, k*interval)
+		if len(r.ByDay) == 0 || r.onDay(day.Weekday()) {
+			return []time.Time{day}
+		}
+		return nil
+	case Weekly:
+		// Weeks start on Monday.
+		monday := start.AddDate(0, 0, -mondayOffset(start.Weekday())+7*k*interval)
+		if len(r.ByDay) == 0 {
+			return []time.Time{monday.AddDate(0, 0, mondayOffset(start.Weekday()))}
+		}
+		var days []time.Time
+		for i := 0; i < 7; i++ {
+			if r.onDay(time.Weekday((i + 1) % 7)) {
+				days = append(days, monday.AddDate(0, 0, i))
+			}
+		}
+		return days
+	case Monthly:
+		first := time.Date(start.Year(), start.Month()+time.Month(k*interval), 1, start.Hour(), start.Minute(), start.Second(), start.Nanosecond(), start.Location())
+		if len(r.ByDay) == 0 {
+			if day := first.AddDate(0, 0, start.Day()-1); day.Month() == first.Month() {
+				return []time.Time{day}
+			}
+			return nil
+		}
+		var matches []time.Time
+		for _, d := range r.ByDay {
+			var month []time.Time
+			for day := first.AddDate(0, 0, (int(d.Day)-int(first.Weekday())+7)%7); day.Month() == first.Month(); day = day.AddDate(0, 0, 7) {
+				month = append(month, day)
+			}
+			switch {
+			case d.N == 0:
+				matches = append(matches, month...)
+			case d.N > 0 && d.N <= len(month):
+				matches = append(matches, month[d.N-1])
+			case d.N < 0 && -d.N <= len(month):
+				matches = append(matches, month[len(month)+d.N])
+			}
+		}
+		sort.Slice(matches, func(i, j int) bool {
+			return matches[i].Before(matches[j])
+		})
+		days := matches[:0]
+		for i, m := range matches {
+			if i == 0 || !m.Equal(matches[i-1]) {
+				days = append(days, m)
+			}
+		}
+		return days
+	}
+	return nil
+}
+
+// The mondayOffset function returns the number of days from Monday to d.
+func mondayOffset(d time.Weekday) int {
+	return (int(d) + 6) % 7
+}
+
+// The onDay method returns true if ByDay includes d.
+func (r Rule) onDay(d time.Weekday) bool {
+	for _, w := range r.ByDay {
+		if w.Day == d {
+			return true
+		}
+	}
+	return false
+}
+
+// The Between method returns the occurrences of the series of r beginning at start, at or after from, and before to.
+func (r Rule) Between(start, from, to time.Time) []time.Time {
+	var between []time.Time
+	r.each(start, func(t time.Time) bool {
+		if !t.Before(to) {
+			return false
+		}
+		if !t.Before(from) {
+			between = append(between, t)
+		}
+		return true
+	})
+	return between
+}
+
+// The After method returns the second occurrence of the series of r beginning at start, or false if there is none.
+func (r Rule) After(start time.Time) (time.Time, bool) {
+	var next time.Time
+	var ok bool
+	r.each(start, func(t time.Time) bool {
+		if t.After(start) {
+			next, ok = t, true
+			return false
+		}
+		return true
+	})
+	return next, ok
+}
+
+// The NextOccurrence function returns the next occurrence of t, a recurring task, if t was just done, having been old.
+// Returns nil if t is not recurring, was already done, already has a next occurrence, or its series has ended. The next
+// occurrence is due at the next time in the series after the due time of t, regardless of when t was done. It has the
+// title, description, priority, tags, list, parent, and owner of t, a start time the same time before its due time, and
+// the rest of the series. It has no id, so that stores generate one.
+func NextOccurrence(old, t Task) (*Task, error) {
+	if t.Recurrence == "" || t.Due == nil || t.Next != "" || t.Status != StatusDone || old.Status == StatusDone {
+		return nil, nil
+	}
+	rule, err := ParseRule(t.Recurrence)
+	if err != nil {
+		return nil, err
+	}
+	due, ok := rule.After(*t.Due)
+	if !ok {
+		return nil, nil
+	}
+	if rule.Count > 0 {
+		rule.Count--
+	}
+	next := &Task{
+		Title:       t.Title,
+		Description: t.Description,
+		Status:      StatusOpen,
+		Due:         &due,
+		Priority:    t.Priority,
+		Tags:        append([]string(nil), t.Tags...),
+		List:        t.List,
+		Parent:      t.Parent,
+		Recurrence:  rule.String(),
+		Owner:       t.Owner,
+	}
+	if t.Start != nil {
+		start := due.Add(t.Start.Sub(*t.Due))
+		next.Start = &start
+	}
+	return next, nil
+}
+
+// An Occurrence is a time a task is due. A recurring task has an Occurrence for each time in its series.
+type Occurrence struct {
+	Due  time.Time `json:"due"`
+	Task Task      `json:"task"`
+}
+
+// The MaxOccurrenceRange is the longest range of occurrences which may be requested at once.
+const MaxOccurrenceRange = 366 * 24 * time.Hour
+
+// The ValidateRange function returns an error wrapping ErrInvalid if to is not after from, or the range is longer than
+// MaxOccurrenceRange.
+func ValidateRange(from, to time.Time) error {
+	if !to.After(from) {
+		return fmt.Errorf("%w: occurrence range end %s is not after start %s", ErrInvalid, to.Format(time.RFC3339), from.Format(time.RFC3339))
+	}
+	if to.Sub(from) > MaxOccurrenceRange {
+		return fmt.Errorf("%w: occurrence range is longer than %d days", ErrInvalid, MaxOccurrenceRange/(24*time.Hour))
+	}
+	return nil
+}
+
+// The ExpandOccurrences function returns the occurrences of tasks at or after from, and before to, ordered by time, and
+// then task id. Tasks which are closed, or have no due time, have no occurrences.
+func ExpandOccurrences(tasks []Task, from, to time.Time) ([]Occurrence, error) {
+	if err := ValidateRange(from, to); err != nil {
+		return nil, err
+	}
+	var occurrences []Occurrence
+	for _, t := range tasks {
+		if t.Due == nil || t.Status.Closed() {
+			continue
+		}
+		if t.Recurrence == "" {
+			if !t.Due.Before(from) && t.Due.Before(to) {
+				occurrences = append(occurrences, Occurrence{Due: *t.Due, Task: t})
+			}
+			continue
+		}
+		rule, err := ParseRule(t.Recurrence)
+		if err != nil {
+			return nil, fmt.Errorf("task %q: %w", t.ID, err)
+		}
+		for _, due := range rule.Between(*t.Due, from, to) {
+			occurrences = append(occurrences, Occurrence{Due: due, Task: t})
+		}
+	}
+	sort.SliceStable(occurrences, func(i, j int) bool {
+		if !occurrences[i].Due.Equal(occurrences[j].Due) {
+			return occurrences[i].Due.Before(occurrences[j].Due)
+		}
+		return occurrences[i].Task.ID < occurrences[j].Task.ID
+	})
+	return occurrences, nil
+}
@@ -0,0 +1,156 @@
+package task
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Tests parsing supported rules, and formatting them back.
+func TestParseRule(t *testing.T) {
+	for _, test := range []struct {
+		rule, expected string
+	}{
+		{"FREQ=DAILY", "FREQ=DAILY"},
+		{"RRULE:freq=weekly;interval=2;byday=mo,we", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE"},
+		{"FREQ=MONTHLY;BYDAY=-1FR,+2TU;COUNT=12", "FREQ=MONTHLY;BYDAY=-1FR,2TU;COUNT=12"},
+		{"FREQ=DAILY;INTERVAL=1;UNTIL=20261231T120000Z", "FREQ=DAILY;UNTIL=20261231T120000Z"},
+		{"FREQ=DAILY;UNTIL=20261231", "FREQ=DAILY;UNTIL=20261231T235959Z"},
+	} {
+		r, err := ParseRule(test.rule)
+		if err != nil {
+			t.Errorf("unexpected error for %q: %s", test.rule, err)
+		} else if got := r.String(); got != test.expected {
+			t.Errorf("expected %q but got %q", test.expected, got)
+		}
+	}
+	for _, rule := range []string{
+		"",
+		"FREQ=YEARLY",
+		"INTERVAL=2",
+		"FREQ=DAILY;INTERVAL=0",
+		"FREQ=DAILY;COUNT=-1",
+		"FREQ=DAILY;COUNT=2;UNTIL=20261231",
+		"FREQ=DAILY;FREQ=WEEKLY",
+		"FREQ=WEEKLY;BYDAY=1MO",
+		"FREQ=MONTHLY;BYDAY=6MO",
+		"FREQ=MONTHLY;BYDAY=XX",
+		"FREQ=DAILY;BYHOUR=9",
+		"FREQ=DAILY;UNTIL=tomorrow",
+	} {
+		if _, err := ParseRule(rule); !errors.Is(err, ErrInvalid) {
+			t.Errorf("expected %v for %q but got %v", ErrInvalid, rule, err)
+		}
+	}
+}
+
+// Tests the occurrences of rules of each frequency.
+func TestRuleBetween(t *testing.T) {
+	// Monday.
+	start := time.Date(2026, 1, 5, 9, 30, 0, 0, time.UTC)
+	from, to := start, start.AddDate(0, 3, 0)
+	for _, test := range []struct {
+		rule     string
+		start    time.Time
+		expected []string
+	}{
+		{"FREQ=DAILY;INTERVAL=3;COUNT=4", start, []string{"01-05", "01-08", "01-11", "01-14"}},
+		{"FREQ=DAILY;BYDAY=SA,SU;COUNT=3", start, []string{"01-05", "01-10", "01-11"}},
+		{"FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR;UNTIL=20260201", start, []string{"01-05", "01-09", "01-19", "01-23"}},
+		{"FREQ=WEEKLY;COUNT=3", start.AddDate(0, 0, 2), []string{"01-07", "01-14", "01-21"}},
+		// Months without a 31st are skipped.
+		{"FREQ=MONTHLY;COUNT=3", time.Date(2026, 1, 31, 9, 30, 0, 0, time.UTC), []string{"01-31", "03-31"}},
+		{"FREQ=MONTHLY;BYDAY=-1FR;COUNT=3", start, []string{"01-05", "01-30", "02-27"}},
+		{"FREQ=MONTHLY;BYDAY=1MO,3MO;COUNT=4", start, []string{"01-05", "01-19", "02-02", "02-16"}},
+	} {
+		r, err := ParseRule(test.rule)
+		if err != nil {
+			t.Fatal("unexpected error: ", err)
+		}
+		var got []string
+		for _, o := range r.Between(test.start, from, to) {
+			if o.Hour() != 9 || o.Minute() != 30 {
+				t.Errorf("%s: expected occurrences at 09:30 but got %s", test.rule, o)
+			}
+			got = append(got, o.Format("01-02"))
+		}
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("%s: expected %v but got %v", test.rule, test.expected, got)
+		}
+	}
+}
+
+// Tests that only doing a recurring task creates its next occurrence, with the rest of its series.
+func TestNextOccurrence(t *testing.T) {
+	due := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+	start := due.Add(-time.Hour)
+	open := Task{ID: "a", Title: "Standup", Status: StatusOpen, Start: &start, Due: &due, BlockedBy: []string{"b"},
+		Recurrence: "FREQ=DAILY;BYDAY=MO,TU,WE,TH,FR;COUNT=5"}
+	done := open
+	done.Status = StatusDone
+
+	next, err := NextOccurrence(open, done)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	nextDue, nextStart := due.AddDate(0, 0, 1), start.AddDate(0, 0, 1)
+	expected := &Task{Title: "Standup", Status: StatusOpen, Start: &nextStart, Due: &nextDue, Recurrence: "FREQ=DAILY;BYDAY=MO,TU,WE,TH,FR;COUNT=4"}
+	if !reflect.DeepEqual(next, expected) {
+		t.Fatalf("expected %v but got %v", expected, next)
+	}
+
+	linked := done
+	linked.Next = "c"
+	last := done
+	last.Recurrence = "FREQ=DAILY;COUNT=1"
+	for _, test := range []struct {
+		old, t Task
+	}{
+		{open, open},
+		{done, done},
+		{open, linked},
+		{open, last},
+		{Task{}, Task{Status: StatusDone, Due: &due}},
+	} {
+		if next, err := NextOccurrence(test.old, test.t); err != nil || next != nil {
+			t.Errorf("expected no next occurrence of %v but got %v, %v", test.t, next, err)
+		}
+	}
+}
+
+// Tests expanding tasks into occurrences, ordered by time and then id.
+func TestExpandOccurrences(t *testing.T) {
+	from := time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 7)
+	at := func(day int) *time.Time {
+		d := time.Date(2026, 1, day, 9, 0, 0, 0, time.UTC)
+		return &d
+	}
+	tasks := []Task{
+		{ID: "daily", Due: at(4), Recurrence: "FREQ=DAILY;INTERVAL=2"},
+		{ID: "once", Due: at(6)},
+		{ID: "done", Due: at(6), Status: StatusDone},
+		{ID: "later", Due: at(20)},
+		{ID: "undated"},
+	}
+	occurrences, err := ExpandOccurrences(tasks, from, to)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	var got []string
+	for _, o := range occurrences {
+		got = append(got, o.Task.ID+" "+o.Due.Format("01-02"))
+	}
+	expected := []string{"daily 01-06", "once 01-06", "daily 01-08", "daily 01-10"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected %v but got %v", expected, got)
+	}
+
+	if _, err := ExpandOccurrences(tasks, to, from); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("expected %v but got %v", ErrInvalid, err)
+	}
+	if _, err := ExpandOccurrences(tasks, from, from.Add(MaxOccurrenceRange+time.Hour)); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("expected %v but got %v", ErrInvalid, err)
+	}
+}
@@ -0,0 +1,245 @@
+package task
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// A SearchResult is a task matching a search query, with its relevance Rank, and a Snippet of its title and
+// description with the matching words highlighted. Ranks are only comparable within the results of a single search.
+type SearchResult struct {
+	Task    Task    `json:"task"`
+	Rank    float64 `json:"rank"`
+	Snippet string  `json:"snippet"`
+}
+
+// The HighlightStart and HighlightStop strings surround each matching word of a Snippet.
+const (
+	HighlightStart = "**"
+	HighlightStop  = "**"
+)
+
+// The SnippetWords are the maximum number of words of a Snippet.
+const SnippetWords = 16
+
+// The TitleWeight and DescriptionWeight are the contributions to a task's rank of each occurrence of a query word in
+// its title, or description. They match the default weights of postgres' ts_rank for the 'A' and 'B' labels.
+const (
+	TitleWeight       = 1.0
+	DescriptionWeight = 0.4
+)
+
+// The SearchTerms function returns the distinct words of query, lower cased, in order. Words are runs of letters and
+// digits. Returns an error wrapping ErrInvalid if query has no words.
+func SearchTerms(query string) ([]string, error) {
+	var terms []string
+	seen := make(map[string]bool)
+	for _, w := range words(query) {
+		if !seen[w] {
+			seen[w] = true
+			terms = append(terms, w)
+		}
+	}
+	if len(terms) == 0 {
+		return nil, fmt.Errorf("%w: search query %q has no words", ErrInvalid, query)
+	}
+	return terms, nil
+}
+
+// The words function returns the words of s, lower cased.
+func words(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), notWordRune)
+}
+
+// The notWordRune function returns true if r is not part of a word.
+func notWordRune(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+// An Index is an in-process inverted index of the words of task titles and descriptions, for stores without native
+// full-text search. It must be kept up to date by adding every task written, and removing every task deleted. It is not
+// safe for concurrent writes.
+type Index struct {
+	// The postings map each word to the weighted number of its occurrences in each task, by task id.
+	postings map[string]map[string]float64
+	// The words map each task id to its distinct words, so that it can be removed from the postings.
+	words map[string][]string
+}
+
+// The NewIndex function returns a new empty Index.
+func NewIndex() *Index {
+	return &Index{postings: make(map[string]map[string]float64), words: make(map[string][]string)}
+}
+
+// The Add method indexes t, replacing any task with the same id.
+func (x *Index) Add(t Task) {
+	x.Remove(t.ID)
+	weights := make(map[string]float64)
+	for _, w := range words(t.Title) {
+		weights[w] += TitleWeight
+	}
+	for _, w := range words(t.Description) {
+		weights[w] += DescriptionWeight
+	}
+	ws := make([]string, 0, len(weights))
+	for w, weight := range weights {
+		ps, ok := x.postings[w]
+		if !ok {
+			ps = make(map[string]float64)
+			x.postings[w] = ps
+		}
+		ps[t.ID] = weight
+		ws = append(ws, w)
+	}
+	x.words[t.ID] = ws
+}
+
+// The Remove method removes the task with the given id from the index, if present.
+func (x *Index) Remove(id string) {
+	for _, w := range x.words[id] {
+		delete(x.postings[w], id)
+		if len(x.postings[w]) == 0 {
+			delete(x.postings, w)
+		}
+	}
+	delete(x.words, id)
+}
+
+// The Search method returns the ids of the tasks containing every one of terms, mapped to their ranks. A task's rank is
+// the weighted number of occurrences of the terms in it. See SearchTerms.
+func (x *Index) Search(terms []string) map[string]float64 {
+	if len(terms) == 0 {
+		return nil
+	}
+	ranks := make(map[string]float64)
+	for id, weight := range x.postings[terms[0]] {
+		ranks[id] = weight
+	}
+	for _, term := range terms[1:] {
+		ps := x.postings[term]
+		for id, rank := range ranks {
+			if weight, ok := ps[id]; ok {
+				ranks[id] = rank + weight
+			} else {
+				delete(ranks, id)
+			}
+		}
+	}
+	return ranks
+}
+
+// The SearchTasks function returns the results of searching tasks for query, ordered by SortResults. It indexes tasks
+// on the fly, so stores which search repeatedly should maintain an Index instead.
+func SearchTasks(tasks []Task, query string) ([]SearchResult, error) {
+	terms, err := SearchTerms(query)
+	if err != nil {
+		return nil, err
+	}
+	x := NewIndex()
+	for _, t := range tasks {
+		x.Add(t)
+	}
+	ranks := x.Search(terms)
+	var results []SearchResult
+	for _, t := range tasks {
+		if rank, ok := ranks[t.ID]; ok {
+			results = append(results, NewSearchResult(t, rank, terms))
+		}
+	}
+	SortResults(results)
+	return results, nil
+}
+
+// The NewSearchResult function returns the SearchResult for t, with rank, and a Snippet highlighting terms.
+func NewSearchResult(t Task, rank float64, terms []string) SearchResult {
+	return SearchResult{Task: t, Rank: rank, Snippet: Snippet(t, terms)}
+}
+
+// The SortResults function orders results by descending rank, and then by task id.
+func SortResults(results []SearchResult) {
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Rank != results[j].Rank {
+			return results[i].Rank > results[j].Rank
+		}
+		return results[i].Task.ID < results[j].Task.ID
+	})
+}
+
+// The Snippet function returns up to SnippetWords whitespace separated words of t's title followed by its description,
+// starting shortly before the first word matching one of terms, or all of them if there are fewer, with each matching
+// word surrounded by HighlightStart and HighlightStop. Elided text is replaced by "...".
+func Snippet(t Task, terms []string) string {
+	match := make(map[string]bool, len(terms))
+	for _, term := range terms {
+		match[term] = true
+	}
+	fields := strings.Fields(t.Title + " " + t.Description)
+	first := 0
+	for i, f := range fields {
+		if matchesAny(f, match) {
+			first = i
+			break
+		}
+	}
+	// Keep some context before the first match, and fill the snippet when the match is near the end.
+	start := first - SnippetWords/4
+	if last := len(fields) - SnippetWords; start > last {
+		start = last
+	}
+	if start < 0 {
+		start = 0
+	}
+	end := start + SnippetWords
+	if end > len(fields) {
+		end = len(fields)
+	}
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("... ")
+	}
+	for i, f := range fields[start:end] {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		highlight(&b, f, match)
+	}
+	if end < len(fields) {
+		b.WriteString(" ...")
+	}
+	return b.String()
+}
+
+// The matchesAny function returns true if any word of field is in match.
+func matchesAny(field string, match map[string]bool) bool {
+	for _, w := range words(field) {
+		if match[w] {
+			return true
+		}
+	}
+	return false
+}
+
+// The highlight function writes field to b, with each word in match surrounded by HighlightStart and HighlightStop.
+func highlight(b *strings.Builder, field string, match map[string]bool) {
+	for field != "" {
+		i := strings.IndexFunc(field, func(r rune) bool { return !notWordRune(r) })
+		if i < 0 {
+			b.WriteString(field)
+			return
+		}
+		b.WriteString(field[:i])
+		field = field[i:]
+		j := strings.IndexFunc(field, notWordRune)
+		if j < 0 {
+			j = len(field)
+		}
+		if w := field[:j]; match[strings.ToLower(w)] {
+			b.WriteString(HighlightStart + w + HighlightStop)
+		} else {
+			b.WriteString(w)
+		}
+		field = field[j:]
+	}
+}
@@ -0,0 +1,102 @@
+package task
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Tests splitting queries into distinct lower cased words.
+func TestSearchTerms(t *testing.T) {
+	for _, test := range []struct {
+		query    string
+		expected []string
+	}{
+		{"milk", []string{"milk"}},
+		{"Buy MILK, buy bread!", []string{"buy", "milk", "bread"}},
+		{"Über-café 2016", []string{"über", "café", "2016"}},
+	} {
+		if got, err := SearchTerms(test.query); err != nil {
+			t.Errorf("unexpected error for %q: %s", test.query, err)
+		} else if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("expected %v for %q but got %v", test.expected, test.query, got)
+		}
+	}
+	for _, query := range []string{"", "  ", "?!-"} {
+		if _, err := SearchTerms(query); !errors.Is(err, ErrInvalid) {
+			t.Errorf("expected %v for %q but got %v", ErrInvalid, query, err)
+		}
+	}
+}
+
+// Tests that an index ranks tasks containing every term, and follows replaced and removed tasks.
+func TestIndex(t *testing.T) {
+	x := NewIndex()
+	x.Add(Task{ID: "a", Title: "Milk milk", Description: "bread"})
+	x.Add(Task{ID: "b", Title: "Bread", Description: "milk"})
+	x.Add(Task{ID: "c", Title: "Eggs"})
+
+	expected := map[string]float64{"a": 2 * TitleWeight, "b": DescriptionWeight}
+	if got := x.Search([]string{"milk"}); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected %v but got %v", expected, got)
+	}
+	expected = map[string]float64{"a": 2*TitleWeight + DescriptionWeight, "b": TitleWeight + DescriptionWeight}
+	if got := x.Search([]string{"milk", "bread"}); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected %v but got %v", expected, got)
+	}
+
+	x.Add(Task{ID: "a", Title: "Eggs"})
+	x.Remove("b")
+	if got := x.Search([]string{"milk"}); len(got) != 0 {
+		t.Fatalf("expected no results but got %v", got)
+	}
+	expected = map[string]float64{"a": TitleWeight, "c": TitleWeight}
+	if got := x.Search([]string{"eggs"}); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected %v but got %v", expected, got)
+	}
+	if len(x.postings) != 1 || len(x.words) != 2 {
+		t.Fatalf("expected removed words to be dropped, but got postings %v and words %v", x.postings, x.words)
+	}
+}
+
+// Tests searching tasks without a maintained index, ordered by rank and then id.
+func TestSearchTasks(t *testing.T) {
+	results, err := SearchTasks([]Task{
+		{ID: "c", Description: "milk"},
+		{ID: "b", Title: "milk"},
+		{ID: "a", Description: "milk"},
+		{ID: "d", Title: "bread"},
+	}, "Milk")
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	var got []string
+	for _, r := range results {
+		got = append(got, r.Task.ID)
+	}
+	if expected := []string{"b", "a", "c"}; !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected %v but got %v", expected, got)
+	}
+}
+
+// Tests highlighting matching words in snippets, and eliding long text.
+func TestSnippet(t *testing.T) {
+	long := strings.Repeat("word ", 20)
+	for _, test := range []struct {
+		task     Task
+		expected string
+	}{
+		{Task{Title: "Buy Milk", Description: "whole milk, not skimmed"}, "Buy **Milk** whole **milk**, not skimmed"},
+		{Task{Title: "Groceries", Description: "(milk)"}, "Groceries (**milk**)"},
+		{Task{Title: "Milk", Description: long}, "**Milk** " + strings.TrimSpace(strings.Repeat("word ", SnippetWords-1)) + " ..."},
+		{Task{Title: "Chores", Description: long + "milk " + long}, "... " + strings.Repeat("word ", SnippetWords/4) + "**milk** " +
+			strings.TrimSpace(strings.Repeat("word ", SnippetWords-SnippetWords/4-1)) + " ..."},
+		{Task{Title: "Chores", Description: long + "milk the cow"}, "... " + strings.Repeat("word ", SnippetWords-3) + "**milk** the cow"},
+		{Task{Title: "Call the farm", Description: "Ask about milk delivery"}, "Call the farm Ask about **milk** delivery"},
+	} {
+		if got := Snippet(test.task, []string{"milk"}); got != test.expected {
+			t.Errorf("expected %q but got %q", test.expected, got)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package task
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// The MaxTagLength is the maximum length of a tag, in bytes.
+const MaxTagLength = 64
+
+// The ValidateTag function returns an error wrapping ErrInvalid if tag is not a valid tag. Tags must be non-empty, at
+// most MaxTagLength bytes, and may not contain whitespace or commas.
+func ValidateTag(tag string) error {
+	if tag == "" {
+		return fmt.Errorf("%w: empty tag", ErrInvalid)
+	}
+	if len(tag) > MaxTagLength {
+		return fmt.Errorf("%w: tag %q is longer than %d bytes", ErrInvalid, tag, MaxTagLength)
+	}
+	if strings.IndexFunc(tag, func(r rune) bool { return r == ',' || unicode.IsSpace(r) }) >= 0 {
+		return fmt.Errorf("%w: tag %q contains whitespace or a comma", ErrInvalid, tag)
+	}
+	return nil
+}
+
+// The NormalizeTags function returns tags sorted, and without duplicates, or nil if there are none. Tags are a set, so
+// stores normalize them before writing.
+func NormalizeTags(tags []string) []string {
+	if len(tags) == 0 {
+		return nil
+	}
+	normalized := append([]string(nil), tags...)
+	sort.Strings(normalized)
+	n := 1
+	for _, tag := range normalized[1:] {
+		if tag != normalized[n-1] {
+			normalized[n] = tag
+			n++
+		}
+	}
+	return normalized[:n]
+}
+
+// The HasTag method returns true if t has tag.
+func (t *Task) HasTag(tag string) bool {
+	for _, tt := range t.Tags {
+		if tt == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// A TagCount is a tag, and the number of tasks with it.
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+// The CountTags function counts the tags of tasks, and returns them ordered by tag.
+func CountTags(tasks []Task) []TagCount {
+	counts := make(map[string]int)
+	for _, t := range tasks {
+		for _, tag := range t.Tags {
+			counts[tag]++
+		}
+	}
+	tagCounts := make([]TagCount, 0, len(counts))
+	for tag, count := range counts {
+		tagCounts = append(tagCounts, TagCount{Tag: tag, Count: count})
+	}
+	sort.Slice(tagCounts, func(i, j int) bool {
+		return tagCounts[i].Tag < tagCounts[j].Tag
+	})
+	return tagCounts
+}
@@ -0,0 +1,51 @@
+package task
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Tests normalizing tags to a sorted set.
+func TestNormalizeTags(t *testing.T) {
+	for _, test := range []struct {
+		tags     []string
+		expected []string
+	}{
+		{nil, nil},
+		{[]string{}, nil},
+		{[]string{"b", "a", "b", "c", "a"}, []string{"a", "b", "c"}},
+	} {
+		if got := NormalizeTags(test.tags); !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("expected %v but got %v", test.expected, got)
+		}
+	}
+}
+
+// Tests that invalid tags are rejected.
+func TestValidateTag(t *testing.T) {
+	for _, tag := range []string{"backend", "ops-2016", "über"} {
+		if err := ValidateTag(tag); err != nil {
+			t.Errorf("unexpected error for %q: %s", tag, err)
+		}
+	}
+	for _, tag := range []string{"", "two words", "a,b", "tab\t", strings.Repeat("x", MaxTagLength+1)} {
+		if err := ValidateTag(tag); !errors.Is(err, ErrInvalid) {
+			t.Errorf("expected %v for %q but got %v", ErrInvalid, tag, err)
+		}
+	}
+}
+
+// Tests counting tags.
+func TestCountTags(t *testing.T) {
+	got := CountTags([]Task{
+		{Tags: []string{"ops", "backend"}},
+		{Tags: []string{"ops"}},
+		{},
+	})
+	expected := []TagCount{{"backend", 1}, {"ops", 2}}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected %v but got %v", expected, got)
+	}
+}
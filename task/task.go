@@ -1,6 +1,13 @@
 // Package task provides the Task data structure, and defines TaskInterface for interacting with Tasks.
 package task
 
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
 // A Task is a single todo item.
 type Task struct {
 
@@ -12,20 +19,277 @@ type Task struct {
 
 	// Description is the main body of this task.
 	Description string `json:"description"`
+
+	// Status is the current state of this task. The empty status is treated as StatusOpen.
+	Status Status `json:"status,omitempty"`
+
+	// Completed is the time this task was done or cancelled, or nil if it is still open or in progress.
+	Completed *time.Time `json:"completed,omitempty"`
+
+	// Start is the optional time work on this task may begin.
+	Start *time.Time `json:"start,omitempty"`
+
+	// Due is the optional deadline of this task.
+	Due *time.Time `json:"due,omitempty"`
+
+	// Priority is the optional urgency of this task.
+	Priority Priority `json:"priority,omitempty"`
+
+	// Tags are an optional set of labels for organizing tasks, e.g. by area of work. See NormalizeTags.
+	Tags []string `json:"tags,omitempty"`
+
+	// List is the id of the TaskList this task belongs to, or empty if it belongs to none.
+	List string `json:"list,omitempty"`
+
+	// Parent is the id of the task this is a subtask of, or empty if it is a top level task.
+	Parent string `json:"parent,omitempty"`
+
+	// BlockedBy are the ids of the tasks which must be closed before this task is actionable. See NormalizeIDs.
+	BlockedBy []string `json:"blocked_by,omitempty"`
+
+	// Recurrence is an optional RFC 5545 RRULE, e.g. "FREQ=WEEKLY;BYDAY=MO", repeating this task from its due time.
+	// See ParseRule and NextOccurrence.
+	Recurrence string `json:"recurrence,omitempty"`
+
+	// Next is the id of the next occurrence of this recurring task, generated when it was done, or empty.
+	Next string `json:"next,omitempty"`
+
+	// CreatedAt is the time this task was created. It is assigned by the store, and may not be set by clients. See Stamp.
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+
+	// UpdatedAt is the time this task was last written. It is assigned by the store, and may not be set by clients.
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+
+	// CreatedBy is the user who created this task, or empty if unknown. It is assigned by the store, from the user of
+	// the creating request. See WithUser.
+	CreatedBy string `json:"created_by,omitempty"`
+
+	// Revision is the version of this task, which starts at 1, and is incremented by every change. It is assigned by
+	// the store, and may not be set by clients. See IfMatch.
+	Revision int64 `json:"revision,omitempty"`
+
+	// Owner is the user this task belongs to, or empty if it belongs to no one. Only the owner and admins may read or
+	// write it. It is assigned by the store, from the user of the creating request, unless set by an admin. See Visible
+	// and AssignOwner.
+	Owner string `json:"owner,omitempty"`
+}
+
+// The Overdue method returns true if t has a due time before now, and is not yet done or cancelled.
+func (t *Task) Overdue(now time.Time) bool {
+	return t.Due != nil && t.Due.Before(now) && !t.Status.Closed()
+}
+
+// The ValidateID function returns an error wrapping ErrInvalid if id is not a valid task id. Ids must be non-empty, and
+// may not contain '/', since they are used as url path segments. Ids beginning with '_', and the ids "lists" and
+// "events", are reserved for other server resources, like "_tags".
+func ValidateID(id string) error {
+	if id == "" {
+		return fmt.Errorf("%w: no id specified", ErrInvalid)
+	}
+	if strings.Contains(id, "/") {
+		return fmt.Errorf("%w: id %q contains '/'", ErrInvalid, id)
+	}
+	if strings.HasPrefix(id, "_") {
+		return fmt.Errorf("%w: id %q begins with reserved '_'", ErrInvalid, id)
+	}
+	if id == "lists" || id == "events" {
+		return fmt.Errorf("%w: id %q is reserved", ErrInvalid, id)
+	}
+	return nil
 }
 
-// The TaskInterface provides an interface for getting, putting, and deleting tasks.
+// The Validate method returns an error wrapping ErrInvalid if t is not a valid task.
+func (t *Task) Validate() error {
+	if err := ValidateID(t.ID); err != nil {
+		return err
+	}
+	if !t.Status.Valid() {
+		return fmt.Errorf("%w: unknown status %q", ErrInvalid, t.Status)
+	}
+	if !t.Priority.Valid() {
+		return fmt.Errorf("%w: unknown priority %q. must be one of P0 to P4", ErrInvalid, t.Priority)
+	}
+	for _, tag := range t.Tags {
+		if err := ValidateTag(tag); err != nil {
+			return err
+		}
+	}
+	if t.List != "" {
+		if err := ValidateID(t.List); err != nil {
+			return fmt.Errorf("invalid list: %w", err)
+		}
+	}
+	if t.Parent != "" {
+		if err := ValidateID(t.Parent); err != nil {
+			return fmt.Errorf("invalid parent: %w", err)
+		}
+		if t.Parent == t.ID {
+			return fmt.Errorf("%w: task %q cannot be its own parent", ErrInvalid, t.ID)
+		}
+	}
+	for _, b := range t.BlockedBy {
+		if err := ValidateID(b); err != nil {
+			return fmt.Errorf("invalid blocker: %w", err)
+		}
+		if b == t.ID {
+			return fmt.Errorf("%w: task %q cannot block itself", ErrCycle, t.ID)
+		}
+	}
+	if t.Recurrence != "" {
+		if _, err := ParseRule(t.Recurrence); err != nil {
+			return err
+		}
+		if t.Due == nil {
+			return fmt.Errorf("%w: recurring task %q has no due time", ErrInvalid, t.ID)
+		}
+	}
+	if t.Next != "" {
+		if err := ValidateID(t.Next); err != nil {
+			return fmt.Errorf("invalid next occurrence: %w", err)
+		}
+	}
+	return nil
+}
+
+// A Status is the state of a task in its lifecycle.
+type Status string
+
+const (
+	// StatusOpen is the status of a task which has not been started.
+	StatusOpen Status = "open"
+
+	// StatusInProgress is the status of a task which has been started, but not finished.
+	StatusInProgress Status = "in-progress"
+
+	// StatusDone is the status of a finished task.
+	StatusDone Status = "done"
+
+	// StatusCancelled is the status of a task which will not be finished.
+	StatusCancelled Status = "cancelled"
+)
+
+// The Valid method returns true if s is a known status, or empty.
+func (s Status) Valid() bool {
+	switch s {
+	case "", StatusOpen, StatusInProgress, StatusDone, StatusCancelled:
+		return true
+	}
+	return false
+}
+
+// The Closed method returns true if s is StatusDone or StatusCancelled.
+func (s Status) Closed() bool {
+	return s == StatusDone || s == StatusCancelled
+}
+
+// The SetStatus method updates the status of t, and sets or clears Completed as the status is closed or reopened.
+func (t *Task) SetStatus(status Status, now time.Time) {
+	if status == "" {
+		status = StatusOpen
+	}
+	if status.Closed() {
+		if !t.Status.Closed() || t.Completed == nil {
+			t.Completed = &now
+		}
+	} else {
+		t.Completed = nil
+	}
+	t.Status = status
+}
+
+// The TaskInterface provides an interface for getting, putting, and deleting tasks. Methods addressing a single task by
+// id return an error wrapping ErrNotFound if no such task exists, or ErrInvalid if the id fails ValidateID, and methods
+// writing tasks return an error wrapping ErrInvalid if the task fails validation, belongs to a TaskList which does not
+// exist, or has a parent or blocker which does not exist, or a parent which is one of its own subtasks. Writes which
+// would make a task depend on itself return an error wrapping ErrCycle. Deleting a task removes it from the BlockedBy
+// of other tasks. Methods writing tasks Stamp them, ignoring any CreatedAt, UpdatedAt, CreatedBy, or Revision supplied,
+// and record the User of the context as CreatedBy. Clearing the parent or blockers of a task when they are deleted also
+// increments its Revision. Put, Update, SetStatus, Delete, and DeleteTree are conditional when their context is from
+// IfMatch, and return an error wrapping ErrRevisionMismatch if the task is at a different revision, or does not exist.
+// Lists of tasks are ordered by id. The tasktest package provides a conformance suite for implementations. Every method
+// accepts a context.Context, which implementations use to cancel work, and to bound it with a deadline.
 type TaskInterface interface {
 
 	// The Get method looks up a single task by id.
-	Get(id string) (*Task, error)
+	Get(ctx context.Context, id string) (*Task, error)
 
 	// The GetAll method lists all tasks.
-	GetAll() ([]Task, error)
+	GetAll(ctx context.Context) ([]Task, error)
+
+	// The Find method lists all tasks matching a Filter.
+	Find(ctx context.Context, filter Filter) ([]Task, error)
+
+	// The List method lists a single page of tasks. Returns an error wrapping ErrInvalid if the options are invalid.
+	List(ctx context.Context, options ListOptions) (*Page, error)
+
+	// The Actionable method lists all tasks matching a Filter which are not closed, and whose blockers are all closed,
+	// ordered by id.
+	Actionable(ctx context.Context, filter Filter) ([]Task, error)
+
+	// The Ordered method lists all tasks matching a Filter, ordered so that every task follows the tasks blocking it,
+	// directly or indirectly. Otherwise, tasks are ordered by id.
+	Ordered(ctx context.Context, filter Filter) ([]Task, error)
+
+	// The Occurrences method lists the occurrences of the tasks matching a Filter, at or after from, and before to. See
+	// ExpandOccurrences. Returns an error wrapping ErrInvalid if the range fails ValidateRange.
+	Occurrences(ctx context.Context, from, to time.Time, filter Filter) ([]Occurrence, error)
+
+	// The Tags method lists every tag in use, with the number of tasks with it, ordered by tag.
+	Tags(ctx context.Context) ([]TagCount, error)
+
+	// The Search method lists the tasks matching a Filter whose titles or descriptions contain every word of query,
+	// ordered by SortResults. Returns an error wrapping ErrInvalid if query has no words. See SearchTerms.
+	Search(ctx context.Context, query string, filter Filter) ([]SearchResult, error)
+
+	// The Put method creates or replaces a single task, and returns the task's id, and whether it was created.
+	Put(ctx context.Context, task Task) (id string, created bool, err error)
+
+	// The Create method adds a single new task, and returns the task's id. Returns ErrConflict if a task already exists
+	// with the same id.
+	Create(ctx context.Context, task Task) (id string, err error)
+
+	// The Update method applies an RFC 7386 JSON merge patch to a single task by id, and returns the updated task. If a
+	// recurring task is done, its next occurrence is created, as by SetStatus.
+	Update(ctx context.Context, id string, patch []byte) (*Task, error)
+
+	// The SetStatus method updates the status of a single task by id, and returns the updated task. If a recurring task
+	// is done, its NextOccurrence is created too, and its Next is set to the new task's id.
+	SetStatus(ctx context.Context, id string, status Status) (*Task, error)
+
+	// The Children method returns the trees of the subtasks of a single task by id, recursively.
+	Children(ctx context.Context, id string) ([]Tree, error)
+
+	// The Delete method deletes a single task by id. Its subtasks are kept, as top level tasks.
+	Delete(ctx context.Context, id string) error
+
+	// The DeleteTree method deletes a single task by id, along with all of its subtasks, recursively. Nothing is deleted,
+	// and an error wrapping ErrForbidden is returned, if the user may not edit one of the subtasks.
+	DeleteTree(ctx context.Context, id string) error
+
+	// The GetList method looks up a single TaskList by id.
+	GetList(ctx context.Context, id string) (*TaskList, error)
+
+	// The GetLists method lists all TaskLists, ordered by id.
+	GetLists(ctx context.Context) ([]TaskList, error)
+
+	// The PutList method creates or replaces a single TaskList, and returns the list's id, and whether it was created.
+	PutList(ctx context.Context, list TaskList) (id string, created bool, err error)
+
+	// The DeleteList method deletes a single TaskList by id, along with all of its tasks. Subtasks of those tasks in
+	// other lists are kept, as top level tasks.
+	DeleteList(ctx context.Context, id string) error
+
+	// The Share method adds grant to the TaskList with the given id, replacing any grant to the same grantee, and
+	// returns the list. Only owners may share a list. See ListRole.
+	Share(ctx context.Context, list string, grant Grant) (*TaskList, error)
 
-	// The Put method adds a single task, and returns the task's id.
-	Put(Task) (id string, err error)
+	// The Unshare method removes the grant to grantee from the TaskList with the given id, if any, and returns the list.
+	// Only owners may unshare a list.
+	Unshare(ctx context.Context, list string, grantee Grantee) (*TaskList, error)
 
-	// The Delete method deletes a single task by id.
-	Delete(id string) error
+	// The Watch method returns a channel of the Events of changes to the tasks visible to ctx, in order, starting after
+	// the event with id after, or with the next change if after is 0. Returns an error wrapping ErrExpired if the event
+	// with id after is no longer retained. The channel is closed when ctx is done, or when the watcher falls too far
+	// behind, or fails, in which case watching may resume after the last event received.
+	Watch(ctx context.Context, after int64) (<-chan Event, error)
 }
@@ -0,0 +1,74 @@
+package task
+
+import (
+	"fmt"
+	"sort"
+)
+
+// A Tree is a task, and the trees of its subtasks, ordered by id.
+type Tree struct {
+	Task
+
+	// Children are the trees of the tasks whose Parent is this task.
+	Children []Tree `json:"children,omitempty"`
+}
+
+// The ChildTrees function returns the trees of the children of the task with id parent, built from tasks, which should
+// include all of its descendants. Tasks which are not descendants of parent are ignored.
+func ChildTrees(tasks []Task, parent string) []Tree {
+	byParent := make(map[string][]Task)
+	for _, t := range tasks {
+		if t.Parent != "" {
+			byParent[t.Parent] = append(byParent[t.Parent], t)
+		}
+	}
+	return childTrees(byParent, parent, map[string]bool{parent: true})
+}
+
+// The childTrees function returns the trees of the children of parent from byParent. Tasks already in seen are skipped,
+// so that a corrupt cycle cannot recurse forever.
+func childTrees(byParent map[string][]Task, parent string, seen map[string]bool) []Tree {
+	children := byParent[parent]
+	if len(children) == 0 {
+		return nil
+	}
+	sort.Slice(children, func(i, j int) bool {
+		return children[i].ID < children[j].ID
+	})
+	trees := make([]Tree, 0, len(children))
+	for _, c := range children {
+		if seen[c.ID] {
+			continue
+		}
+		seen[c.ID] = true
+		trees = append(trees, Tree{Task: c, Children: childTrees(byParent, c.ID, seen)})
+	}
+	return trees
+}
+
+// The CheckParent function returns an error wrapping ErrInvalid if t has a parent which does not exist, or which would
+// make t its own ancestor. The parent function looks up the parent id of a task, and whether the task exists.
+func CheckParent(t Task, parent func(id string) (string, bool)) error {
+	if t.Parent == "" {
+		return nil
+	}
+	seen := map[string]bool{}
+	for id := t.Parent; id != ""; {
+		if id == t.ID {
+			return fmt.Errorf("%w: parent %q of task %q is its own subtask", ErrInvalid, t.Parent, t.ID)
+		}
+		if seen[id] {
+			break
+		}
+		seen[id] = true
+		next, ok := parent(id)
+		if !ok {
+			if id == t.Parent {
+				return fmt.Errorf("%w: unknown parent %q", ErrInvalid, t.Parent)
+			}
+			break
+		}
+		id = next
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package task
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// Tests building nested trees of subtasks, ordered by id.
+func TestChildTrees(t *testing.T) {
+	tasks := []Task{
+		{ID: "list"},
+		{ID: "milk", Parent: "list"},
+		{ID: "eggs", Parent: "list"},
+		{ID: "free-range", Parent: "eggs"},
+		{ID: "other"},
+	}
+	expected := []Tree{
+		{Task: Task{ID: "eggs", Parent: "list"}, Children: []Tree{{Task: Task{ID: "free-range", Parent: "eggs"}}}},
+		{Task: Task{ID: "milk", Parent: "list"}},
+	}
+	if got := ChildTrees(tasks, "list"); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected %v but got %v", expected, got)
+	}
+	if got := ChildTrees(tasks, "other"); got != nil {
+		t.Fatalf("expected no children but got %v", got)
+	}
+
+	// A corrupt cycle is not followed forever.
+	cycle := []Task{{ID: "a", Parent: "b"}, {ID: "b", Parent: "a"}}
+	if got := ChildTrees(cycle, "a"); len(got) != 1 || len(got[0].Children) != 0 {
+		t.Fatalf("expected a single child but got %v", got)
+	}
+}
+
+// Tests that unknown parents, and parents which are subtasks, are rejected.
+func TestCheckParent(t *testing.T) {
+	parents := map[string]string{"a": "", "b": "a", "c": "b"}
+	parent := func(id string) (string, bool) {
+		p, ok := parents[id]
+		return p, ok
+	}
+	for _, tk := range []Task{{ID: "new"}, {ID: "new", Parent: "c"}, {ID: "b", Parent: "a"}, {ID: "a"}} {
+		if err := CheckParent(tk, parent); err != nil {
+			t.Errorf("unexpected error for %v: %s", tk, err)
+		}
+	}
+	for _, tk := range []Task{{ID: "new", Parent: "missing"}, {ID: "a", Parent: "c"}, {ID: "b", Parent: "c"}} {
+		if err := CheckParent(tk, parent); !errors.Is(err, ErrInvalid) {
+			t.Errorf("expected %v for %v but got %v", ErrInvalid, tk, err)
+		}
+	}
+}
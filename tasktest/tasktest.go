@@ -0,0 +1,1643 @@
+// Package tasktest provides a conformance test suite for implementations of task.TaskInterface.
+//
+// Every implementation should pass the suite, so that callers observe the same behavior regardless of the backend:
+//
+//	func TestConformance(t *testing.T) {
+//		tasktest.RunConformance(t, func(t *testing.T) task.TaskInterface {
+//			return NewMemstore()
+//		})
+//	}
+package tasktest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jmank88/todo/task"
+)
+
+// A Factory returns a new, empty task.TaskInterface for a single test. Any cleanup should be registered with t.Cleanup.
+type Factory func(t *testing.T) task.TaskInterface
+
+// The RunConformance function runs the conformance suite as subtests of t. Each subtest calls factory for a new store.
+func RunConformance(t *testing.T, factory Factory) {
+	for _, test := range []struct {
+		name string
+		fn   func(*testing.T, task.TaskInterface)
+	}{
+		{"PutGet", testPutGet},
+		{"PutGeneratedID", testPutGeneratedID},
+		{"PutReplace", testPutReplace},
+		{"CreateConflict", testCreateConflict},
+		{"Missing", testMissing},
+		{"InvalidID", testInvalidID},
+		{"InvalidTask", testInvalidTask},
+		{"Update", testUpdate},
+		{"SetStatus", testSetStatus},
+		{"Delete", testDelete},
+		{"GetAll", testGetAll},
+		{"Find", testFind},
+		{"List", testList},
+		{"ListInvalid", testListInvalid},
+		{"Tags", testTags},
+		{"Lists", testLists},
+		{"ListsInvalid", testListsInvalid},
+		{"Subtasks", testSubtasks},
+		{"SubtasksDelete", testSubtasksDelete},
+		{"SubtasksInvalid", testSubtasksInvalid},
+		{"Dependencies", testDependencies},
+		{"DependenciesInvalid", testDependenciesInvalid},
+		{"Recurrence", testRecurrence},
+		{"RecurrenceInvalid", testRecurrenceInvalid},
+		{"Timestamps", testTimestamps},
+		{"Revisions", testRevisions},
+		{"Search", testSearch},
+		{"Unicode", testUnicode},
+		{"LargeDescription", testLargeDescription},
+		{"ConcurrentWriters", testConcurrentWriters},
+		{"Watch", testWatch},
+		{"WatchDelete", testWatchDelete},
+	} {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			test.fn(t, factory(t))
+		})
+	}
+}
+
+// An As function returns the context and task.TaskInterface for acting as user, or as an admin user if admin is true.
+// Every user shares the same store.
+type As func(user string, admin bool) (context.Context, task.TaskInterface)
+
+// A UsersFactory returns an As function for a new, empty store for a single test. Any cleanup should be registered with
+// t.Cleanup.
+type UsersFactory func(t *testing.T) As
+
+// The Groups are the members of each group the users of the isolation tests belong to, by group name.
+var Groups = map[string][]string{"team": {"carol"}}
+
+// The ContextUsers function returns a UsersFactory for stores created by factory, which act as users with contexts
+// carrying them, and their Groups. See task.WithUser, task.AsAdmin, and task.WithGroups.
+func ContextUsers(factory Factory) UsersFactory {
+	return func(t *testing.T) As {
+		ti := factory(t)
+		return func(user string, admin bool) (context.Context, task.TaskInterface) {
+			ctx := task.WithUser(context.Background(), user)
+			if admin {
+				ctx = task.AsAdmin(ctx)
+			}
+			var groups []string
+			for group, members := range Groups {
+				for _, member := range members {
+					if member == user {
+						groups = append(groups, group)
+					}
+				}
+			}
+			if len(groups) > 0 {
+				ctx = task.WithGroups(ctx, groups)
+			}
+			return ctx, ti
+		}
+	}
+}
+
+// The RunIsolation function runs the suite of tests isolating the tasks and lists of users from each other, as subtests
+// of t. Each subtest calls factory for a new store.
+func RunIsolation(t *testing.T, factory UsersFactory) {
+	for _, test := range []struct {
+		name string
+		fn   func(*testing.T, As)
+	}{
+		{"Reads", testIsolatedReads},
+		{"Writes", testIsolatedWrites},
+		{"Owners", testOwners},
+		{"Admin", testAdmin},
+		{"Sharing", testSharing},
+		{"DeleteTree", testIsolatedDeleteTree},
+		{"Watch", testIsolatedWatch},
+	} {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			test.fn(t, factory(t))
+		})
+	}
+}
+
+var ctx = context.Background()
+
+// The date function returns a time truncated to microseconds, the finest precision every store must preserve.
+func date(year int, month time.Month, day, hour, min int) *time.Time {
+	d := time.Date(year, month, day, hour, min, 0, 123456000, time.UTC)
+	return &d
+}
+
+// The normalize function returns a copy of t with all times in UTC, so that equal tasks compare equal regardless of the
+// location a store returns times in. The server-assigned CreatedAt, UpdatedAt, and Revision are cleared, since they
+// cannot be expected exactly. See testTimestamps and testRevisions.
+func normalize(t task.Task) task.Task {
+	for _, p := range []**time.Time{&t.Completed, &t.Start, &t.Due} {
+		if *p != nil {
+			u := (*p).UTC()
+			*p = &u
+		}
+	}
+	t.CreatedAt, t.UpdatedAt, t.Revision = nil, nil, 0
+	return t
+}
+
+// The assertEqual function fails the test if got is not equal to expected.
+func assertEqual(t *testing.T, expected task.Task, got *task.Task) {
+	t.Helper()
+	if got == nil {
+		t.Fatalf("expected %v but got nil", expected)
+	}
+	if e, g := normalize(expected), normalize(*got); !reflect.DeepEqual(e, g) {
+		t.Fatalf("expected %v but got %v", e, g)
+	}
+}
+
+// The assertIs function fails the test if err does not wrap target.
+func assertIs(t *testing.T, target, err error) {
+	t.Helper()
+	if !errors.Is(err, target) {
+		t.Fatalf("expected %v but got %v", target, err)
+	}
+}
+
+// The put function puts tk, and fails the test on error.
+func put(t *testing.T, ti task.TaskInterface, tk task.Task) string {
+	t.Helper()
+	id, _, err := ti.Put(ctx, tk)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	return id
+}
+
+// The get function gets the task with the given id, and fails the test on error.
+func get(t *testing.T, ti task.TaskInterface, id string) *task.Task {
+	t.Helper()
+	got, err := ti.Get(ctx, id)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	return got
+}
+
+// The ids function returns the ids of tasks.
+func ids(tasks []task.Task) []string {
+	ids := make([]string, len(tasks))
+	for i := range tasks {
+		ids[i] = tasks[i].ID
+	}
+	return ids
+}
+
+// Tests getting a task after putting it, with every field set.
+func testPutGet(t *testing.T, ti task.TaskInterface) {
+	expected := task.Task{
+		ID:          "testId",
+		Title:       "testTitle",
+		Description: "testDescription",
+		Status:      task.StatusInProgress,
+		Priority:    task.PriorityP1,
+		Start:       date(2016, 1, 2, 3, 4),
+		Due:         date(2016, 2, 3, 4, 5),
+		Recurrence:  "FREQ=WEEKLY;BYDAY=MO,WE",
+		Next:        "nextId",
+	}
+	if id, created, err := ti.Put(ctx, expected); err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if id != expected.ID {
+		t.Fatalf("expected id %q but got %q", expected.ID, id)
+	} else if !created {
+		t.Fatal("expected task to be created")
+	}
+	assertEqual(t, expected, get(t, ti, expected.ID))
+}
+
+// Tests that putting a task without an id generates a unique one.
+func testPutGeneratedID(t *testing.T, ti task.TaskInterface) {
+	first := put(t, ti, task.Task{Title: "first"})
+	second := put(t, ti, task.Task{Title: "second"})
+	if first == "" || second == "" || first == second {
+		t.Fatalf("expected unique generated ids but got %q and %q", first, second)
+	}
+	assertEqual(t, task.Task{ID: first, Title: "first", Status: task.StatusOpen}, get(t, ti, first))
+
+	id, err := ti.Create(ctx, task.Task{Title: "created"})
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	assertEqual(t, task.Task{ID: id, Title: "created", Status: task.StatusOpen}, get(t, ti, id))
+}
+
+// Tests that putting a duplicate id replaces the existing task entirely.
+func testPutReplace(t *testing.T, ti task.TaskInterface) {
+	put(t, ti, task.Task{ID: "testId", Title: "old", Description: "old", Due: date(2016, 1, 2, 3, 4)})
+
+	expected := task.Task{ID: "testId", Title: "new", Status: task.StatusOpen}
+	if _, created, err := ti.Put(ctx, expected); err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if created {
+		t.Fatal("expected task to be replaced")
+	}
+	assertEqual(t, expected, get(t, ti, "testId"))
+
+	if all, err := ti.GetAll(ctx); err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if len(all) != 1 {
+		t.Fatalf("expected 1 task but got %v", all)
+	}
+}
+
+// Tests that creating a duplicate id is a conflict, and leaves the existing task unchanged.
+func testCreateConflict(t *testing.T, ti task.TaskInterface) {
+	expected := task.Task{ID: "testId", Title: "first", Status: task.StatusOpen}
+	if id, err := ti.Create(ctx, expected); err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if id != expected.ID {
+		t.Fatalf("expected id %q but got %q", expected.ID, id)
+	}
+	_, err := ti.Create(ctx, task.Task{ID: "testId", Title: "second"})
+	assertIs(t, task.ErrConflict, err)
+	assertEqual(t, expected, get(t, ti, "testId"))
+}
+
+// Tests that every method returns task.ErrNotFound for a missing id.
+func testMissing(t *testing.T, ti task.TaskInterface) {
+	_, err := ti.Get(ctx, "missing")
+	assertIs(t, task.ErrNotFound, err)
+	_, err = ti.Update(ctx, "missing", []byte(`{"title":"new"}`))
+	assertIs(t, task.ErrNotFound, err)
+	_, err = ti.SetStatus(ctx, "missing", task.StatusDone)
+	assertIs(t, task.ErrNotFound, err)
+	assertIs(t, task.ErrNotFound, ti.Delete(ctx, "missing"))
+}
+
+// Tests that every method returns task.ErrInvalid for an empty id, or an id containing a slash.
+func testInvalidID(t *testing.T, ti task.TaskInterface) {
+	for _, id := range []string{"", "a/b", "/", "_tags", "lists", "events"} {
+		_, err := ti.Get(ctx, id)
+		assertIs(t, task.ErrInvalid, err)
+		_, err = ti.Update(ctx, id, []byte(`{"title":"new"}`))
+		assertIs(t, task.ErrInvalid, err)
+		_, err = ti.SetStatus(ctx, id, task.StatusDone)
+		assertIs(t, task.ErrInvalid, err)
+		assertIs(t, task.ErrInvalid, ti.Delete(ctx, id))
+	}
+	_, _, err := ti.Put(ctx, task.Task{ID: "a/b"})
+	assertIs(t, task.ErrInvalid, err)
+	_, err = ti.Create(ctx, task.Task{ID: "a/b"})
+	assertIs(t, task.ErrInvalid, err)
+}
+
+// Tests that invalid tasks, statuses and patches are rejected with task.ErrInvalid, and leave the store unchanged.
+func testInvalidTask(t *testing.T, ti task.TaskInterface) {
+	_, _, err := ti.Put(ctx, task.Task{ID: "bad", Status: "finished"})
+	assertIs(t, task.ErrInvalid, err)
+	_, _, err = ti.Put(ctx, task.Task{ID: "bad", Priority: "P5"})
+	assertIs(t, task.ErrInvalid, err)
+	_, err = ti.Get(ctx, "bad")
+	assertIs(t, task.ErrNotFound, err)
+
+	expected := task.Task{ID: "testId", Title: "testTitle", Status: task.StatusOpen}
+	put(t, ti, expected)
+	_, err = ti.SetStatus(ctx, "testId", "finished")
+	assertIs(t, task.ErrInvalid, err)
+	for _, patch := range []string{`[]`, `{"id":"other"}`, `{"status":"finished"}`, `{"priority":"high"}`} {
+		_, err = ti.Update(ctx, "testId", []byte(patch))
+		assertIs(t, task.ErrInvalid, err)
+	}
+	assertEqual(t, expected, get(t, ti, "testId"))
+}
+
+// Tests that a merge patch changes only the fields it names.
+func testUpdate(t *testing.T, ti task.TaskInterface) {
+	put(t, ti, task.Task{ID: "testId", Title: "testTitle", Description: "testDescription", Due: date(2016, 1, 2, 3, 4)})
+
+	expected := task.Task{ID: "testId", Title: "newTitle", Description: "testDescription", Status: task.StatusOpen}
+	got, err := ti.Update(ctx, "testId", []byte(`{"title":"newTitle","due":null}`))
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	assertEqual(t, expected, got)
+	assertEqual(t, expected, get(t, ti, "testId"))
+}
+
+// Tests that closing a task records its completion time, and reopening clears it.
+func testSetStatus(t *testing.T, ti task.TaskInterface) {
+	put(t, ti, task.Task{ID: "testId", Title: "testTitle"})
+
+	before := time.Now().Add(-time.Minute)
+	got, err := ti.SetStatus(ctx, "testId", task.StatusDone)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if got.Status != task.StatusDone || got.Title != "testTitle" {
+		t.Fatalf("expected done task but got %v", got)
+	} else if got.Completed == nil || got.Completed.Before(before) {
+		t.Fatalf("expected recent completion time but got %v", got.Completed)
+	}
+	assertEqual(t, *got, get(t, ti, "testId"))
+
+	got, err = ti.SetStatus(ctx, "testId", task.StatusOpen)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	expected := task.Task{ID: "testId", Title: "testTitle", Status: task.StatusOpen}
+	assertEqual(t, expected, got)
+	assertEqual(t, expected, get(t, ti, "testId"))
+}
+
+// Tests that a deleted task is gone, and deleting it again is task.ErrNotFound.
+func testDelete(t *testing.T, ti task.TaskInterface) {
+	put(t, ti, task.Task{ID: "delete"})
+	put(t, ti, task.Task{ID: "keep"})
+
+	if err := ti.Delete(ctx, "delete"); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	_, err := ti.Get(ctx, "delete")
+	assertIs(t, task.ErrNotFound, err)
+	assertIs(t, task.ErrNotFound, ti.Delete(ctx, "delete"))
+	get(t, ti, "keep")
+}
+
+// Tests listing all tasks, from an empty store and after puts.
+func testGetAll(t *testing.T, ti task.TaskInterface) {
+	if all, err := ti.GetAll(ctx); err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if len(all) != 0 {
+		t.Fatalf("expected no tasks but got %v", all)
+	}
+
+	put(t, ti, task.Task{ID: "b"})
+	put(t, ti, task.Task{ID: "a"})
+	if all, err := ti.GetAll(ctx); err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if got := ids(all); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Fatalf("expected tasks [a b] but got %v", got)
+	}
+}
+
+// Tests finding tasks with each kind of filter.
+func testFind(t *testing.T, ti task.TaskInterface) {
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	yesterday, tomorrow := now.AddDate(0, 0, -1), now.AddDate(0, 0, 1)
+	put(t, ti, task.Task{ID: "overdue", Due: &yesterday})
+	put(t, ti, task.Task{ID: "done", Due: &yesterday, Status: task.StatusDone})
+	put(t, ti, task.Task{ID: "upcoming", Due: &tomorrow})
+	put(t, ti, task.Task{ID: "undated"})
+
+	for _, test := range []struct {
+		filter   task.Filter
+		expected []string
+	}{
+		{task.Filter{}, []string{"done", "overdue", "undated", "upcoming"}},
+		{task.Filter{Overdue: true}, []string{"overdue"}},
+		{task.Filter{DueBefore: &now}, []string{"done", "overdue"}},
+		{task.Filter{DueAfter: &now}, []string{"upcoming"}},
+		{task.Filter{DueAfter: &yesterday, DueBefore: &now}, []string{"done", "overdue"}},
+	} {
+		found, err := ti.Find(ctx, test.filter)
+		if err != nil {
+			t.Fatal("unexpected error: ", err)
+		}
+		if got := ids(found); !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("expected %v for filter %+v but got %v", test.expected, test.filter, got)
+		}
+	}
+}
+
+// Tests paging through tasks in each order, with a filter.
+func testList(t *testing.T, ti task.TaskInterface) {
+	early, late := date(2016, 1, 2, 3, 4), date(2016, 1, 2, 5, 6)
+	for _, tk := range []task.Task{
+		{ID: "c", Title: "a", Due: late, Priority: task.PriorityP1},
+		{ID: "a", Title: "b", Priority: task.PriorityP0},
+		{ID: "e", Title: "b", Due: early},
+		{ID: "b", Title: "c", Due: early, Priority: task.PriorityP1, Status: task.StatusDone},
+		{ID: "d", Title: "a"},
+		{ID: "É", Title: "É", Priority: task.PriorityP2},
+	} {
+		put(t, ti, tk)
+	}
+
+	for _, test := range []struct {
+		options  task.ListOptions
+		expected []string
+	}{
+		{task.ListOptions{}, []string{"a", "b", "c", "d", "e", "É"}},
+		{task.ListOptions{Sort: "-id"}, []string{"É", "e", "d", "c", "b", "a"}},
+		{task.ListOptions{Sort: task.SortTitle}, []string{"c", "d", "a", "e", "b", "É"}},
+		{task.ListOptions{Sort: "-title"}, []string{"É", "b", "e", "a", "d", "c"}},
+		{task.ListOptions{Sort: task.SortDue}, []string{"b", "e", "c", "a", "d", "É"}},
+		{task.ListOptions{Sort: "-due"}, []string{"É", "d", "a", "c", "e", "b"}},
+		{task.ListOptions{Sort: task.SortDue, Filter: task.Filter{DueBefore: late}}, []string{"b", "e"}},
+		{task.ListOptions{Sort: task.SortPriority}, []string{"a", "b", "c", "É", "d", "e"}},
+		{task.ListOptions{Sort: "-priority"}, []string{"e", "d", "É", "c", "b", "a"}},
+		{task.ListOptions{Sort: task.SortPriority, Filter: task.Filter{
+			Priorities: []task.Priority{task.PriorityP0, task.PriorityP1},
+			Statuses:   []task.Status{task.StatusOpen, task.StatusInProgress},
+		}}, []string{"a", "c"}},
+		{task.ListOptions{Filter: task.Filter{Priorities: []task.Priority{""}}}, []string{"d", "e"}},
+	} {
+		for _, limit := range []int{1, 2, 4, 0} {
+			options := test.options
+			options.Limit = limit
+			var got []string
+			for pages := 0; ; pages++ {
+				if pages > len(test.expected) {
+					t.Fatalf("too many pages for %+v", options)
+				}
+				page, err := ti.List(ctx, options)
+				if err != nil {
+					t.Fatal("unexpected error: ", err)
+				}
+				if len(page.Tasks) > options.PageLimit() {
+					t.Fatalf("expected at most %d tasks but got %d", options.PageLimit(), len(page.Tasks))
+				}
+				got = append(got, ids(page.Tasks)...)
+				if page.Next == "" {
+					break
+				}
+				options.Cursor = page.Next
+			}
+			if !reflect.DeepEqual(got, test.expected) {
+				t.Errorf("expected %v for %+v but got %v", test.expected, options, got)
+			}
+		}
+	}
+}
+
+// Tests that invalid list options are rejected with task.ErrInvalid.
+func testListInvalid(t *testing.T, ti task.TaskInterface) {
+	for _, options := range []task.ListOptions{
+		{Sort: "urgency"},
+		{Limit: -1},
+		{Cursor: "not a cursor"},
+	} {
+		_, err := ti.List(ctx, options)
+		assertIs(t, task.ErrInvalid, err)
+	}
+}
+
+// Tests storing tags as a sorted set, filtering by them, and counting them.
+func testTags(t *testing.T, ti task.TaskInterface) {
+	put(t, ti, task.Task{ID: "a", Tags: []string{"ops", "backend", "ops"}})
+	put(t, ti, task.Task{ID: "b", Tags: []string{"ops"}})
+	put(t, ti, task.Task{ID: "c", Tags: []string{"shopping"}})
+	put(t, ti, task.Task{ID: "d"})
+
+	if got := get(t, ti, "a").Tags; !reflect.DeepEqual(got, []string{"backend", "ops"}) {
+		t.Fatalf("expected tags [backend ops] but got %v", got)
+	}
+	if got := get(t, ti, "d").Tags; got != nil {
+		t.Fatalf("expected no tags but got %v", got)
+	}
+
+	for _, test := range []struct {
+		filter   task.Filter
+		expected []string
+	}{
+		{task.Filter{Tags: []string{"ops"}}, []string{"a", "b"}},
+		{task.Filter{Tags: []string{"backend", "shopping"}}, []string{"a", "c"}},
+		{task.Filter{Tags: []string{"backend", "ops"}, AllTags: true}, []string{"a"}},
+		{task.Filter{Tags: []string{"missing"}}, []string{}},
+	} {
+		found, err := ti.Find(ctx, test.filter)
+		if err != nil {
+			t.Fatal("unexpected error: ", err)
+		}
+		if got := ids(found); !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("expected %v for filter %+v but got %v", test.expected, test.filter, got)
+		}
+	}
+
+	if got, err := ti.Update(ctx, "a", []byte(`{"tags":["shopping"]}`)); err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if !reflect.DeepEqual(got.Tags, []string{"shopping"}) {
+		t.Fatalf("expected tags [shopping] but got %v", got.Tags)
+	}
+	expected := []task.TagCount{{Tag: "ops", Count: 1}, {Tag: "shopping", Count: 2}}
+	if got, err := ti.Tags(ctx); err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected %v but got %v", expected, got)
+	}
+
+	_, _, err := ti.Put(ctx, task.Task{ID: "e", Tags: []string{"two words"}})
+	assertIs(t, task.ErrInvalid, err)
+	_, err = ti.Update(ctx, "b", []byte(`{"tags":[""]}`))
+	assertIs(t, task.ErrInvalid, err)
+}
+
+// Tests searching the words of task titles and descriptions, with ranking, snippets, and filters, and that searches
+// follow updates and deletes.
+func testSearch(t *testing.T, ti task.TaskInterface) {
+	put(t, ti, task.Task{ID: "a", Title: "Buy milk", Description: "Whole milk from the farm shop"})
+	put(t, ti, task.Task{ID: "b", Title: "Call the farm", Description: "Ask about milk delivery"})
+	put(t, ti, task.Task{ID: "c", Title: "Pay invoice", Status: task.StatusDone})
+	put(t, ti, task.Task{ID: "d", Title: "Walk the dog"})
+
+	search := func(query string, filter task.Filter) []task.SearchResult {
+		t.Helper()
+		results, err := ti.Search(ctx, query, filter)
+		if err != nil {
+			t.Fatalf("unexpected error searching for %q: %s", query, err)
+		}
+		return results
+	}
+	resultIDs := func(results []task.SearchResult) []string {
+		ids := []string{}
+		for _, r := range results {
+			ids = append(ids, r.Task.ID)
+		}
+		return ids
+	}
+
+	// Title matches rank above description matches.
+	results := search("milk", task.Filter{})
+	if got := resultIDs(results); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Fatalf("expected [a b] but got %v", got)
+	}
+	if results[0].Rank <= results[1].Rank {
+		t.Errorf("expected rank of a %v to be above rank of b %v", results[0].Rank, results[1].Rank)
+	}
+	if results[0].Task.Title != "Buy milk" {
+		t.Errorf("expected task title %q but got %q", "Buy milk", results[0].Task.Title)
+	}
+	if highlighted := task.HighlightStart + "milk" + task.HighlightStop; !strings.Contains(results[0].Snippet, highlighted) {
+		t.Errorf("expected snippet to contain %q but got %q", highlighted, results[0].Snippet)
+	}
+
+	for _, test := range []struct {
+		query    string
+		filter   task.Filter
+		expected []string
+	}{
+		{"MILK", task.Filter{}, []string{"a", "b"}},
+		{"farm milk", task.Filter{}, []string{"a", "b"}},
+		{"milk dog", task.Filter{}, []string{}},
+		{"invoice", task.Filter{}, []string{"c"}},
+		{"invoice", task.Filter{Statuses: []task.Status{task.StatusOpen}}, []string{}},
+		{"walk", task.Filter{}, []string{"d"}},
+		{"giraffe", task.Filter{}, []string{}},
+	} {
+		got := resultIDs(search(test.query, test.filter))
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("expected %v for query %q and filter %+v but got %v", test.expected, test.query, test.filter, got)
+		}
+	}
+
+	if _, err := ti.Update(ctx, "a", []byte(`{"title":"Buy bread","description":""}`)); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if got := resultIDs(search("milk", task.Filter{})); !reflect.DeepEqual(got, []string{"b"}) {
+		t.Fatalf("expected [b] after update but got %v", got)
+	}
+	if got := resultIDs(search("bread", task.Filter{})); !reflect.DeepEqual(got, []string{"a"}) {
+		t.Fatalf("expected [a] after update but got %v", got)
+	}
+	if err := ti.Delete(ctx, "b"); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if got := resultIDs(search("milk", task.Filter{})); len(got) != 0 {
+		t.Fatalf("expected no results after delete but got %v", got)
+	}
+
+	for _, query := range []string{"", " !? "} {
+		_, err := ti.Search(ctx, query, task.Filter{})
+		assertIs(t, task.ErrInvalid, err)
+	}
+}
+
+// Tests storing lists, the tasks in them, and deleting a list along with its tasks. Skipped if lists are not supported.
+func testLists(t *testing.T, ti task.TaskInterface) {
+	if id, created, err := ti.PutList(ctx, task.TaskList{ID: "shopping", Name: "Shop"}); errors.Is(err, task.ErrListsUnsupported) {
+		t.Skip(err)
+	} else if err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if id != "shopping" || !created {
+		t.Fatalf("expected list %q to be created but got %q, %t", "shopping", id, created)
+	}
+	if _, created, err := ti.PutList(ctx, task.TaskList{ID: "shopping", Name: "Shopping"}); err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if created {
+		t.Fatal("expected list to be replaced")
+	}
+	generated, _, err := ti.PutList(ctx, task.TaskList{Name: "Sprint"})
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if generated == "" {
+		t.Fatal("expected generated list id")
+	}
+
+	if got, err := ti.GetList(ctx, "shopping"); err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if expected := (task.TaskList{ID: "shopping", Name: "Shopping"}); !reflect.DeepEqual(*got, expected) {
+		t.Fatalf("expected %v but got %v", expected, *got)
+	}
+	expected := []task.TaskList{{ID: generated, Name: "Sprint"}, {ID: "shopping", Name: "Shopping"}}
+	if expected[1].ID < expected[0].ID {
+		expected[0], expected[1] = expected[1], expected[0]
+	}
+	if got, err := ti.GetLists(ctx); err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected %v but got %v", expected, got)
+	}
+
+	put(t, ti, task.Task{ID: "milk", List: "shopping"})
+	put(t, ti, task.Task{ID: "eggs", List: "shopping"})
+	put(t, ti, task.Task{ID: "deploy", List: generated})
+	put(t, ti, task.Task{ID: "inbox"})
+	put(t, ti, task.Task{ID: "note", Parent: "milk"})
+	if got := get(t, ti, "milk").List; got != "shopping" {
+		t.Fatalf("expected list %q but got %q", "shopping", got)
+	}
+	if got, err := ti.Find(ctx, task.Filter{List: "shopping"}); err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if !reflect.DeepEqual(ids(got), []string{"eggs", "milk"}) {
+		t.Fatalf("expected [eggs milk] but got %v", ids(got))
+	}
+	if page, err := ti.List(ctx, task.ListOptions{Filter: task.Filter{List: generated}}); err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if !reflect.DeepEqual(ids(page.Tasks), []string{"deploy"}) {
+		t.Fatalf("expected [deploy] but got %v", ids(page.Tasks))
+	}
+
+	// Moving a task between lists.
+	if got, err := ti.Update(ctx, "eggs", []byte(`{"list":"`+generated+`"}`)); err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if got.List != generated {
+		t.Fatalf("expected list %q but got %q", generated, got.List)
+	}
+
+	if err := ti.DeleteList(ctx, "shopping"); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	_, err = ti.GetList(ctx, "shopping")
+	assertIs(t, task.ErrNotFound, err)
+	_, err = ti.Get(ctx, "milk")
+	assertIs(t, task.ErrNotFound, err)
+	if all, err := ti.GetAll(ctx); err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if !reflect.DeepEqual(ids(all), []string{"deploy", "eggs", "inbox", "note"}) {
+		t.Fatalf("expected [deploy eggs inbox note] but got %v", ids(all))
+	}
+	if got := get(t, ti, "note").Parent; got != "" {
+		t.Fatalf("expected no parent but got %q", got)
+	}
+	assertIs(t, task.ErrNotFound, ti.DeleteList(ctx, "shopping"))
+}
+
+// Tests that invalid list ids, and tasks in lists which do not exist, are rejected.
+func testListsInvalid(t *testing.T, ti task.TaskInterface) {
+	for _, id := range []string{"a/b", "_tags", "lists"} {
+		_, _, err := ti.PutList(ctx, task.TaskList{ID: id})
+		assertIs(t, task.ErrInvalid, err)
+		_, err = ti.GetList(ctx, id)
+		assertIs(t, task.ErrInvalid, err)
+		assertIs(t, task.ErrInvalid, ti.DeleteList(ctx, id))
+	}
+	_, err := ti.GetList(ctx, "missing")
+	assertIs(t, task.ErrNotFound, err)
+
+	_, _, err = ti.Put(ctx, task.Task{ID: "a", List: "missing"})
+	assertIs(t, task.ErrInvalid, err)
+	_, err = ti.Create(ctx, task.Task{ID: "a", List: "missing"})
+	assertIs(t, task.ErrInvalid, err)
+	put(t, ti, task.Task{ID: "a"})
+	_, err = ti.Update(ctx, "a", []byte(`{"list":"missing"}`))
+	assertIs(t, task.ErrInvalid, err)
+	if got := get(t, ti, "a").List; got != "" {
+		t.Fatalf("expected no list but got %q", got)
+	}
+}
+
+// The tree function returns the ids of trees, nested like the trees.
+func tree(trees []task.Tree) []interface{} {
+	ids := make([]interface{}, len(trees))
+	for i, t := range trees {
+		if len(t.Children) == 0 {
+			ids[i] = t.ID
+		} else {
+			ids[i] = map[string]interface{}{t.ID: tree(t.Children)}
+		}
+	}
+	return ids
+}
+
+// Tests nesting subtasks, and getting them as trees.
+func testSubtasks(t *testing.T, ti task.TaskInterface) {
+	put(t, ti, task.Task{ID: "shopping", Title: "Shopping"})
+	put(t, ti, task.Task{ID: "milk", Parent: "shopping"})
+	put(t, ti, task.Task{ID: "eggs", Parent: "shopping"})
+	put(t, ti, task.Task{ID: "free-range", Parent: "eggs"})
+	put(t, ti, task.Task{ID: "other"})
+
+	if got := get(t, ti, "milk").Parent; got != "shopping" {
+		t.Fatalf("expected parent %q but got %q", "shopping", got)
+	}
+	children, err := ti.Children(ctx, "shopping")
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	expected := []interface{}{map[string]interface{}{"eggs": []interface{}{"free-range"}}, "milk"}
+	if got := tree(children); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected %v but got %v", expected, got)
+	}
+	assertEqual(t, task.Task{ID: "free-range", Status: task.StatusOpen, Parent: "eggs"}, &children[0].Children[0].Task)
+	if children, err := ti.Children(ctx, "other"); err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if len(children) != 0 {
+		t.Fatalf("expected no subtasks but got %v", children)
+	}
+	_, err = ti.Children(ctx, "missing")
+	assertIs(t, task.ErrNotFound, err)
+
+	if got, err := ti.Find(ctx, task.Filter{Parent: "shopping"}); err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if !reflect.DeepEqual(ids(got), []string{"eggs", "milk"}) {
+		t.Fatalf("expected [eggs milk] but got %v", ids(got))
+	}
+
+	// Moving a subtask to the top level.
+	if got, err := ti.Update(ctx, "free-range", []byte(`{"parent":null}`)); err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if got.Parent != "" {
+		t.Fatalf("expected no parent but got %q", got.Parent)
+	}
+}
+
+// Tests that deleting a task keeps its subtasks as top level tasks, and deleting a tree deletes its subtasks.
+func testSubtasksDelete(t *testing.T, ti task.TaskInterface) {
+	for _, tk := range []task.Task{
+		{ID: "a"}, {ID: "b", Parent: "a"}, {ID: "c", Parent: "b"}, {ID: "d", Parent: "a"}, {ID: "e"},
+	} {
+		put(t, ti, tk)
+	}
+
+	if err := ti.Delete(ctx, "b"); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if got := get(t, ti, "c").Parent; got != "" {
+		t.Fatalf("expected no parent but got %q", got)
+	}
+
+	put(t, ti, task.Task{ID: "c", Parent: "d"})
+	if err := ti.DeleteTree(ctx, "a"); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if all, err := ti.GetAll(ctx); err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if !reflect.DeepEqual(ids(all), []string{"e"}) {
+		t.Fatalf("expected [e] but got %v", ids(all))
+	}
+	assertIs(t, task.ErrNotFound, ti.DeleteTree(ctx, "a"))
+	assertIs(t, task.ErrInvalid, ti.DeleteTree(ctx, "a/b"))
+}
+
+// Tests that unknown parents, and parents which would make a task its own subtask, are rejected.
+func testSubtasksInvalid(t *testing.T, ti task.TaskInterface) {
+	put(t, ti, task.Task{ID: "a"})
+	put(t, ti, task.Task{ID: "b", Parent: "a"})
+	put(t, ti, task.Task{ID: "c", Parent: "b"})
+
+	_, _, err := ti.Put(ctx, task.Task{ID: "d", Parent: "missing"})
+	assertIs(t, task.ErrInvalid, err)
+	_, err = ti.Create(ctx, task.Task{ID: "d", Parent: "d"})
+	assertIs(t, task.ErrInvalid, err)
+	_, err = ti.Update(ctx, "a", []byte(`{"parent":"c"}`))
+	assertIs(t, task.ErrInvalid, err)
+	_, _, err = ti.Put(ctx, task.Task{ID: "b", Parent: "c"})
+	assertIs(t, task.ErrInvalid, err)
+	if got := get(t, ti, "a").Parent; got != "" {
+		t.Fatalf("expected no parent but got %q", got)
+	}
+}
+
+// Tests blockers, the actionable and ordered views, and that deleting a task removes it from the tasks it blocks.
+func testDependencies(t *testing.T, ti task.TaskInterface) {
+	put(t, ti, task.Task{ID: "shop", Tags: []string{"errand"}})
+	put(t, ti, task.Task{ID: "cook", BlockedBy: []string{"shop", "shop"}})
+	put(t, ti, task.Task{ID: "invite"})
+	put(t, ti, task.Task{ID: "eat", BlockedBy: []string{"cook", "invite"}})
+	put(t, ti, task.Task{ID: "bank", Status: task.StatusDone, Tags: []string{"errand"}})
+
+	if got := get(t, ti, "eat").BlockedBy; !reflect.DeepEqual(got, []string{"cook", "invite"}) {
+		t.Fatalf("expected blockers [cook invite] but got %v", got)
+	}
+	if got := get(t, ti, "cook").BlockedBy; !reflect.DeepEqual(got, []string{"shop"}) {
+		t.Fatalf("expected blockers [shop] but got %v", got)
+	}
+	assertIDs := func(expected []string, tasks []task.Task, err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatal("unexpected error: ", err)
+		} else if got := ids(tasks); !reflect.DeepEqual(got, expected) {
+			t.Fatalf("expected %v but got %v", expected, got)
+		}
+	}
+	actionable, err := ti.Actionable(ctx, task.Filter{})
+	assertIDs([]string{"invite", "shop"}, actionable, err)
+	actionable, err = ti.Actionable(ctx, task.Filter{Tags: []string{"errand"}})
+	assertIDs([]string{"shop"}, actionable, err)
+	ordered, err := ti.Ordered(ctx, task.Filter{})
+	assertIDs([]string{"bank", "invite", "shop", "cook", "eat"}, ordered, err)
+	ordered, err = ti.Ordered(ctx, task.Filter{Statuses: []task.Status{task.StatusOpen}})
+	assertIDs([]string{"invite", "shop", "cook", "eat"}, ordered, err)
+
+	if _, err := ti.SetStatus(ctx, "shop", task.StatusDone); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	actionable, err = ti.Actionable(ctx, task.Filter{})
+	assertIDs([]string{"cook", "invite"}, actionable, err)
+
+	if err := ti.Delete(ctx, "invite"); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if got := get(t, ti, "eat").BlockedBy; !reflect.DeepEqual(got, []string{"cook"}) {
+		t.Fatalf("expected blockers [cook] but got %v", got)
+	}
+	if got, err := ti.Update(ctx, "eat", []byte(`{"blocked_by":null}`)); err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if len(got.BlockedBy) != 0 {
+		t.Fatalf("expected no blockers but got %v", got.BlockedBy)
+	}
+}
+
+// Tests that unknown blockers are rejected, and blockers which would make a task depend on itself are rejected with
+// task.ErrCycle.
+func testDependenciesInvalid(t *testing.T, ti task.TaskInterface) {
+	put(t, ti, task.Task{ID: "a"})
+	put(t, ti, task.Task{ID: "b", BlockedBy: []string{"a"}})
+	put(t, ti, task.Task{ID: "c", BlockedBy: []string{"b"}})
+
+	_, _, err := ti.Put(ctx, task.Task{ID: "d", BlockedBy: []string{"missing"}})
+	assertIs(t, task.ErrInvalid, err)
+	_, err = ti.Create(ctx, task.Task{ID: "d", BlockedBy: []string{"a/b"}})
+	assertIs(t, task.ErrInvalid, err)
+	_, err = ti.Create(ctx, task.Task{ID: "d", BlockedBy: []string{"d"}})
+	assertIs(t, task.ErrCycle, err)
+	_, err = ti.Update(ctx, "a", []byte(`{"blocked_by":["c"]}`))
+	assertIs(t, task.ErrCycle, err)
+	_, _, err = ti.Put(ctx, task.Task{ID: "b", BlockedBy: []string{"c"}})
+	assertIs(t, task.ErrCycle, err)
+	if got := get(t, ti, "a").BlockedBy; len(got) != 0 {
+		t.Fatalf("expected no blockers but got %v", got)
+	}
+	if got := get(t, ti, "b").BlockedBy; !reflect.DeepEqual(got, []string{"a"}) {
+		t.Fatalf("expected blockers [a] but got %v", got)
+	}
+}
+
+// Tests that doing a recurring task creates its next occurrence once, until the series ends, and listing occurrences.
+func testRecurrence(t *testing.T, ti task.TaskInterface) {
+	put(t, ti, task.Task{ID: "trash", Title: "Take out trash", Tags: []string{"chore"}, Start: date(2026, 1, 4, 18, 0),
+		Due: date(2026, 1, 5, 8, 0), Recurrence: "FREQ=WEEKLY;BYDAY=MO,TH;COUNT=3"})
+	put(t, ti, task.Task{ID: "report", Due: date(2026, 1, 7, 9, 0)})
+
+	done, err := ti.SetStatus(ctx, "trash", task.StatusDone)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if done.Next == "" {
+		t.Fatal("expected a next occurrence")
+	}
+	assertEqual(t, task.Task{ID: done.Next, Title: "Take out trash", Status: task.StatusOpen, Tags: []string{"chore"},
+		Start: date(2026, 1, 7, 18, 0), Due: date(2026, 1, 8, 8, 0), Recurrence: "FREQ=WEEKLY;BYDAY=MO,TH;COUNT=2"},
+		get(t, ti, done.Next))
+	if got := get(t, ti, "trash").Next; got != done.Next {
+		t.Fatalf("expected next %q but got %q", done.Next, got)
+	}
+
+	// Reopening and doing the task again does not create another occurrence.
+	if _, err := ti.SetStatus(ctx, "trash", task.StatusOpen); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if _, err := ti.Update(ctx, "trash", []byte(`{"status":"done"}`)); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if all, err := ti.GetAll(ctx); err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if len(all) != 3 {
+		t.Fatalf("expected 3 tasks but got %v", ids(all))
+	}
+
+	occurrences, err := ti.Occurrences(ctx, *date(2026, 1, 5, 0, 0), *date(2026, 1, 20, 0, 0), task.Filter{})
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	var got []string
+	for _, o := range occurrences {
+		got = append(got, o.Task.ID+" "+o.Due.UTC().Format("01-02"))
+	}
+	if expected := []string{"report 01-07", done.Next + " 01-08", done.Next + " 01-12"}; !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected %v but got %v", expected, got)
+	}
+	_, err = ti.Occurrences(ctx, *date(2026, 1, 5, 0, 0), *date(2026, 1, 5, 0, 0), task.Filter{})
+	assertIs(t, task.ErrInvalid, err)
+
+	// The last occurrence of the series has no next occurrence.
+	second, err := ti.Update(ctx, done.Next, []byte(`{"status":"done"}`))
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	last, err := ti.SetStatus(ctx, second.Next, task.StatusDone)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if last.Next != "" || last.Recurrence != "FREQ=WEEKLY;BYDAY=MO,TH;COUNT=1" {
+		t.Fatalf("expected the last occurrence but got %v", last)
+	}
+}
+
+// Tests that malformed recurrence rules, and recurring tasks without a due time, are rejected.
+func testRecurrenceInvalid(t *testing.T, ti task.TaskInterface) {
+	for _, tk := range []task.Task{
+		{ID: "a", Due: date(2026, 1, 5, 8, 0), Recurrence: "FREQ=YEARLY"},
+		{ID: "a", Due: date(2026, 1, 5, 8, 0), Recurrence: "FREQ=WEEKLY;BYDAY=1MO"},
+		{ID: "a", Recurrence: "FREQ=DAILY"},
+	} {
+		_, _, err := ti.Put(ctx, tk)
+		assertIs(t, task.ErrInvalid, err)
+	}
+	put(t, ti, task.Task{ID: "a"})
+	_, err := ti.Update(ctx, "a", []byte(`{"recurrence":"FREQ=DAILY"}`))
+	assertIs(t, task.ErrInvalid, err)
+}
+
+// Tests that writes stamp tasks with the time they were created and last updated, ignoring any times or creator supplied
+// by the client, and that tasks can be filtered and listed by those times.
+func testTimestamps(t *testing.T, ti task.TaskInterface) {
+	forged := date(2000, 1, 1, 0, 0)
+	before := time.Now().Truncate(time.Microsecond)
+	put(t, ti, task.Task{ID: "a", CreatedAt: forged, UpdatedAt: forged, CreatedBy: "mallory"})
+	a := get(t, ti, "a")
+	if a.CreatedAt == nil || a.CreatedAt.Before(before) || a.UpdatedAt == nil || !a.UpdatedAt.Equal(*a.CreatedAt) {
+		t.Fatalf("expected created and updated after %s but got %v and %v", before, a.CreatedAt, a.UpdatedAt)
+	}
+	if a.CreatedBy != "" {
+		t.Fatalf("expected no creator but got %q", a.CreatedBy)
+	}
+	if _, err := ti.Create(ctx, task.Task{ID: "b", CreatedAt: forged}); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	b := get(t, ti, "b")
+
+	// Stamps are truncated to microseconds, so wait for the next one.
+	time.Sleep(time.Millisecond)
+	since := time.Now()
+	updated, err := ti.Update(ctx, "a", []byte(`{"title":"updated","created_at":"2000-01-01T00:00:00Z"}`))
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if !updated.CreatedAt.Equal(*a.CreatedAt) || updated.UpdatedAt.Before(since.Truncate(time.Microsecond)) {
+		t.Fatalf("expected created at %s and updated after %s but got %v and %v", a.CreatedAt, since, updated.CreatedAt, updated.UpdatedAt)
+	}
+	if got := get(t, ti, "a"); !got.UpdatedAt.Equal(*updated.UpdatedAt) {
+		t.Fatalf("expected updated at %s but got %v", updated.UpdatedAt, got.UpdatedAt)
+	}
+	if got, err := ti.Find(ctx, task.Filter{UpdatedSince: &since}); err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if !reflect.DeepEqual(ids(got), []string{"a"}) {
+		t.Fatalf("expected [a] but got %v", ids(got))
+	}
+
+	// Replacing a task keeps its creation time.
+	time.Sleep(time.Millisecond)
+	put(t, ti, task.Task{ID: "b", Title: "replaced"})
+	if got := get(t, ti, "b"); !got.CreatedAt.Equal(*b.CreatedAt) || !got.UpdatedAt.After(*updated.UpdatedAt) {
+		t.Fatalf("expected created at %s and updated after %s but got %v and %v", b.CreatedAt, updated.UpdatedAt, got.CreatedAt, got.UpdatedAt)
+	}
+	for _, test := range []struct {
+		sort     task.Sort
+		expected []string
+	}{
+		{task.SortCreated, []string{"a", "b"}},
+		{"-created", []string{"b", "a"}},
+		{task.SortUpdated, []string{"a", "b"}},
+		{"-updated", []string{"b", "a"}},
+	} {
+		page, err := ti.List(ctx, task.ListOptions{Sort: test.sort, Limit: 1})
+		if err != nil {
+			t.Fatal("unexpected error: ", err)
+		}
+		next, err := ti.List(ctx, task.ListOptions{Sort: test.sort, Limit: 1, Cursor: page.Next})
+		if err != nil {
+			t.Fatal("unexpected error: ", err)
+		}
+		if got := ids(append(page.Tasks, next.Tasks...)); !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("%q: expected %v but got %v", test.sort, test.expected, got)
+		}
+	}
+}
+
+// Tests that every change increments the revision of a task, and that conditional writes only succeed at the expected
+// revision.
+func testRevisions(t *testing.T, ti task.TaskInterface) {
+	put(t, ti, task.Task{ID: "a", Revision: 7})
+	put(t, ti, task.Task{ID: "a", Title: "replaced"})
+	if _, err := ti.Update(ctx, "a", []byte(`{"title":"updated","revision":7}`)); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if got, err := ti.SetStatus(ctx, "a", task.StatusDone); err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if got.Revision != 4 {
+		t.Fatalf("expected revision 4 but got %d", got.Revision)
+	}
+
+	stale := task.IfMatch(ctx, 3)
+	_, _, err := ti.Put(stale, task.Task{ID: "a"})
+	assertIs(t, task.ErrRevisionMismatch, err)
+	_, err = ti.Update(stale, "a", []byte(`{"title":"stale"}`))
+	assertIs(t, task.ErrRevisionMismatch, err)
+	_, err = ti.SetStatus(stale, "a", task.StatusOpen)
+	assertIs(t, task.ErrRevisionMismatch, err)
+	assertIs(t, task.ErrRevisionMismatch, ti.Delete(stale, "a"))
+	assertIs(t, task.ErrRevisionMismatch, ti.DeleteTree(stale, "a"))
+	if got := get(t, ti, "a"); got.Revision != 4 || got.Title != "updated" {
+		t.Fatalf("expected unchanged revision 4 but got %v", got)
+	}
+	_, _, err = ti.Put(task.IfMatch(ctx, 1), task.Task{ID: "missing"})
+	assertIs(t, task.ErrRevisionMismatch, err)
+
+	if got, err := ti.Update(task.IfMatch(ctx, 4), "a", []byte(`{"title":"current"}`)); err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if got.Revision != 5 {
+		t.Fatalf("expected revision 5 but got %d", got.Revision)
+	}
+
+	// Deleting a task changes the tasks whose parent or blockers it was.
+	put(t, ti, task.Task{ID: "b", Parent: "a"})
+	put(t, ti, task.Task{ID: "c", BlockedBy: []string{"a"}})
+	put(t, ti, task.Task{ID: "d"})
+	if err := ti.Delete(task.IfMatch(ctx, 5), "a"); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	for id, expected := range map[string]int64{"b": 2, "c": 2, "d": 1} {
+		if got := get(t, ti, id); got.Revision != expected {
+			t.Errorf("expected %q at revision %d but got %d", id, expected, got.Revision)
+		}
+	}
+}
+
+// Tests that ids and fields are not restricted to ascii.
+func testUnicode(t *testing.T, ti task.TaskInterface) {
+	for _, expected := range []task.Task{
+		{ID: "über", Title: "Ünïcödé", Description: "日本語のテキスト", Status: task.StatusOpen},
+		{ID: "日本語", Title: "emoji ✅🎉", Description: "tab\tnewline\nquote\"", Status: task.StatusOpen},
+		{ID: "a b?c#d%e", Title: "reserved url characters", Status: task.StatusOpen},
+	} {
+		put(t, ti, expected)
+		assertEqual(t, expected, get(t, ti, expected.ID))
+		if _, err := ti.Update(ctx, expected.ID, []byte(`{"description":"ok"}`)); err != nil {
+			t.Fatal("unexpected error: ", err)
+		}
+		if err := ti.Delete(ctx, expected.ID); err != nil {
+			t.Fatal("unexpected error: ", err)
+		}
+	}
+}
+
+// Tests a task with a description of a megabyte.
+func testLargeDescription(t *testing.T, ti task.TaskInterface) {
+	expected := task.Task{
+		ID:          "large",
+		Description: strings.Repeat("0123456789abcdef", 1<<16),
+		Status:      task.StatusOpen,
+	}
+	put(t, ti, expected)
+	got := get(t, ti, expected.ID)
+	if len(got.Description) != len(expected.Description) {
+		t.Fatalf("expected description of %d bytes but got %d", len(expected.Description), len(got.Description))
+	}
+	assertEqual(t, expected, got)
+}
+
+// Tests concurrent writers, to distinct tasks and to the same task.
+func testConcurrentWriters(t *testing.T, ti task.TaskInterface) {
+	const n = 20
+	put(t, ti, task.Task{ID: "shared"})
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if _, _, err := ti.Put(ctx, task.Task{ID: fmt.Sprint("task", i)}); err != nil {
+				t.Error("unexpected error: ", err)
+			}
+			if _, err := ti.Update(ctx, "shared", []byte(fmt.Sprintf(`{"title":"%d"}`, i))); err != nil {
+				t.Error("unexpected error: ", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if all, err := ti.GetAll(ctx); err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if len(all) != n+1 {
+		t.Fatalf("expected %d tasks but got %d", n+1, len(all))
+	}
+	if got := get(t, ti, "shared"); got.Title == "" {
+		t.Fatal("expected title from one of the updates")
+	}
+}
+
+// The putAs function puts tk with ctx and ti, and fails the test on error.
+func putAs(t *testing.T, ctx context.Context, ti task.TaskInterface, tk task.Task) {
+	t.Helper()
+	if _, _, err := ti.Put(ctx, tk); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+}
+
+// The putListAs function puts l with ctx and ti, and fails the test on error.
+func putListAs(t *testing.T, ctx context.Context, ti task.TaskInterface, l task.TaskList) {
+	t.Helper()
+	if _, _, err := ti.PutList(ctx, l); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+}
+
+// Tests that users only read their own tasks and lists.
+func testIsolatedReads(t *testing.T, as As) {
+	alice, ati := as("alice", false)
+	bob, bti := as("bob", false)
+	putListAs(t, alice, ati, task.TaskList{ID: "alices"})
+	putListAs(t, bob, bti, task.TaskList{ID: "bobs"})
+	putAs(t, alice, ati, task.Task{ID: "a", Title: "Buy milk", Tags: []string{"home"}, List: "alices"})
+	putAs(t, alice, ati, task.Task{ID: "a1", Parent: "a"})
+	putAs(t, bob, bti, task.Task{ID: "b", Title: "Buy milk", Tags: []string{"work"}, List: "bobs"})
+	putAs(t, bob, bti, task.Task{ID: "b1", Parent: "b"})
+
+	if got, err := ati.Get(alice, "a"); err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if got.Owner != "alice" {
+		t.Fatalf("expected owner %q but got %q", "alice", got.Owner)
+	}
+	_, err := ati.Get(alice, "b")
+	assertIs(t, task.ErrNotFound, err)
+	_, err = ati.Children(alice, "b")
+	assertIs(t, task.ErrNotFound, err)
+	_, err = ati.GetList(alice, "bobs")
+	assertIs(t, task.ErrNotFound, err)
+
+	list := func(ctx context.Context, options task.ListOptions) ([]task.Task, error) {
+		page, err := ati.List(ctx, options)
+		if err != nil {
+			return nil, err
+		}
+		return page.Tasks, nil
+	}
+	for _, test := range []struct {
+		name     string
+		fn       func() ([]task.Task, error)
+		expected []string
+	}{
+		{"GetAll", func() ([]task.Task, error) { return ati.GetAll(alice) }, []string{"a", "a1"}},
+		{"Find", func() ([]task.Task, error) { return ati.Find(alice, task.Filter{Tags: []string{"home", "work"}}) }, []string{"a"}},
+		{"List", func() ([]task.Task, error) { return list(alice, task.ListOptions{}) }, []string{"a", "a1"}},
+		{"Actionable", func() ([]task.Task, error) { return ati.Actionable(alice, task.Filter{}) }, []string{"a", "a1"}},
+		{"Ordered", func() ([]task.Task, error) { return ati.Ordered(alice, task.Filter{}) }, []string{"a", "a1"}},
+	} {
+		if got, err := test.fn(); err != nil {
+			t.Fatalf("%s: unexpected error: %s", test.name, err)
+		} else if !reflect.DeepEqual(ids(got), test.expected) {
+			t.Errorf("%s: expected %v but got %v", test.name, test.expected, ids(got))
+		}
+	}
+	if got, err := ati.Tags(alice); err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if expected := []task.TagCount{{Tag: "home", Count: 1}}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected tags %v but got %v", expected, got)
+	}
+	if got, err := ati.Search(alice, "milk", task.Filter{}); err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if len(got) != 1 || got[0].Task.ID != "a" {
+		t.Errorf("expected to find only %q but got %v", "a", got)
+	}
+	if got, err := ati.GetLists(alice); err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if len(got) != 1 || got[0].ID != "alices" || got[0].Owner != "alice" {
+		t.Errorf("expected only list %q owned by alice but got %v", "alices", got)
+	}
+	if got, err := bti.Children(bob, "b"); err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if len(got) != 1 || got[0].ID != "b1" {
+		t.Errorf("expected subtask %q but got %v", "b1", got)
+	}
+}
+
+// Tests that users cannot change, delete, or refer to the tasks and lists of others, even knowing their ids.
+func testIsolatedWrites(t *testing.T, as As) {
+	alice, ati := as("alice", false)
+	bob, bti := as("bob", false)
+	putListAs(t, alice, ati, task.TaskList{ID: "alices", Name: "Alice's"})
+	putAs(t, alice, ati, task.Task{ID: "a", Title: "mine", List: "alices"})
+
+	_, err := bti.Update(bob, "a", []byte(`{"title":"stolen"}`))
+	assertIs(t, task.ErrNotFound, err)
+	_, err = bti.SetStatus(bob, "a", task.StatusDone)
+	assertIs(t, task.ErrNotFound, err)
+	assertIs(t, task.ErrNotFound, bti.Delete(bob, "a"))
+	assertIs(t, task.ErrNotFound, bti.DeleteTree(bob, "a"))
+	assertIs(t, task.ErrNotFound, bti.DeleteList(bob, "alices"))
+	_, _, err = bti.Put(bob, task.Task{ID: "a", Title: "stolen"})
+	assertIs(t, task.ErrConflict, err)
+	_, err = bti.Create(bob, task.Task{ID: "a", Title: "stolen"})
+	assertIs(t, task.ErrConflict, err)
+	_, _, err = bti.PutList(bob, task.TaskList{ID: "alices", Name: "stolen"})
+	assertIs(t, task.ErrConflict, err)
+	for _, tk := range []task.Task{
+		{ID: "b", Parent: "a"},
+		{ID: "b", BlockedBy: []string{"a"}},
+		{ID: "b", List: "alices"},
+	} {
+		_, _, err = bti.Put(bob, tk)
+		assertIs(t, task.ErrInvalid, err)
+	}
+
+	if got, err := ati.Get(alice, "a"); err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if got.Title != "mine" || got.Revision != 1 {
+		t.Fatalf("expected task unchanged but got %v", got)
+	}
+	if got, err := ati.GetList(alice, "alices"); err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if got.Name != "Alice's" {
+		t.Fatalf("expected list unchanged but got %v", got)
+	}
+}
+
+// Tests that users cannot give their tasks and lists away, or create them for others.
+func testOwners(t *testing.T, as As) {
+	alice, ati := as("alice", false)
+	bob, bti := as("bob", false)
+	putAs(t, alice, ati, task.Task{ID: "a", Owner: "bob"})
+	putListAs(t, alice, ati, task.TaskList{ID: "alices", Owner: "bob"})
+	putAs(t, alice, ati, task.Task{ID: "a", Title: "replaced", Owner: "bob"})
+	if _, err := ati.Update(alice, "a", []byte(`{"owner":"bob"}`)); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+
+	if got, err := ati.Get(alice, "a"); err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if got.Owner != "alice" {
+		t.Fatalf("expected owner %q but got %q", "alice", got.Owner)
+	}
+	if got, err := ati.GetList(alice, "alices"); err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if got.Owner != "alice" {
+		t.Fatalf("expected owner %q but got %q", "alice", got.Owner)
+	}
+	if got, err := bti.GetAll(bob); err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if len(got) != 0 {
+		t.Fatalf("expected no tasks but got %v", got)
+	}
+}
+
+// Tests that admins read and write the tasks and lists of every user, and assign their owners.
+func testAdmin(t *testing.T, as As) {
+	alice, ati := as("alice", false)
+	bob, bti := as("bob", false)
+	admin, rti := as("root", true)
+	putListAs(t, alice, ati, task.TaskList{ID: "alices"})
+	putAs(t, alice, ati, task.Task{ID: "a", List: "alices"})
+	putAs(t, bob, bti, task.Task{ID: "b"})
+
+	if got, err := rti.GetAll(admin); err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if !reflect.DeepEqual(ids(got), []string{"a", "b"}) || got[0].Owner != "alice" || got[1].Owner != "bob" {
+		t.Fatalf("expected tasks of alice and bob but got %v", got)
+	}
+	if _, err := rti.Update(admin, "b", []byte(`{"title":"checked"}`)); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if got, err := bti.Get(bob, "b"); err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if got.Title != "checked" || got.Owner != "bob" {
+		t.Fatalf("expected bob's task updated but got %v", got)
+	}
+
+	putAs(t, admin, rti, task.Task{ID: "c", Owner: "bob"})
+	putAs(t, admin, rti, task.Task{ID: "a", Owner: "bob"})
+	putAs(t, admin, rti, task.Task{ID: "d"})
+	if got, err := bti.GetAll(bob); err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if !reflect.DeepEqual(ids(got), []string{"a", "b", "c"}) {
+		t.Fatalf("expected tasks given to bob but got %v", ids(got))
+	}
+	_, err := ati.Get(alice, "a")
+	assertIs(t, task.ErrNotFound, err)
+	if got, err := rti.Get(admin, "d"); err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if got.Owner != "root" {
+		t.Fatalf("expected owner %q but got %q", "root", got.Owner)
+	}
+
+	if err := rti.Delete(admin, "b"); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if err := rti.DeleteList(admin, "alices"); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if got, err := rti.GetAll(admin); err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if !reflect.DeepEqual(ids(got), []string{"a", "c", "d"}) {
+		t.Fatalf("expected remaining tasks but got %v", ids(got))
+	}
+}
+
+// The shareAs function shares list with grant, with ctx and ti, and returns the grants of the list, failing the test on
+// error.
+func shareAs(t *testing.T, ctx context.Context, ti task.TaskInterface, list string, grant task.Grant) []task.Grant {
+	t.Helper()
+	l, err := ti.Share(ctx, list, grant)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	return l.Grants
+}
+
+// Tests that lists shared with users and groups give viewers read access to their tasks, and editors write access,
+// until they are unshared, and that only owners share lists.
+func testSharing(t *testing.T, as As) {
+	alice, ati := as("alice", false)
+	bob, bti := as("bob", false)
+	carol, cti := as("carol", false)
+	dave, dti := as("dave", false)
+	putListAs(t, alice, ati, task.TaskList{ID: "shared", Name: "Shared"})
+	putAs(t, alice, ati, task.Task{ID: "a", Title: "shared", List: "shared"})
+	putAs(t, alice, ati, task.Task{ID: "p", Title: "private"})
+
+	bobViewer := task.Grant{Grantee: task.Grantee{User: "bob"}, Role: task.RoleViewer}
+	if got := shareAs(t, alice, ati, "shared", bobViewer); !reflect.DeepEqual(got, []task.Grant{bobViewer}) {
+		t.Fatalf("expected grants %v but got %v", []task.Grant{bobViewer}, got)
+	}
+	if _, err := bti.Get(bob, "a"); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if got, err := bti.GetAll(bob); err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if !reflect.DeepEqual(ids(got), []string{"a"}) {
+		t.Fatalf("expected only the shared task but got %v", ids(got))
+	}
+	if got, err := bti.GetLists(bob); err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if len(got) != 1 || got[0].ID != "shared" || got[0].Owner != "alice" {
+		t.Fatalf("expected the shared list but got %v", got)
+	}
+	_, err := bti.Update(bob, "a", []byte(`{"title":"viewed"}`))
+	assertIs(t, task.ErrForbidden, err)
+	assertIs(t, task.ErrForbidden, bti.Delete(bob, "a"))
+	_, _, err = bti.Put(bob, task.Task{ID: "b", List: "shared"})
+	assertIs(t, task.ErrForbidden, err)
+	_, _, err = bti.PutList(bob, task.TaskList{ID: "shared", Name: "viewed"})
+	assertIs(t, task.ErrForbidden, err)
+	_, err = bti.Share(bob, "shared", task.Grant{Grantee: task.Grantee{User: "dave"}, Role: task.RoleViewer})
+	assertIs(t, task.ErrForbidden, err)
+	_, err = dti.Share(dave, "shared", task.Grant{Grantee: task.Grantee{User: "dave"}, Role: task.RoleViewer})
+	assertIs(t, task.ErrNotFound, err)
+
+	teamEditor := task.Grant{Grantee: task.Grantee{Group: "team"}, Role: task.RoleEditor}
+	shareAs(t, alice, ati, "shared", teamEditor)
+	if _, err := cti.Update(carol, "a", []byte(`{"title":"edited"}`)); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	putAs(t, carol, cti, task.Task{ID: "c", List: "shared"})
+	if got, err := ati.Get(alice, "c"); err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if got.Owner != "carol" {
+		t.Fatalf("expected owner %q but got %q", "carol", got.Owner)
+	}
+	if got, err := ati.Get(alice, "a"); err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if got.Title != "edited" || got.Owner != "alice" {
+		t.Fatalf("expected alice's task edited but got %v", got)
+	}
+	_, err = cti.Get(carol, "p")
+	assertIs(t, task.ErrNotFound, err)
+	assertIs(t, task.ErrForbidden, cti.DeleteList(carol, "shared"))
+
+	bobEditor := task.Grant{Grantee: bobViewer.Grantee, Role: task.RoleEditor}
+	if got, expected := shareAs(t, alice, ati, "shared", bobEditor), []task.Grant{bobEditor, teamEditor}; !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected grants %v but got %v", expected, got)
+	}
+	if l, err := ati.Unshare(alice, "shared", bobViewer.Grantee); err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if !reflect.DeepEqual(l.Grants, []task.Grant{teamEditor}) {
+		t.Fatalf("expected grants %v but got %v", []task.Grant{teamEditor}, l.Grants)
+	}
+	_, err = bti.Get(bob, "a")
+	assertIs(t, task.ErrNotFound, err)
+
+	for _, grant := range []task.Grant{
+		{Grantee: task.Grantee{User: "bob"}, Role: task.RoleOwner},
+		{Grantee: task.Grantee{User: "bob"}},
+		{Grantee: task.Grantee{User: "bob", Group: "team"}, Role: task.RoleViewer},
+		{Role: task.RoleViewer},
+	} {
+		_, err = ati.Share(alice, "shared", grant)
+		assertIs(t, task.ErrInvalid, err)
+	}
+	_, err = ati.Unshare(alice, "shared", task.Grantee{})
+	assertIs(t, task.ErrInvalid, err)
+}
+
+// Tests that a task is only deleted with its subtasks if the user may edit all of them, even those they may not read.
+func testIsolatedDeleteTree(t *testing.T, as As) {
+	alice, ati := as("alice", false)
+	bob, bti := as("bob", false)
+	putListAs(t, alice, ati, task.TaskList{ID: "shared", Name: "Shared"})
+	putAs(t, alice, ati, task.Task{ID: "a", Title: "shared", List: "shared"})
+	putAs(t, alice, ati, task.Task{ID: "a1", Parent: "a"})
+	shareAs(t, alice, ati, "shared", task.Grant{Grantee: task.Grantee{User: "bob"}, Role: task.RoleViewer})
+	putAs(t, bob, bti, task.Task{ID: "b", Parent: "a"})
+
+	assertIs(t, task.ErrForbidden, ati.DeleteTree(alice, "a"))
+	for _, id := range []string{"a", "a1"} {
+		if _, err := ati.Get(alice, id); err != nil {
+			t.Fatal("unexpected error: ", err)
+		}
+	}
+	if got, err := bti.Get(bob, "b"); err != nil {
+		t.Fatal("unexpected error: ", err)
+	} else if got.Parent != "a" {
+		t.Fatalf("expected parent %q but got %q", "a", got.Parent)
+	}
+
+	if err := bti.Delete(bob, "b"); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if err := ati.DeleteTree(alice, "a"); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	for _, id := range []string{"a", "a1"} {
+		_, err := ati.Get(alice, id)
+		assertIs(t, task.ErrNotFound, err)
+	}
+}
+
+// The nextEvent function returns the next event from events, and fails the test if the channel is closed, or no event
+// arrives within a few seconds.
+func nextEvent(t *testing.T, events <-chan task.Event) task.Event {
+	t.Helper()
+	select {
+	case e, ok := <-events:
+		if !ok {
+			t.Fatal("expected an event but the channel was closed")
+		}
+		return e
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for an event")
+	}
+	return task.Event{}
+}
+
+// Tests watching the events of created, updated, and deleted tasks, in order, resuming after an event, and that
+// resuming after an unknown event fails.
+func testWatch(t *testing.T, ti task.TaskInterface) {
+	put(t, ti, task.Task{ID: "before"})
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	events, err := ti.Watch(ctx, 0)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+
+	put(t, ti, task.Task{ID: "a", Title: "new"})
+	if _, err := ti.Update(ctx, "a", []byte(`{"title":"changed"}`)); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	put(t, ti, task.Task{ID: "b", Parent: "a"})
+	if err := ti.Delete(ctx, "a"); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	type change struct {
+		Type     task.EventType
+		ID       string
+		Title    string
+		Revision int64
+	}
+	expected := []change{
+		{task.EventCreated, "a", "new", 1},
+		{task.EventUpdated, "a", "changed", 2},
+		{task.EventCreated, "b", "", 1},
+		{task.EventDeleted, "a", "changed", 2},
+		{task.EventUpdated, "b", "", 2},
+	}
+	var got []task.Event
+	for range expected {
+		e := nextEvent(t, events)
+		if len(got) > 0 && e.ID <= got[len(got)-1].ID {
+			t.Fatalf("expected increasing event ids but got %d after %d", e.ID, got[len(got)-1].ID)
+		}
+		got = append(got, e)
+	}
+	changes := func(events []task.Event) []change {
+		var changes []change
+		for _, e := range events {
+			changes = append(changes, change{e.Type, e.Task.ID, e.Task.Title, e.Task.Revision})
+		}
+		return changes
+	}
+	if !reflect.DeepEqual(changes(got), expected) {
+		t.Fatalf("expected %v but got %v", expected, changes(got))
+	}
+	if got[4].Task.Parent != "" {
+		t.Errorf("expected the parent of %q cleared but got %q", "b", got[4].Task.Parent)
+	}
+
+	resumed, err := ti.Watch(ctx, got[1].ID)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	for _, e := range got[2:] {
+		if r := nextEvent(t, resumed); r.ID != e.ID || r.Type != e.Type || r.Task.ID != e.Task.ID {
+			t.Fatalf("expected resumed event %d %s %q but got %d %s %q", e.ID, e.Type, e.Task.ID, r.ID, r.Type, r.Task.ID)
+		}
+	}
+
+	cancel()
+	closed := make(chan struct{})
+	go func() {
+		for range events {
+		}
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the channel to be closed when the context is done")
+	}
+
+	_, err = ti.Watch(context.Background(), got[4].ID+1000)
+	assertIs(t, task.ErrExpired, err)
+}
+
+// Tests that deleting a task publishes its deleted event before the updated events of its subtasks and the tasks it
+// blocked, so that watchers never see a dependent changed by a deletion they have not seen yet.
+func testWatchDelete(t *testing.T, ti task.TaskInterface) {
+	put(t, ti, task.Task{ID: "a"})
+	put(t, ti, task.Task{ID: "b", Parent: "a"})
+	put(t, ti, task.Task{ID: "c", BlockedBy: []string{"a"}})
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	events, err := ti.Watch(ctx, 0)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if err := ti.Delete(ctx, "a"); err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+	if e := nextEvent(t, events); e.Type != task.EventDeleted || e.Task.ID != "a" {
+		t.Fatalf("expected %q deleted first but got %s %q", "a", e.Type, e.Task.ID)
+	}
+	var updated []string
+	for range []string{"b", "c"} {
+		e := nextEvent(t, events)
+		if e.Type != task.EventUpdated {
+			t.Fatalf("expected %s but got %s %q", task.EventUpdated, e.Type, e.Task.ID)
+		}
+		updated = append(updated, e.Task.ID)
+	}
+	sort.Strings(updated)
+	if !reflect.DeepEqual(updated, []string{"b", "c"}) {
+		t.Fatalf("expected %v updated but got %v", []string{"b", "c"}, updated)
+	}
+}
+
+// Tests that users only watch the events of the tasks they may read.
+func testIsolatedWatch(t *testing.T, as As) {
+	alice, ati := as("alice", false)
+	bob, bti := as("bob", false)
+	ctx, cancel := context.WithCancel(bob)
+	defer cancel()
+	events, err := bti.Watch(ctx, 0)
+	if err != nil {
+		t.Fatal("unexpected error: ", err)
+	}
+
+	putAs(t, alice, ati, task.Task{ID: "private"})
+	putListAs(t, alice, ati, task.TaskList{ID: "shared"})
+	shareAs(t, alice, ati, "shared", task.Grant{Grantee: task.Grantee{User: "bob"}, Role: task.RoleViewer})
+	putAs(t, alice, ati, task.Task{ID: "s", List: "shared"})
+	putAs(t, bob, bti, task.Task{ID: "b"})
+	for _, id := range []string{"s", "b"} {
+		if e := nextEvent(t, events); e.Type != task.EventCreated || e.Task.ID != id {
+			t.Fatalf("expected %q created but got %q %s", id, e.Task.ID, e.Type)
+		}
+	}
+}